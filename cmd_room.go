@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"pockerplan/ppback/admin"
+	"pockerplan/ppback/model"
+)
+
+// commandTimeout bounds every CLI command talking to a server.
+const commandTimeout = 30 * time.Second
+
+// RoomCmd groups commands operating on a single room through the REST API.
+type RoomCmd struct {
+	Create        RoomCreateCmd        `cmd:"" help:"Create a room and print its ID, admin secret and URL."`
+	ImportTickets RoomImportTicketsCmd `cmd:"" help:"Add tickets to a room from a CSV file (title, optional description)."`
+	Export        RoomExportCmd        `cmd:"" help:"Print a room with its tickets and votes."`
+}
+
+// RoomsCmd groups commands operating on all rooms through the admin socket.
+type RoomsCmd struct {
+	List    RoomsListCmd    `cmd:"" help:"List rooms with user and ticket counts."`
+	Dump    RoomsDumpCmd    `cmd:"" help:"Print the full state of a room."`
+	Delete  RoomsDeleteCmd  `cmd:"" help:"Close a room and disconnect its participants."`
+	Debug   RoomsDebugCmd   `cmd:"" help:"Log every RPC of a room with its payloads (secrets redacted)."`
+	Cleanup RoomsCleanupCmd `cmd:"" help:"Remove expired rooms now."`
+}
+
+// StatsCmd prints server counters through the admin socket.
+type StatsCmd struct {
+	adminFlags
+}
+
+// apiFlags selects the server the REST commands talk to.
+type apiFlags struct {
+	Server string `default:"http://localhost:8080" env:"POCKERPLAN_SERVER" help:"Base URL of the running server."`
+}
+
+// apiErrorBody mirrors the error body of the REST API.
+type apiErrorBody struct {
+	Error string `json:"error"`
+	Code  uint32 `json:"code"`
+}
+
+// call sends a REST request and decodes the JSON reply into out unless out is nil.
+func (f apiFlags) call(ctx context.Context, method, path, secret string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(f.Server, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e apiErrorBody
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			return fmt.Errorf("%s %s: %s (code %d)", method, path, e.Error, e.Code)
+		}
+		return fmt.Errorf("%s %s: unexpected status %d", method, path, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type RoomCreateCmd struct {
+	apiFlags
+	Scale    string `default:"fibonacci" help:"Estimation scale ID."`
+	UserName string `default:"Facilitator" help:"Display name of the admin user."`
+	Avatar   string `default:"owl" help:"Avatar ID of the admin user."`
+}
+
+func (c *RoomCreateCmd) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var resp model.CreateRoomResponse
+	req := model.CreateRoomRequest{ScaleID: c.Scale, UserName: c.UserName, AvatarID: c.Avatar}
+	if err := c.call(ctx, http.MethodPost, "/api/v1/rooms", "", req, &resp); err != nil {
+		return err
+	}
+	return printJSON(struct {
+		RoomID      string `json:"roomId"`
+		AdminSecret string `json:"adminSecret"`
+		URL         string `json:"url"`
+	}{resp.RoomID, resp.AdminSecret, strings.TrimRight(c.Server, "/") + "/room/" + resp.RoomID})
+}
+
+type RoomImportTicketsCmd struct {
+	apiFlags
+	Room        string `required:"" env:"POCKERPLAN_ROOM" help:"Room ID."`
+	AdminSecret string `required:"" env:"POCKERPLAN_ADMIN_SECRET" help:"Room admin secret."`
+	File        string `arg:"" type:"existingfile" help:"CSV file: one ticket per row with a title and an optional description; further columns are added to the description. A header row naming a \"title\" column selects the title and \"description\" columns by name."`
+}
+
+func (c *RoomImportTicketsCmd) Run() error {
+	f, err := os.Open(c.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	contents, err := readTicketsCSV(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.File, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	path := "/api/v1/rooms/" + url.PathEscape(c.Room) + "/tickets"
+	for _, content := range contents {
+		var resp model.AddTicketResponse
+		if err := c.call(ctx, http.MethodPost, path, c.AdminSecret, map[string]string{"content": content}, &resp); err != nil {
+			return err
+		}
+		fmt.Println(resp.TicketID)
+	}
+	return nil
+}
+
+// readTicketsCSV converts CSV rows into ticket contents. Without a header the
+// first column is the title and every other non-empty column becomes a
+// paragraph of the description. A first row naming a "title" column is a
+// header: the title and "description" columns are then taken by name and the
+// rest are ignored, so that files written by writeTicketsCSV read back.
+func readTicketsCSV(r io.Reader) ([]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	titleCol, descCol, first := 0, -1, 1
+	if len(rows) > 0 {
+		for i, name := range rows[0] {
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "title":
+				titleCol, first = i, 2
+			case "description":
+				descCol = i
+			}
+		}
+		if first == 1 {
+			descCol = -1 // a "description" cell without a title column is data
+		} else {
+			rows = rows[1:]
+		}
+	}
+
+	var contents []string
+	for i, row := range rows {
+		var title string
+		if titleCol < len(row) {
+			title = strings.TrimSpace(row[titleCol])
+		}
+		if title == "" {
+			if len(row) == 1 && strings.TrimSpace(row[0]) == "" {
+				continue // blank line
+			}
+			return nil, fmt.Errorf("row %d: empty title", i+first)
+		}
+		var desc []string
+		for j, cell := range row {
+			if j == titleCol || (first == 2 && j != descCol) {
+				continue
+			}
+			if cell = strings.TrimSpace(cell); cell != "" {
+				desc = append(desc, cell)
+			}
+		}
+		content := "# " + title
+		if len(desc) > 0 {
+			content += "\n\n" + strings.Join(desc, "\n\n")
+		}
+		contents = append(contents, content)
+	}
+	if len(contents) == 0 {
+		return nil, errors.New("no tickets found")
+	}
+	return contents, nil
+}
+
+type RoomExportCmd struct {
+	apiFlags
+	Room   string `arg:"" help:"Room ID."`
+	Format string `enum:"json,csv" default:"json" help:"Output format (json, csv)."`
+}
+
+func (c *RoomExportCmd) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var snap model.RoomSnapshot
+	if err := c.call(ctx, http.MethodGet, "/api/v1/rooms/"+url.PathEscape(c.Room), "", nil, &snap); err != nil {
+		return err
+	}
+	if c.Format == "json" {
+		return printJSON(snap)
+	}
+	return writeTicketsCSV(os.Stdout, &snap)
+}
+
+// writeTicketsCSV writes one row per ticket. Votes are listed as "name=value"
+// pairs and stay empty while they are hidden. The description is the content
+// after the title line.
+func writeTicketsCSV(w io.Writer, snap *model.RoomSnapshot) error {
+	names := make(map[string]string, len(snap.Users))
+	for _, u := range snap.Users {
+		names[u.ID] = u.Name
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "title", "status", "votes", "external_key", "description"})
+	for _, t := range snap.Tickets {
+		title, desc, _ := strings.Cut(t.Content, "\n")
+		var votes []string
+		for _, v := range t.Votes {
+			if v.Value != "" {
+				votes = append(votes, names[v.UserID]+"="+v.Value)
+			}
+		}
+		cw.Write([]string{t.ID, strings.TrimPrefix(title, "# "), string(t.Status), strings.Join(votes, ";"), t.ExternalKey, strings.TrimSpace(desc)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// adminFlags selects the admin socket of a running server.
+type adminFlags struct {
+	AdminSocket string `required:"" env:"ADMIN_SOCKET" help:"Path of the server's admin socket."`
+}
+
+func (f adminFlags) client() *admin.Client {
+	return admin.NewClient(f.AdminSocket)
+}
+
+type RoomsListCmd struct {
+	adminFlags
+	JSON bool `help:"Print JSON instead of a table."`
+}
+
+func (c *RoomsListCmd) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	list, err := c.client().ListRooms(ctx)
+	if err != nil {
+		return err
+	}
+	if c.JSON {
+		return printJSON(list)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSCALE\tSTATE\tUSERS\tTICKETS\tLAST ACTIVITY")
+	for _, r := range list {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			r.ID, r.Name, r.Scale, r.State, r.Users, r.Tickets, r.LastActivityAt.Local().Format(time.DateTime))
+	}
+	return tw.Flush()
+}
+
+type RoomsDumpCmd struct {
+	adminFlags
+	Room string `arg:"" help:"Room ID."`
+}
+
+func (c *RoomsDumpCmd) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	data, err := c.client().DumpRoom(ctx, c.Room)
+	if err != nil {
+		return err
+	}
+	return printJSON(data)
+}
+
+type RoomsDeleteCmd struct {
+	adminFlags
+	Room string `arg:"" help:"Room ID."`
+}
+
+func (c *RoomsDeleteCmd) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	return c.client().DeleteRoom(ctx, c.Room)
+}
+
+type RoomsDebugCmd struct {
+	adminFlags
+	Room string        `arg:"" help:"Room ID."`
+	For  time.Duration `default:"30m" help:"How long to keep debug logging on."`
+	Off  bool          `help:"Turn debug logging off."`
+}
+
+func (c *RoomsDebugCmd) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	d := c.For
+	if c.Off {
+		d = 0
+	}
+	status, err := c.client().SetRoomDebug(ctx, c.Room, d)
+	if err != nil {
+		return err
+	}
+	if !status.Enabled {
+		fmt.Println("debug logging off")
+		return nil
+	}
+	fmt.Printf("debug logging on until %s\n", status.Until.Local().Format(time.DateTime))
+	return nil
+}
+
+type RoomsCleanupCmd struct {
+	adminFlags
+}
+
+func (c *RoomsCleanupCmd) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	removed, err := c.client().Cleanup(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("removed %d room(s)\n", removed)
+	return nil
+}
+
+func (c *StatsCmd) Run() error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	stats, err := c.client().Stats(ctx)
+	if err != nil {
+		return err
+	}
+	return printJSON(stats)
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pockerplan/ppback/model"
+)
+
+func TestReadTicketsCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "title only",
+			input: "Login page\nLogout\n",
+			want:  []string{"# Login page", "# Logout"},
+		},
+		{
+			name:  "title and description",
+			input: "Login page, Add a form\n",
+			want:  []string{"# Login page\n\nAdd a form"},
+		},
+		{
+			name:  "extra columns join the description",
+			input: "Login page,Add a form,,Use OAuth\n",
+			want:  []string{"# Login page\n\nAdd a form\n\nUse OAuth"},
+		},
+		{
+			name:  "header skipped",
+			input: "Title,Description\nLogin page,Add a form\n",
+			want:  []string{"# Login page\n\nAdd a form"},
+		},
+		{
+			name:  "header selects columns by name",
+			input: "id,title,status,description\nt1,Login page,pending,Add a form\n",
+			want:  []string{"# Login page\n\nAdd a form"},
+		},
+		{
+			name:  "description without a title column is data",
+			input: "description,Details\n",
+			want:  []string{"# description\n\nDetails"},
+		},
+		{
+			name:  "quoted multiline description",
+			input: "Login page,\"First line\nSecond line\"\n",
+			want:  []string{"# Login page\n\nFirst line\nSecond line"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTicketsCSV(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadTicketsCSVErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty title", "Login page\n,Orphan description\n", "row 2: empty title"},
+		{"empty title after header", "title,description\n,Orphan\n", "row 2: empty title"},
+		{"empty file", "", "no tickets found"},
+		{"header only", "title\n", "no tickets found"},
+		{"malformed", "\"unterminated\n", "extraneous or missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readTicketsCSV(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestWriteTicketsCSV(t *testing.T) {
+	snap := &model.RoomSnapshot{
+		Users: []*model.User{{ID: "u1", Name: "Alice"}, {ID: "u2", Name: "Bob"}},
+		Tickets: []*model.TicketSnapshot{
+			{
+				ID:          "t1",
+				Content:     "# Login page\n\nAdd a form, with \"quotes\"",
+				Status:      model.TicketStatusRevealed,
+				Votes:       []model.VoteInfo{{UserID: "u1", Value: "5"}, {UserID: "u2", Value: "8"}},
+				ExternalKey: "PROJ-1",
+			},
+			{
+				ID:      "t2",
+				Content: "# Logout",
+				Status:  model.TicketStatusVoting,
+				Votes:   []model.VoteInfo{{UserID: "u1"}},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := writeTicketsCSV(&buf, snap); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	want := [][]string{
+		{"id", "title", "status", "votes", "external_key", "description"},
+		{"t1", "Login page", string(model.TicketStatusRevealed), "Alice=5;Bob=8", "PROJ-1", "Add a form, with \"quotes\""},
+		{"t2", "Logout", string(model.TicketStatusVoting), "", "", ""},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %q, want %q", rows, want)
+	}
+
+	// Importing an export recreates the ticket contents.
+	contents, err := readTicketsCSV(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("import export: %v", err)
+	}
+	for i, tk := range snap.Tickets {
+		if contents[i] != tk.Content {
+			t.Errorf("ticket %d: got %q, want %q", i, contents[i], tk.Content)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Package admin serves an operator API on a Unix domain socket. It is only
+// reachable by local users with access to the socket file, so it carries no
+// authentication of its own.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"pockerplan/ppback/hub"
+	"pockerplan/ppback/model"
+	"pockerplan/ppback/room"
+
+	"github.com/rs/zerolog"
+)
+
+// socketMode restricts the socket to the user running the server.
+const socketMode = 0o600
+
+// Server handles admin requests.
+type Server struct {
+	rooms  *room.Manager
+	hub    *hub.Hub
+	logger zerolog.Logger
+	mux    *http.ServeMux
+	http   *http.Server
+}
+
+// New creates an admin server over the given room manager and hub.
+func New(rm *room.Manager, h *hub.Hub, logger zerolog.Logger) *Server {
+	s := &Server{
+		rooms:  rm,
+		hub:    h,
+		logger: logger,
+		mux:    http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /rooms", s.handleListRooms)
+	s.mux.HandleFunc("GET /rooms/{id}", s.handleDumpRoom)
+	s.mux.HandleFunc("DELETE /rooms/{id}", s.handleDeleteRoom)
+	s.mux.HandleFunc("GET /rooms/{id}/debug", s.handleGetDebug)
+	s.mux.HandleFunc("POST /rooms/{id}/debug", s.handleSetDebug)
+	s.mux.HandleFunc("DELETE /rooms/{id}/debug", s.handleSetDebug)
+	s.mux.HandleFunc("POST /cleanup", s.handleCleanup)
+	s.mux.HandleFunc("GET /stats", s.handleStats)
+	s.http = &http.Server{Handler: s.mux}
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Listen creates the socket at path, replacing a stale one left by a previous
+// run, and serves requests in the background until Shutdown.
+func (s *Server) Listen(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove stale socket: %w", err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, socketMode); err != nil {
+		l.Close()
+		return err
+	}
+	go func() {
+		if err := s.http.Serve(l); err != nil && err != http.ErrServerClosed {
+			s.logger.Error().Err(err).Msg("admin socket")
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops the server and removes the socket file.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+func (s *Server) handleListRooms(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.rooms.List())
+}
+
+// handleDumpRoom returns the full room state. Secrets are excluded by the
+// model's JSON tags.
+func (s *Server) handleDumpRoom(w http.ResponseWriter, r *http.Request) {
+	var data []byte
+	err := s.rooms.WithRoom(r.PathValue("id"), func(rm *model.Room) error {
+		var err error
+		data, err = json.Marshal(rm)
+		return err
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (s *Server) handleDeleteRoom(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.hub.CloseRoom(id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// defaultDebugDuration is how long room debug logging stays on when the
+// request does not say.
+const defaultDebugDuration = 30 * time.Minute
+
+// DebugStatus is the reply of the /rooms/{id}/debug endpoints.
+type DebugStatus struct {
+	Enabled bool      `json:"enabled"`
+	Until   time.Time `json:"until,omitzero"`
+}
+
+func (s *Server) handleGetDebug(w http.ResponseWriter, r *http.Request) {
+	s.writeDebugStatus(w, r.PathValue("id"))
+}
+
+// handleSetDebug turns room debug logging on for the duration in the "for"
+// query parameter (POST), or off (DELETE).
+func (s *Server) handleSetDebug(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var d time.Duration
+	if r.Method == http.MethodPost {
+		d = defaultDebugDuration
+		if v := r.URL.Query().Get("for"); v != "" {
+			var err error
+			if d, err = time.ParseDuration(v); err != nil || d <= 0 {
+				writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid duration " + strconv.Quote(v)})
+				return
+			}
+		}
+	}
+	if err := s.hub.SetRoomDebug(id, d); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.writeDebugStatus(w, id)
+}
+
+func (s *Server) writeDebugStatus(w http.ResponseWriter, roomID string) {
+	until, err := s.hub.RoomDebugUntil(roomID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, DebugStatus{Enabled: !until.IsZero(), Until: until})
+}
+
+// CleanupResult is the reply of POST /cleanup.
+type CleanupResult struct {
+	Removed int `json:"removed"`
+}
+
+func (s *Server) handleCleanup(w http.ResponseWriter, r *http.Request) {
+	removed := s.rooms.Cleanup()
+	s.logger.Info().Int("removed", removed).Msg("cleanup triggered")
+	writeJSON(w, http.StatusOK, CleanupResult{Removed: removed})
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.hub.Stats())
+}
+
+// errorResponse is the JSON body of a failed admin request.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, room.ErrRoomNotFound) {
+		status = http.StatusNotFound
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,234 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pockerplan/ppback/client"
+	"pockerplan/ppback/hub"
+	"pockerplan/ppback/model"
+	"pockerplan/ppback/room"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/rs/zerolog"
+)
+
+type testEnv struct {
+	rooms *room.Manager
+	hub   *hub.Hub
+	admin *Client
+	wsURL string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	rm := room.NewManager()
+	h, err := hub.New(rm, 3, false, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("create hub: %v", err)
+	}
+	if err := h.Run(); err != nil {
+		t.Fatalf("run hub: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/connection/websocket", centrifuge.NewWebsocketHandler(h.Node(), centrifuge.WebsocketConfig{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}))
+	srv := httptest.NewServer(mux)
+
+	s := New(rm, h, zerolog.Nop())
+	path := filepath.Join(t.TempDir(), "admin.sock")
+	if err := s.Listen(path); err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() {
+		s.Shutdown(context.Background())
+		srv.Close()
+		_ = h.Shutdown()
+	})
+	return &testEnv{
+		rooms: rm,
+		hub:   h,
+		admin: NewClient(path),
+		wsURL: "ws" + strings.TrimPrefix(srv.URL, "http") + "/connection/websocket",
+	}
+}
+
+func TestListRooms(t *testing.T) {
+	env := newTestEnv(t)
+	r, _ := env.rooms.Create("fibonacci", 3)
+
+	list, err := env.admin.ListRooms(context.Background())
+	if err != nil {
+		t.Fatalf("list rooms: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != r.ID {
+		t.Fatalf("expected room %s, got %+v", r.ID, list)
+	}
+}
+
+func TestDumpRoom(t *testing.T) {
+	env := newTestEnv(t)
+	r, _ := env.rooms.Create("fibonacci", 3)
+	env.rooms.WithRoom(r.ID, func(r *model.Room) error {
+		room.AddUser(r, &model.User{ID: "u1", Name: "Alice", AvatarID: "cat"})
+		return nil
+	})
+
+	data, err := env.admin.DumpRoom(context.Background(), r.ID)
+	if err != nil {
+		t.Fatalf("dump room: %v", err)
+	}
+	if strings.Contains(string(data), r.AdminSecret) {
+		t.Error("dump must not contain the admin secret")
+	}
+	var dumped model.Room
+	if err := json.Unmarshal(data, &dumped); err != nil {
+		t.Fatalf("unmarshal dump: %v", err)
+	}
+	if dumped.ID != r.ID || dumped.Users["u1"] == nil {
+		t.Errorf("unexpected dump %s", data)
+	}
+
+	if _, err := env.admin.DumpRoom(context.Background(), "missing"); err == nil {
+		t.Error("expected error for unknown room")
+	}
+}
+
+func TestDeleteRoom(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	dialCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	c, err := client.Dial(dialCtx, env.wsURL)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	created, err := c.CreateRoom(ctx, model.CreateRoomRequest{ScaleID: "fibonacci", UserName: "Alice", AvatarID: "cat"})
+	if err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	snaps, err := c.Watch(ctx, created.RoomID)
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if stats := env.hub.Stats(); stats.Members != 1 || stats.Subscriptions != 1 {
+		t.Fatalf("expected 1 member and 1 subscription, got %+v", stats)
+	}
+
+	if err := env.admin.DeleteRoom(ctx, created.RoomID); err != nil {
+		t.Fatalf("delete room: %v", err)
+	}
+	if _, err := env.rooms.Get(created.RoomID); err == nil {
+		t.Error("expected room to be deleted")
+	}
+
+	timeout := time.After(5 * time.Second)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-snaps:
+			closed = !ok
+		case <-timeout:
+			t.Fatal("subscriber was not unsubscribed")
+		}
+	}
+
+	stats, err := env.admin.Stats(ctx)
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	if stats.Rooms != 0 || stats.Members != 0 || stats.Connections != 1 {
+		t.Errorf("unexpected stats after delete: %+v", stats)
+	}
+
+	if err := env.admin.DeleteRoom(ctx, created.RoomID); err == nil {
+		t.Error("expected error when deleting a missing room")
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	env := newTestEnv(t)
+	r, _ := env.rooms.Create("fibonacci", 3)
+	env.rooms.Create("fibonacci", 3)
+	env.rooms.WithRoom(r.ID, func(r *model.Room) error {
+		r.LastActivityAt = time.Now().Add(-48 * time.Hour)
+		return nil
+	})
+
+	removed, err := env.admin.Cleanup(context.Background())
+	if err != nil {
+		t.Fatalf("cleanup: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 room removed, got %d", removed)
+	}
+	if env.rooms.Count() != 1 {
+		t.Errorf("expected 1 room left, got %d", env.rooms.Count())
+	}
+}
+
+func TestRoomDebug(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	r, _ := env.rooms.Create("fibonacci", 3)
+
+	status, err := env.admin.SetRoomDebug(ctx, r.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("set debug: %v", err)
+	}
+	if !status.Enabled || time.Until(status.Until) < 59*time.Minute {
+		t.Errorf("expected debug on for an hour, got %+v", status)
+	}
+	if status, err = env.admin.RoomDebug(ctx, r.ID); err != nil || !status.Enabled {
+		t.Errorf("expected debug on, got %+v, %v", status, err)
+	}
+
+	status, err = env.admin.SetRoomDebug(ctx, r.ID, 0)
+	if err != nil {
+		t.Fatalf("clear debug: %v", err)
+	}
+	if status.Enabled {
+		t.Errorf("expected debug off, got %+v", status)
+	}
+
+	if _, err := env.admin.SetRoomDebug(ctx, "missing", time.Hour); err == nil {
+		t.Error("expected error for a missing room")
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	env := newTestEnv(t)
+	path := filepath.Join(t.TempDir(), "admin.sock")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := New(env.rooms, env.hub, zerolog.Nop())
+	if err := s.Listen(path); err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer s.Shutdown(context.Background())
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		t.Errorf("expected a socket, got mode %v", info.Mode())
+	}
+	if perm := info.Mode().Perm(); perm != socketMode {
+		t.Errorf("expected permissions %o, got %o", socketMode, perm)
+	}
+	if _, err := NewClient(path).ListRooms(context.Background()); err != nil {
+		t.Errorf("list rooms: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"pockerplan/ppback/hub"
+	"pockerplan/ppback/room"
+)
+
+// Client talks to an admin server over its Unix socket.
+type Client struct {
+	http *http.Client
+}
+
+// NewClient creates a client for the socket at path.
+func NewClient(path string) *Client {
+	return &Client{http: &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		},
+	}}
+}
+
+// ListRooms returns summaries of all rooms on the server.
+func (c *Client) ListRooms(ctx context.Context) ([]room.Summary, error) {
+	var list []room.Summary
+	if err := c.do(ctx, http.MethodGet, "/rooms", &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// DumpRoom returns the full JSON state of a room.
+func (c *Client) DumpRoom(ctx context.Context, id string) (json.RawMessage, error) {
+	var data json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/rooms/"+url.PathEscape(id), &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// DeleteRoom closes a room and disconnects its participants from it.
+func (c *Client) DeleteRoom(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/rooms/"+url.PathEscape(id), nil)
+}
+
+// SetRoomDebug logs every RPC of a room with its payloads for the duration d,
+// or turns debug logging off when d is zero.
+func (c *Client) SetRoomDebug(ctx context.Context, id string, d time.Duration) (DebugStatus, error) {
+	var status DebugStatus
+	path := "/rooms/" + url.PathEscape(id) + "/debug"
+	method := http.MethodDelete
+	if d > 0 {
+		method = http.MethodPost
+		path += "?for=" + url.QueryEscape(d.String())
+	}
+	err := c.do(ctx, method, path, &status)
+	return status, err
+}
+
+// RoomDebug reports whether debug logging is on for a room.
+func (c *Client) RoomDebug(ctx context.Context, id string) (DebugStatus, error) {
+	var status DebugStatus
+	err := c.do(ctx, http.MethodGet, "/rooms/"+url.PathEscape(id)+"/debug", &status)
+	return status, err
+}
+
+// Cleanup removes expired rooms right away and returns how many were removed.
+func (c *Client) Cleanup(ctx context.Context) (int, error) {
+	var res CleanupResult
+	if err := c.do(ctx, http.MethodPost, "/cleanup", &res); err != nil {
+		return 0, err
+	}
+	return res.Removed, nil
+}
+
+// Stats returns the hub's connection and room counters.
+func (c *Client) Stats(ctx context.Context) (hub.Stats, error) {
+	var stats hub.Stats
+	err := c.do(ctx, http.MethodGet, "/stats", &stats)
+	return stats, err
+}
+
+// do sends a request and decodes a JSON reply into out unless out is nil.
+func (c *Client) do(ctx context.Context, method, path string, out any) error {
+	// The host is ignored by the Unix dialer.
+	req, err := http.NewRequestWithContext(ctx, method, "http://admin"+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e errorResponse
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			return fmt.Errorf("admin: %s", e.Error)
+		}
+		return fmt.Errorf("admin: unexpected status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
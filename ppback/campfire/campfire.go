@@ -29,6 +29,7 @@ const (
 
 var ErrTreeNotFound = errors.New("tree not found")
 var ErrTreeAlreadyBurned = errors.New("tree already burned")
+var ErrNoCampfire = errors.New("room has no campfire theme")
 
 // fnv1a is a port of the frontend hashString (FNV-1a over UTF-8 bytes).
 func fnv1a(s string) uint32 {
@@ -76,15 +77,16 @@ func Init(r *model.Room) {
 	}
 }
 
-// FeedFire marks a tree as burned, increments fire level, and appends an event.
-func FeedFire(r *model.Room, userID string, treeID int, fromX, fromY float64) error {
+// FeedFire marks a tree as burned, increments fire level, and returns the event
+// to publish.
+func FeedFire(r *model.Room, userID string, treeID int, fromX, fromY float64) (model.RoomEvent, error) {
 	if r.ThemeState == nil || r.ThemeState.Theme != model.ThemeTypeCampfire {
-		return errors.New("room has no campfire theme")
+		return model.RoomEvent{}, ErrNoCampfire
 	}
 
 	var state model.CampfireState
 	if err := json.Unmarshal(r.ThemeState.Data, &state); err != nil {
-		return err
+		return model.RoomEvent{}, err
 	}
 
 	idx := -1
@@ -95,12 +97,12 @@ func FeedFire(r *model.Room, userID string, treeID int, fromX, fromY float64) er
 		}
 	}
 	if idx == -1 {
-		return ErrTreeNotFound
+		return model.RoomEvent{}, ErrTreeNotFound
 	}
 
 	now := time.Now()
 	if state.Trees[idx].BurnedAt != nil {
-		return ErrTreeAlreadyBurned
+		return model.RoomEvent{}, ErrTreeAlreadyBurned
 	}
 
 	burnedAt := now
@@ -119,23 +121,21 @@ func FeedFire(r *model.Room, userID string, treeID int, fromX, fromY float64) er
 		FromY:  fromY,
 	})
 	if err != nil {
-		return err
+		return model.RoomEvent{}, err
 	}
 
 	data, err := json.Marshal(state)
 	if err != nil {
-		return err
+		return model.RoomEvent{}, err
 	}
 	r.ThemeState.Data = json.RawMessage(data)
 
-	r.PendingEvents = append(r.PendingEvents, model.RoomEvent{
+	return model.RoomEvent{
 		Type:    "theme_interaction",
 		Action:  "feed_fire",
 		FromID:  userID,
 		Payload: json.RawMessage(payload),
-	})
-
-	return nil
+	}, nil
 }
 
 // Normalize runs fire decay and tree respawn. Returns true if anything changed.
@@ -48,7 +48,8 @@ func TestFeedFire_HappyPath(t *testing.T) {
 	fromX := s0.Trees[0].X
 	fromY := s0.Trees[0].Y
 
-	if err := FeedFire(r, "user1", treeID, fromX, fromY); err != nil {
+	ev, err := FeedFire(r, "user1", treeID, fromX, fromY)
+	if err != nil {
 		t.Fatalf("FeedFire: %v", err)
 	}
 
@@ -63,11 +64,7 @@ func TestFeedFire_HappyPath(t *testing.T) {
 		t.Errorf("want FireLevel 1, got %d", s.FireLevel)
 	}
 
-	// Event appended
-	if len(r.PendingEvents) != 1 {
-		t.Fatalf("want 1 event, got %d", len(r.PendingEvents))
-	}
-	ev := r.PendingEvents[0]
+	// Event returned
 	if ev.Type != "theme_interaction" || ev.Action != "feed_fire" {
 		t.Errorf("unexpected event: %+v", ev)
 	}
@@ -86,10 +83,10 @@ func TestFeedFire_AlreadyBurned(t *testing.T) {
 	s0 := getState(t, r)
 	treeID := s0.Trees[0].ID
 
-	if err := FeedFire(r, "user1", treeID, 0, 0); err != nil {
+	if _, err := FeedFire(r, "user1", treeID, 0, 0); err != nil {
 		t.Fatalf("first FeedFire: %v", err)
 	}
-	err := FeedFire(r, "user1", treeID, 0, 0)
+	_, err := FeedFire(r, "user1", treeID, 0, 0)
 	if err != ErrTreeAlreadyBurned {
 		t.Errorf("want ErrTreeAlreadyBurned, got %v", err)
 	}
@@ -97,7 +94,7 @@ func TestFeedFire_AlreadyBurned(t *testing.T) {
 
 func TestFeedFire_NotFound(t *testing.T) {
 	r := newCampfireRoom()
-	err := FeedFire(r, "user1", 9999, 0, 0)
+	_, err := FeedFire(r, "user1", 9999, 0, 0)
 	if err != ErrTreeNotFound {
 		t.Errorf("want ErrTreeNotFound, got %v", err)
 	}
@@ -107,7 +104,7 @@ func TestFeedFire_FireLevelCap(t *testing.T) {
 	r := newCampfireRoom()
 	s0 := getState(t, r)
 	for i := 0; i < maxFireLevel+2 && i < len(s0.Trees); i++ {
-		_ = FeedFire(r, "user1", s0.Trees[i].ID, 0, 0)
+		_, _ = FeedFire(r, "user1", s0.Trees[i].ID, 0, 0)
 	}
 	s := getState(t, r)
 	if s.FireLevel > maxFireLevel {
@@ -120,8 +117,8 @@ func TestNormalize_Decay(t *testing.T) {
 	s0 := getState(t, r)
 
 	// Feed fire to set level to 2.
-	_ = FeedFire(r, "u", s0.Trees[0].ID, 0, 0)
-	_ = FeedFire(r, "u", s0.Trees[1].ID, 0, 0)
+	_, _ = FeedFire(r, "u", s0.Trees[0].ID, 0, 0)
+	_, _ = FeedFire(r, "u", s0.Trees[1].ID, 0, 0)
 
 	// Backdate LastFedAt by 65s = 2 full 30s intervals.
 	var state model.CampfireState
@@ -157,7 +154,7 @@ func TestNormalize_Respawn(t *testing.T) {
 	s0 := getState(t, r)
 	treeID := s0.Trees[0].ID
 
-	_ = FeedFire(r, "u", treeID, 0, 0)
+	_, _ = FeedFire(r, "u", treeID, 0, 0)
 
 	// Backdate RespawnAt to the past.
 	var state model.CampfireState
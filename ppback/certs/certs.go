@@ -0,0 +1,127 @@
+// Package certs serves a TLS certificate from files that may be replaced
+// while the server runs, for example by a renewal job.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Certificate is a key pair loaded from a certificate and a key file.
+type Certificate struct {
+	certFile, keyFile string
+	logger            zerolog.Logger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time // latest modification time of the two files when loaded
+}
+
+// Load reads the key pair. The certificate file may contain intermediate
+// certificates after the leaf.
+func Load(certFile, keyFile string, logger zerolog.Logger) (*Certificate, error) {
+	c := &Certificate{certFile: certFile, keyFile: keyFile, logger: logger}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload reads the files again. On error the previous key pair stays in use.
+func (c *Certificate) Reload() error {
+	modTime, err := c.filesModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("load key pair: %w", err)
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return fmt.Errorf("parse certificate: %w", err)
+	}
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.mu.Unlock()
+	c.logger.Info().
+		Str("subject", cert.Leaf.Subject.String()).
+		Time("not_after", cert.Leaf.NotAfter).
+		Msg("certificate loaded")
+	return nil
+}
+
+// GetCertificate returns the current key pair. It is meant for
+// tls.Config.GetCertificate.
+func (c *Certificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// TLSConfig returns a server configuration serving the current key pair.
+func (c *Certificate) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.GetCertificate,
+	}
+}
+
+// StartWatch checks the files every interval in a goroutine and reloads the
+// key pair when either has changed, until done is closed. A failed reload is
+// logged and retried on the next change.
+func (c *Certificate) StartWatch(interval time.Duration, done <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.reloadIfChanged()
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+func (c *Certificate) reloadIfChanged() {
+	modTime, err := c.filesModTime()
+	if err != nil {
+		// A renewal job may replace the files one after the other.
+		c.logger.Debug().Err(err).Msg("certificate files not readable")
+		return
+	}
+	c.mu.RLock()
+	changed := !modTime.Equal(c.modTime)
+	c.mu.RUnlock()
+	if !changed {
+		return
+	}
+	if err := c.Reload(); err != nil {
+		c.logger.Error().Err(err).Msg("certificate reload failed")
+		c.mu.Lock()
+		c.modTime = modTime // retry once the files change again
+		c.mu.Unlock()
+	}
+}
+
+func (c *Certificate) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{c.certFile, c.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,111 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// writePair writes a self-signed certificate for name and its key, dated
+// modTime.
+func writePair(t *testing.T, dir, name string, modTime time.Time) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), modTime)
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), modTime)
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, name string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commonName(t *testing.T, c *Certificate) string {
+	t.Helper()
+	cert, err := c.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.Leaf.Subject.CommonName
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writePair(t, dir, "one.example", time.Now())
+
+	c, err := Load(certFile, keyFile, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if name := commonName(t, c); name != "one.example" {
+		t.Errorf("expected one.example, got %s", name)
+	}
+
+	if _, err := Load(certFile, filepath.Join(dir, "missing.pem"), zerolog.Nop()); err == nil {
+		t.Error("expected error for missing key file")
+	}
+}
+
+func TestReloadIfChanged(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+	certFile, keyFile := writePair(t, dir, "one.example", start)
+	c, err := Load(certFile, keyFile, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	c.reloadIfChanged()
+	if name := commonName(t, c); name != "one.example" {
+		t.Fatalf("expected unchanged certificate, got %s", name)
+	}
+
+	// A broken certificate is rejected and the previous one stays in use.
+	writeFile(t, certFile, []byte("garbage"), start.Add(time.Second))
+	c.reloadIfChanged()
+	if name := commonName(t, c); name != "one.example" {
+		t.Fatalf("expected previous certificate after failed reload, got %s", name)
+	}
+
+	writePair(t, dir, "two.example", start.Add(2*time.Second))
+	c.reloadIfChanged()
+	if name := commonName(t, c); name != "two.example" {
+		t.Errorf("expected renewed certificate, got %s", name)
+	}
+}
@@ -0,0 +1,224 @@
+// Package client is a Go SDK for pockerplan rooms. It connects to the server
+// over WebSocket, wraps every RPC method with the request and response types
+// from package model and streams room snapshots.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"pockerplan/ppback/model"
+
+	centrifuge "github.com/centrifugal/centrifuge-go"
+)
+
+// snapshotBuffer is the capacity of a Watch channel. Snapshots carry the full
+// room state, so when a consumer falls behind the oldest ones are dropped.
+const snapshotBuffer = 16
+
+var ErrClosed = errors.New("client closed")
+
+// Error is an RPC error returned by the server. Code is one of the application
+// codes in model (e.g. model.CodeNotVoting) or a centrifuge protocol code
+// (e.g. 103 permission denied, 107 bad request, 108 not available).
+type Error struct {
+	Code    uint32
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("pockerplan: %s (code %d)", e.Message, e.Code)
+}
+
+// Client is a connection to a pockerplan server. It is safe for concurrent use.
+type Client struct {
+	cli *centrifuge.Client
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Dial connects to the WebSocket endpoint, e.g.
+// "ws://localhost:8080/connection/websocket", and waits until the connection
+// is established or ctx expires.
+func Dial(ctx context.Context, url string) (*Client, error) {
+	cli := centrifuge.NewJsonClient(url, centrifuge.Config{})
+
+	connected := make(chan struct{})
+	var once sync.Once
+	cli.OnConnected(func(centrifuge.ConnectedEvent) {
+		once.Do(func() { close(connected) })
+	})
+	if err := cli.Connect(); err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	select {
+	case <-connected:
+	case <-ctx.Done():
+		cli.Close()
+		return nil, fmt.Errorf("connect: %w", ctx.Err())
+	}
+	return &Client{cli: cli, done: make(chan struct{})}, nil
+}
+
+// Close disconnects from the server and closes all Watch channels.
+func (c *Client) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.cli.Close()
+	})
+}
+
+// call sends an RPC and decodes the reply into resp unless resp is nil.
+func (c *Client) call(ctx context.Context, method string, req, resp any) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("%s: encode request: %w", method, err)
+	}
+	result, err := c.cli.RPC(ctx, method, data)
+	if err != nil {
+		var ce *centrifuge.Error
+		if errors.As(err, &ce) {
+			return &Error{Code: ce.Code, Message: ce.Message}
+		}
+		if errors.Is(err, centrifuge.ErrClientClosed) {
+			return ErrClosed
+		}
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if resp == nil {
+		return nil
+	}
+	if err := json.Unmarshal(result.Data, resp); err != nil {
+		return fmt.Errorf("%s: decode response: %w", method, err)
+	}
+	return nil
+}
+
+// CreateRoom creates a room; the caller becomes its admin.
+func (c *Client) CreateRoom(ctx context.Context, req model.CreateRoomRequest) (*model.CreateRoomResponse, error) {
+	var resp model.CreateRoomResponse
+	if err := c.call(ctx, "create_room", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// JoinRoom joins an existing room. Call Watch first to receive the broadcast
+// that follows the join.
+func (c *Client) JoinRoom(ctx context.Context, req model.JoinRoomRequest) (*model.JoinRoomResponse, error) {
+	var resp model.JoinRoomResponse
+	if err := c.call(ctx, "join_room", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetRoom returns the current room snapshot without joining the room.
+func (c *Client) GetRoom(ctx context.Context, roomID string) (*model.RoomSnapshot, error) {
+	var resp model.RoomSnapshot
+	if err := c.call(ctx, "get_room", model.GetRoomRequest{RoomID: roomID}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) SubmitVote(ctx context.Context, req model.SubmitVoteRequest) error {
+	return c.call(ctx, "submit_vote", req, nil)
+}
+
+func (c *Client) RemoveVote(ctx context.Context, req model.RemoveVoteRequest) error {
+	return c.call(ctx, "remove_vote", req, nil)
+}
+
+func (c *Client) SetThinking(ctx context.Context, req model.SetThinkingRequest) error {
+	return c.call(ctx, "set_thinking", req, nil)
+}
+
+func (c *Client) AddTicket(ctx context.Context, req model.AddTicketRequest) (*model.AddTicketResponse, error) {
+	var resp model.AddTicketResponse
+	if err := c.call(ctx, "add_ticket", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) ImportTickets(ctx context.Context, req model.ImportTicketsRequest) (*model.ImportTicketsResponse, error) {
+	var resp model.ImportTicketsResponse
+	if err := c.call(ctx, "import_tickets", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) SyncEstimates(ctx context.Context, req model.AdminActionRequest) (*model.SyncEstimatesResponse, error) {
+	var resp model.SyncEstimatesResponse
+	if err := c.call(ctx, "sync_estimates", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *Client) SetWebhook(ctx context.Context, req model.SetWebhookRequest) error {
+	return c.call(ctx, "set_webhook", req, nil)
+}
+
+func (c *Client) StartReveal(ctx context.Context, req model.AdminActionRequest) error {
+	return c.call(ctx, "start_reveal", req, nil)
+}
+
+func (c *Client) RevealVotes(ctx context.Context, req model.AdminActionRequest) error {
+	return c.call(ctx, "reveal_votes", req, nil)
+}
+
+func (c *Client) ResetVotes(ctx context.Context, req model.AdminActionRequest) error {
+	return c.call(ctx, "reset_votes", req, nil)
+}
+
+func (c *Client) NextTicket(ctx context.Context, req model.AdminActionRequest) error {
+	return c.call(ctx, "next_ticket", req, nil)
+}
+
+func (c *Client) PrevTicket(ctx context.Context, req model.AdminActionRequest) error {
+	return c.call(ctx, "prev_ticket", req, nil)
+}
+
+func (c *Client) SetTicket(ctx context.Context, req model.SetTicketRequest) error {
+	return c.call(ctx, "set_ticket", req, nil)
+}
+
+func (c *Client) UpdateRoomName(ctx context.Context, req model.UpdateRoomNameRequest) error {
+	return c.call(ctx, "update_room_name", req, nil)
+}
+
+func (c *Client) StartFreeVote(ctx context.Context, req model.AdminActionRequest) error {
+	return c.call(ctx, "start_free_vote", req, nil)
+}
+
+func (c *Client) InteractPlayer(ctx context.Context, req model.InteractPlayerRequest) error {
+	return c.call(ctx, "interact_player", req, nil)
+}
+
+func (c *Client) ThemeInteract(ctx context.Context, req model.ThemeInteractRequest) error {
+	return c.call(ctx, "theme_interact", req, nil)
+}
+
+// Nudge reminds users that the room is waiting for their vote and returns the
+// IDs of the users nudged.
+func (c *Client) Nudge(ctx context.Context, req model.NudgeRequest) (*model.NudgeResponse, error) {
+	var resp model.NudgeResponse
+	if err := c.call(ctx, "nudge", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Heartbeat reports that the user behind this connection is active, so they
+// are not shown as away. Any other call counts as activity too.
+func (c *Client) Heartbeat(ctx context.Context) error {
+	return c.call(ctx, "heartbeat", struct{}{}, nil)
+}
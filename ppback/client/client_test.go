@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pockerplan/ppback/hub"
+	"pockerplan/ppback/model"
+	"pockerplan/ppback/room"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/rs/zerolog"
+)
+
+func newTestServer(t *testing.T, opts ...hub.Option) string {
+	t.Helper()
+	h, err := hub.New(room.NewManager(), 3, true, zerolog.Nop(), opts...)
+	if err != nil {
+		t.Fatalf("create hub: %v", err)
+	}
+	if err := h.Run(); err != nil {
+		t.Fatalf("run hub: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/connection/websocket", centrifuge.NewWebsocketHandler(h.Node(), centrifuge.WebsocketConfig{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(func() {
+		srv.Close()
+		_ = h.Shutdown()
+	})
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/connection/websocket"
+}
+
+func dial(t *testing.T, url string) *Client {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := Dial(ctx, url)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+// waitSnapshot reads snapshots until one satisfies cond.
+func waitSnapshot(t *testing.T, ch <-chan *model.RoomSnapshot, cond func(*model.RoomSnapshot) bool) *model.RoomSnapshot {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case snap, ok := <-ch:
+			if !ok {
+				t.Fatal("snapshot stream closed")
+			}
+			if cond(snap) {
+				return snap
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for snapshot")
+		}
+	}
+}
+
+func TestClientVotingRound(t *testing.T) {
+	url := newTestServer(t)
+	ctx := context.Background()
+	admin := dial(t, url)
+	player := dial(t, url)
+
+	created, err := admin.CreateRoom(ctx, model.CreateRoomRequest{ScaleID: "fibonacci", UserName: "Alice", AvatarID: "cat"})
+	if err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	adminReq := model.AdminActionRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret}
+
+	snaps, err := admin.Watch(ctx, created.RoomID)
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if _, err := player.Watch(ctx, created.RoomID); err != nil {
+		t.Fatalf("player watch: %v", err)
+	}
+
+	joined, err := player.JoinRoom(ctx, model.JoinRoomRequest{RoomID: created.RoomID, UserName: "Bob", AvatarID: "dog"})
+	if err != nil {
+		t.Fatalf("join room: %v", err)
+	}
+	if joined.State == nil || len(joined.State.Users) != 2 {
+		t.Fatalf("expected join state with 2 users, got %+v", joined.State)
+	}
+	waitSnapshot(t, snaps, func(s *model.RoomSnapshot) bool { return len(s.Users) == 2 })
+
+	ticket, err := admin.AddTicket(ctx, model.AddTicketRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, Content: "Login page"})
+	if err != nil {
+		t.Fatalf("add ticket: %v", err)
+	}
+	if err := admin.SetTicket(ctx, model.SetTicketRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, TicketID: ticket.TicketID}); err != nil {
+		t.Fatalf("set ticket: %v", err)
+	}
+
+	for _, v := range []struct {
+		c      *Client
+		userID string
+		value  string
+	}{{admin, created.UserID, "5"}, {player, joined.UserID, "8"}} {
+		if err := v.c.SubmitVote(ctx, model.SubmitVoteRequest{RoomID: created.RoomID, UserID: v.userID, Value: v.value}); err != nil {
+			t.Fatalf("submit vote: %v", err)
+		}
+	}
+	if err := admin.RevealVotes(ctx, adminReq); err != nil {
+		t.Fatalf("reveal: %v", err)
+	}
+
+	snap := waitSnapshot(t, snaps, func(s *model.RoomSnapshot) bool { return s.State == model.RoomStateRevealed })
+	if snap.CurrentTicketID != ticket.TicketID {
+		t.Errorf("expected current ticket %s, got %s", ticket.TicketID, snap.CurrentTicketID)
+	}
+	if votes := snap.Tickets[0].Votes; len(votes) != 2 || votes[0].Value == "" {
+		t.Errorf("expected 2 revealed votes, got %+v", votes)
+	}
+
+	got, err := player.GetRoom(ctx, created.RoomID)
+	if err != nil {
+		t.Fatalf("get room: %v", err)
+	}
+	if got.State != model.RoomStateRevealed {
+		t.Errorf("expected revealed state from GetRoom, got %s", got.State)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	url := newTestServer(t)
+	ctx := context.Background()
+	c := dial(t, url)
+
+	var apiErr *Error
+	if _, err := c.GetRoom(ctx, "missing"); !errors.As(err, &apiErr) || apiErr.Code != model.CodeRoomNotFound {
+		t.Errorf("expected room not found, got %v", err)
+	}
+
+	created, err := c.CreateRoom(ctx, model.CreateRoomRequest{ScaleID: "fibonacci", UserName: "Alice", AvatarID: "cat"})
+	if err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	err = c.RevealVotes(ctx, model.AdminActionRequest{RoomID: created.RoomID, AdminSecret: "wrong"})
+	if !errors.As(err, &apiErr) || apiErr.Code != model.CodeInvalidAdminSecret {
+		t.Errorf("expected invalid admin secret, got %v", err)
+	}
+
+	if _, err := c.Watch(ctx, "missing"); !errors.As(err, &apiErr) {
+		t.Errorf("expected subscription error for unknown room, got %v", err)
+	}
+}
+
+func TestClientWatchStopsOnCancel(t *testing.T) {
+	url := newTestServer(t)
+	c := dial(t, url)
+
+	created, err := c.CreateRoom(context.Background(), model.CreateRoomRequest{ScaleID: "fibonacci", UserName: "Alice", AvatarID: "cat"})
+	if err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	snaps, err := c.Watch(ctx, created.RoomID)
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	cancel()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-snaps:
+			if !ok {
+				// The room can be watched again once the previous stream ended.
+				if _, err := c.Watch(context.Background(), created.RoomID); err != nil {
+					t.Fatalf("re-watch: %v", err)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("stream not closed after cancel")
+		}
+	}
+}
+
+func TestRoomStateResyncsOnGap(t *testing.T) {
+	s := &stream{ch: make(chan *model.RoomSnapshot, snapshotBuffer), done: make(chan struct{})}
+	fetched := &model.RoomSnapshot{ID: "r", Revision: 3, Name: "fetched"}
+	st := &roomState{stream: s}
+	st.resync = func() { st.resynced(fetched) }
+
+	st.reset(&model.RoomSnapshot{ID: "r", Revision: 1})
+	name := func(n string) *model.RoomDelta { return &model.RoomDelta{Name: &n} }
+	st.apply(&model.RoomUpdate{Type: model.UpdateDelta, Revision: 1, Delta: name("stale")})
+	st.apply(&model.RoomUpdate{Type: model.UpdateDelta, Revision: 3, Delta: name("gap")})
+	st.apply(&model.RoomUpdate{Type: model.UpdateDelta, Revision: 4, Delta: name("after")})
+
+	snap := waitSnapshot(t, s.ch, func(s *model.RoomSnapshot) bool { return s.Revision == 4 })
+	if snap.Name != "after" {
+		t.Errorf("expected name 'after', got %q", snap.Name)
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.resyncing || len(st.pending) != 0 {
+		t.Errorf("expected resync to finish, resyncing=%v pending=%d", st.resyncing, len(st.pending))
+	}
+}
+
+func TestClientWatchAsUser(t *testing.T) {
+	url := newTestServer(t, hub.WithDisconnectGrace(0))
+	ctx := context.Background()
+
+	first := dial(t, url)
+	created, err := first.CreateRoom(ctx, model.CreateRoomRequest{ScaleID: "fibonacci", UserName: "Alice", AvatarID: "cat"})
+	if err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	first.Close()
+
+	c := dial(t, url)
+	watcher := dial(t, url)
+	snaps, err := watcher.Watch(ctx, created.RoomID)
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	waitSnapshot(t, snaps, func(s *model.RoomSnapshot) bool { return !s.Users[0].Connected })
+
+	if _, err := c.Watch(ctx, created.RoomID, AsUser(created.UserID)); err != nil {
+		t.Fatalf("watch as user: %v", err)
+	}
+	waitSnapshot(t, snaps, func(s *model.RoomSnapshot) bool { return s.Users[0].Connected })
+}
@@ -0,0 +1,238 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"pockerplan/ppback/model"
+
+	centrifuge "github.com/centrifugal/centrifuge-go"
+)
+
+// WatchOption configures a Watch subscription.
+type WatchOption func(*centrifuge.SubscriptionConfig)
+
+// AsUser ties the subscription to a user who joined the room. When the
+// connection drops and comes back, the server shows the user online again
+// without another JoinRoom.
+func AsUser(userID string) WatchOption {
+	return func(cfg *centrifuge.SubscriptionConfig) {
+		cfg.Data, _ = json.Marshal(model.SubscribeRoomData{UserID: userID})
+	}
+}
+
+// Watch subscribes to the room channel and returns a stream of room
+// snapshots, starting with the state at subscription time. The server publishes
+// deltas; Watch applies them and refetches the room when one is missed. The
+// channel is closed when ctx is done, the subscription ends on the server side
+// or the client is closed. If the consumer falls behind, the oldest snapshots
+// are dropped since each one carries the full room state. Room events are not
+// part of the state and are not delivered.
+//
+// Only one Watch per room may be active on a client.
+func (c *Client) Watch(ctx context.Context, roomID string, opts ...WatchOption) (<-chan *model.RoomSnapshot, error) {
+	var cfg centrifuge.SubscriptionConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	sub, err := c.cli.NewSubscription("room:"+roomID, cfg)
+	if err != nil {
+		if errors.Is(err, centrifuge.ErrDuplicateSubscription) {
+			return nil, fmt.Errorf("watch %s: already watching", roomID)
+		}
+		return nil, fmt.Errorf("watch %s: %w", roomID, err)
+	}
+
+	s := &stream{
+		ch:   make(chan *model.RoomSnapshot, snapshotBuffer),
+		done: make(chan struct{}),
+	}
+	state := &roomState{stream: s}
+	state.resync = func() {
+		snap, err := c.GetRoom(ctx, roomID)
+		if err != nil {
+			state.mu.Lock()
+			state.resyncing = false
+			state.mu.Unlock()
+			return
+		}
+		state.resynced(snap)
+	}
+	subscribed := make(chan error, 1)
+	var once sync.Once
+	settle := func(err error) { once.Do(func() { subscribed <- err }) }
+
+	sub.OnSubscribed(func(e centrifuge.SubscribedEvent) {
+		var update model.RoomUpdate
+		if err := json.Unmarshal(e.Data, &update); err == nil && update.Snapshot != nil {
+			// A (re)subscription always starts over: the server may have been
+			// restarted and counts revisions from zero again.
+			state.reset(update.Snapshot)
+		}
+		settle(nil)
+	})
+	sub.OnUnsubscribed(func(e centrifuge.UnsubscribedEvent) {
+		settle(&Error{Code: e.Code, Message: e.Reason})
+		s.close()
+	})
+	sub.OnPublication(func(e centrifuge.PublicationEvent) {
+		var update model.RoomUpdate
+		if err := json.Unmarshal(e.Data, &update); err != nil {
+			return
+		}
+		state.apply(&update)
+	})
+
+	stop := func() {
+		_ = sub.Unsubscribe()
+		_ = c.cli.RemoveSubscription(sub)
+		s.close()
+	}
+
+	if err := sub.Subscribe(); err != nil {
+		stop()
+		return nil, fmt.Errorf("watch %s: %w", roomID, err)
+	}
+	select {
+	case err := <-subscribed:
+		if err != nil {
+			stop()
+			return nil, err
+		}
+	case <-ctx.Done():
+		stop()
+		return nil, ctx.Err()
+	case <-c.done:
+		stop()
+		return nil, ErrClosed
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.done:
+		case <-s.done:
+		}
+		stop()
+	}()
+	return s.ch, nil
+}
+
+// roomState materializes room snapshots from the updates published on a room
+// channel.
+type roomState struct {
+	mu        sync.Mutex
+	stream    *stream
+	cur       *model.RoomSnapshot
+	pending   []*model.RoomUpdate // deltas received while resyncing
+	resyncing bool
+	resync    func() // fetches the room and calls resynced; run in a goroutine
+}
+
+func (st *roomState) reset(snap *model.RoomSnapshot) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.cur = snap
+	st.pending = nil
+	st.stream.send(snap)
+}
+
+func (st *roomState) apply(u *model.RoomUpdate) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.resyncing {
+		st.pending = append(st.pending, u)
+		return
+	}
+	st.applyLocked(u)
+}
+
+// applyLocked applies one update. Old revisions are ignored; a missing one
+// starts a resync.
+func (st *roomState) applyLocked(u *model.RoomUpdate) {
+	switch {
+	case u.Type == model.UpdateSnapshot && u.Snapshot != nil:
+		if st.cur != nil && u.Revision < st.cur.Revision {
+			return
+		}
+		st.cur = u.Snapshot
+	case u.Type == model.UpdateDelta && u.Delta != nil && st.cur != nil:
+		if u.Revision <= st.cur.Revision {
+			return
+		}
+		if u.Revision > st.cur.Revision+1 {
+			st.resyncing = true
+			st.pending = append(st.pending, u)
+			go st.resync()
+			return
+		}
+		st.cur = model.Apply(st.cur, u.Delta)
+		st.cur.Revision = u.Revision
+	default:
+		return
+	}
+	st.stream.send(st.cur)
+}
+
+// resynced installs a fetched snapshot and replays the deltas buffered while
+// it was fetched.
+func (st *roomState) resynced(snap *model.RoomSnapshot) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.resyncing = false
+	if st.cur == nil || snap.Revision >= st.cur.Revision {
+		st.cur = snap
+		st.stream.send(snap)
+	}
+	pending := st.pending
+	st.pending = nil
+	for i, u := range pending {
+		st.applyLocked(u)
+		if st.resyncing {
+			st.pending = append(st.pending, pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// stream is a snapshot channel that drops the oldest value when full and can
+// be closed from any goroutine.
+type stream struct {
+	mu     sync.Mutex
+	ch     chan *model.RoomSnapshot
+	done   chan struct{}
+	closed bool
+}
+
+func (s *stream) send(snap *model.RoomSnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for {
+		select {
+		case s.ch <- snap:
+			return
+		default:
+		}
+		select {
+		case <-s.ch:
+		default:
+		}
+	}
+}
+
+func (s *stream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.ch)
+	close(s.done)
+}
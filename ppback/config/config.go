@@ -0,0 +1,135 @@
+// Package config reads server settings from a YAML file. Keys are the names
+// of command-line flags, so every flag can be set in the file; flags and
+// environment variables take precedence over it.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"pockerplan/ppback/scale"
+
+	"github.com/alecthomas/kong"
+	"gopkg.in/yaml.v3"
+)
+
+// Loader is a kong.ConfigurationLoader for YAML configuration files. Keys may
+// be written as the flag (rpc-rate) or with underscores (rpc_rate). A flag
+// whose environment variable is set ignores the file, so the order of
+// precedence is flags, environment, file, defaults.
+func Loader(r io.Reader) (kong.Resolver, error) {
+	name := "config"
+	if f, ok := r.(interface{ Name() string }); ok {
+		name = f.Name()
+	}
+	var values map[string]any
+	if err := yaml.NewDecoder(r).Decode(&values); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	res := &resolver{path: name, values: make(map[string]any, len(values))}
+	for k, v := range values {
+		res.values[strings.ReplaceAll(k, "_", "-")] = v
+	}
+	return res, nil
+}
+
+type resolver struct {
+	path   string
+	values map[string]any // flag name -> value
+}
+
+// Validate rejects keys that do not name a flag of the application.
+func (r *resolver) Validate(app *kong.Application) error {
+	flags := map[string]bool{}
+	kong.Visit(app, func(node kong.Visitable, next kong.Next) error {
+		if f, ok := node.(*kong.Flag); ok {
+			flags[f.Name] = true
+		}
+		return next(nil)
+	})
+	var unknown []string
+	for k := range r.values {
+		if !flags[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%s: unknown settings: %s", r.path, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func (r *resolver) Resolve(ctx *kong.Context, parent *kong.Path, flag *kong.Flag) (any, error) {
+	for _, env := range flag.Envs {
+		if _, ok := os.LookupEnv(env); ok {
+			return nil, nil
+		}
+	}
+	v, ok := r.values[flag.Name]
+	if !ok {
+		return nil, nil
+	}
+	// Durations and other text values are decoded by the flag's mapper;
+	// YAML may have read them as numbers or booleans.
+	switch v := v.(type) {
+	case []any, map[string]any, string:
+		return v, nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// Scales is a flag holding custom estimation scales. On the command line and
+// in the environment it is a JSON array; in a config file, a YAML list:
+//
+//	scales:
+//	  - id: hours
+//	    name: Hours
+//	    values: ["1", "2", "4", "8", "?"]
+type Scales []scale.EstimationScale
+
+// Decode implements kong.MapperValue.
+func (s *Scales) Decode(ctx *kong.DecodeContext) error {
+	token := ctx.Scan.Pop()
+	var data []byte
+	switch v := token.Value.(type) {
+	case string:
+		data = []byte(v)
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return fmt.Errorf("scales: %w", err)
+		}
+	}
+	// Values are decoded loosely so that numeric cards need no quotes.
+	var raw []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Values []any  `json:"values"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("scales must be a list of {id, name, values}: %w", err)
+	}
+	list := make([]scale.EstimationScale, len(raw))
+	for i, r := range raw {
+		list[i] = scale.EstimationScale{ID: r.ID, Name: r.Name, Values: make([]string, len(r.Values))}
+		for j, v := range r.Values {
+			switch v.(type) {
+			case string, float64, bool:
+				list[i].Values[j] = fmt.Sprint(v)
+			default:
+				return fmt.Errorf("scale %q: value #%d is not a string or number", r.ID, j+1)
+			}
+		}
+	}
+	if err := scale.Validate(list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+)
+
+type testCLI struct {
+	Config   kong.ConfigFlag `type:"existingfile"`
+	Rate     float64         `default:"20" env:"TEST_RATE"`
+	RoomTTL  time.Duration   `default:"24h"`
+	Tickets  bool
+	Webhooks []string
+	Label    string
+	Scales   Scales
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func parse(t *testing.T, args ...string) (*testCLI, error) {
+	t.Helper()
+	var cli testCLI
+	parser, err := kong.New(&cli, kong.Configuration(Loader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = parser.Parse(args)
+	return &cli, err
+}
+
+func TestLoader(t *testing.T) {
+	path := writeConfig(t, `
+rate: 5
+room_ttl: 48h
+tickets: true
+webhooks: [http://a, http://b]
+label: 42
+scales:
+  - id: hours
+    name: Hours
+    values: [1, 2, 4, 8, "?"]
+`)
+	cli, err := parse(t, "--config", path)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cli.Rate != 5 || cli.RoomTTL != 48*time.Hour || !cli.Tickets || cli.Label != "42" {
+		t.Errorf("unexpected settings %+v", cli)
+	}
+	if strings.Join(cli.Webhooks, ",") != "http://a,http://b" {
+		t.Errorf("unexpected webhooks %v", cli.Webhooks)
+	}
+	if len(cli.Scales) != 1 || strings.Join(cli.Scales[0].Values, ",") != "1,2,4,8,?" {
+		t.Errorf("unexpected scales %+v", cli.Scales)
+	}
+}
+
+func TestLoaderPrecedence(t *testing.T) {
+	path := writeConfig(t, "rate: 5\n")
+
+	cli, err := parse(t, "--config", path, "--rate", "7")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cli.Rate != 7 {
+		t.Errorf("expected flag to win, got %v", cli.Rate)
+	}
+
+	t.Setenv("TEST_RATE", "6")
+	cli, err = parse(t, "--config", path)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cli.Rate != 6 {
+		t.Errorf("expected environment to win, got %v", cli.Rate)
+	}
+}
+
+func TestLoaderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", "rate: 5\nrtae: 6\nfoo: 1\n", "unknown settings: foo, rtae"},
+		{"syntax", "rate: [5\n", "config.yaml: yaml: line"},
+		{"bad value", "room-ttl: soon\n", "--room-ttl"},
+		{"bad scale", "scales:\n  - id: hours\n    name: Hours\n    values: [1, 1]\n", `value "1" appears twice`},
+		{"scale not a list", "scales: hours\n", "scales must be a list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(t, "--config", writeConfig(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
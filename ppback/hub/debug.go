@@ -0,0 +1,134 @@
+package hub
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// redactedKeys are JSON fields whose values never appear in debug logs.
+var redactedKeys = map[string]bool{
+	"adminSecret": true,
+	"secret":      true,
+	"token":       true,
+}
+
+// SetRoomDebug logs every RPC of the room with its request and reply for the
+// duration d, regardless of the log level. Secrets are redacted. A zero or
+// negative duration turns debug logging off.
+func (h *Hub) SetRoomDebug(roomID string, d time.Duration) error {
+	if _, err := h.rooms.Get(roomID); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if d <= 0 {
+		delete(h.debugRooms, roomID)
+	} else {
+		h.debugRooms[roomID] = time.Now().Add(d)
+	}
+	h.logger.Info().
+		Str("room_id", roomID).
+		Dur("duration", d).
+		Msg("room debug logging changed")
+	return nil
+}
+
+// RoomDebugUntil returns when debug logging of the room ends, or the zero time
+// when it is off.
+func (h *Hub) RoomDebugUntil(roomID string) (time.Time, error) {
+	if _, err := h.rooms.Get(roomID); err != nil {
+		return time.Time{}, err
+	}
+	h.mu.RLock()
+	until := h.debugRooms[roomID]
+	h.mu.RUnlock()
+	if !until.After(time.Now()) {
+		return time.Time{}, nil
+	}
+	return until, nil
+}
+
+// roomDebug reports whether debug logging is on for the room and drops the
+// entry once it has expired.
+func (h *Hub) roomDebug(roomID string) bool {
+	if roomID == "" {
+		return false
+	}
+	h.mu.RLock()
+	until, ok := h.debugRooms[roomID]
+	h.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	if time.Now().Before(until) {
+		return true
+	}
+	h.mu.Lock()
+	if h.debugRooms[roomID] == until {
+		delete(h.debugRooms, roomID)
+	}
+	h.mu.Unlock()
+	return false
+}
+
+// logRoomDebug logs the payloads of RPCs in rooms with debug logging on. The
+// entries bypass the log level so that one room can be inspected without
+// raising the level for the whole server.
+func (h *Hub) logRoomDebug(next HandlerFunc) HandlerFunc {
+	return func(c *Call, data []byte) ([]byte, error) {
+		start := time.Now()
+		reply, err := next(c, data)
+		if !h.roomDebug(c.RoomID) {
+			return reply, err
+		}
+		ev := h.logger.Log().
+			Str("method", c.Method).
+			Str("client", c.ClientID).
+			Str("room_id", c.RoomID).
+			Str("user_id", c.UserID).
+			RawJSON("request", redactJSON(data)).
+			Dur("duration", time.Since(start))
+		if err != nil {
+			ev = ev.AnErr("rpc_error", err)
+		} else {
+			ev = ev.RawJSON("reply", redactJSON(reply))
+		}
+		ev.Msg("room debug")
+		return reply, err
+	}
+}
+
+// redactJSON replaces the values of secret fields anywhere in a JSON document.
+// Data that is not valid JSON is replaced as a whole.
+func redactJSON(data []byte) []byte {
+	if len(data) == 0 {
+		return []byte(`null`)
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []byte(`"[invalid JSON]"`)
+	}
+	out, err := json.Marshal(redact(v))
+	if err != nil {
+		return []byte(`"[invalid JSON]"`)
+	}
+	return out
+}
+
+func redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if redactedKeys[k] {
+				v[k] = "[redacted]"
+			} else {
+				v[k] = redact(val)
+			}
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = redact(val)
+		}
+	}
+	return v
+}
@@ -0,0 +1,107 @@
+package hub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pockerplan/ppback/model"
+	"pockerplan/ppback/room"
+
+	"github.com/rs/zerolog"
+)
+
+// lockedBuffer is a bytes.Buffer safe for concurrent log writes.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRoomDebugLogging(t *testing.T) {
+	var logs lockedBuffer
+	rm := room.NewManager()
+	// Debug entries must show up even though the logger only passes errors.
+	h, err := New(rm, 3, false, zerolog.New(&logs).Level(zerolog.ErrorLevel), WithBroadcastWindow(0))
+	if err != nil {
+		t.Fatalf("create hub: %v", err)
+	}
+	if err := h.Run(); err != nil {
+		t.Fatalf("run hub: %v", err)
+	}
+	t.Cleanup(func() { _ = h.Shutdown() })
+
+	debugged, _ := rm.Create("fibonacci", 3)
+	other, _ := rm.Create("fibonacci", 3)
+	call := func(r *model.Room) {
+		data, _ := json.Marshal(model.AdminActionRequest{RoomID: r.ID, AdminSecret: r.AdminSecret})
+		if _, err := h.Call(context.Background(), "start_free_vote", data); err != nil {
+			t.Fatalf("start_free_vote: %v", err)
+		}
+	}
+
+	call(debugged)
+	if logs.String() != "" {
+		t.Fatalf("expected no logs before debug is on, got %s", logs.String())
+	}
+
+	if err := h.SetRoomDebug(debugged.ID, time.Minute); err != nil {
+		t.Fatalf("set debug: %v", err)
+	}
+	call(debugged)
+	call(other)
+
+	out := logs.String()
+	if strings.Count(out, `"message":"room debug"`) != 1 {
+		t.Fatalf("expected one debug entry, got %s", out)
+	}
+	if !strings.Contains(out, debugged.ID) || strings.Contains(out, other.ID) {
+		t.Errorf("expected only the debugged room to be logged, got %s", out)
+	}
+	if strings.Contains(out, debugged.AdminSecret) || !strings.Contains(out, "[redacted]") {
+		t.Errorf("expected the admin secret to be redacted, got %s", out)
+	}
+
+	if err := h.SetRoomDebug(debugged.ID, 0); err != nil {
+		t.Fatalf("clear debug: %v", err)
+	}
+	if until, _ := h.RoomDebugUntil(debugged.ID); !until.IsZero() {
+		t.Errorf("expected debug off, got until %v", until)
+	}
+	if err := h.SetRoomDebug("missing", time.Minute); err == nil {
+		t.Error("expected error for a missing room")
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`{"roomId":"r","adminSecret":"s"}`, `{"adminSecret":"[redacted]","roomId":"r"}`},
+		{`{"webhook":{"url":"u","secret":"s"}}`, `{"webhook":{"secret":"[redacted]","url":"u"}}`},
+		{`[{"token":"t"}]`, `[{"token":"[redacted]"}]`},
+		{`not json`, `"[invalid JSON]"`},
+		{``, `null`},
+	}
+	for _, tt := range tests {
+		if got := string(redactJSON([]byte(tt.in))); got != tt.want {
+			t.Errorf("redactJSON(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
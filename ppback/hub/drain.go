@@ -0,0 +1,127 @@
+package hub
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"pockerplan/ppback/model"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+// drainDisconnectWait bounds how long Drain waits for clients to go away
+// after telling them to disconnect.
+const drainDisconnectWait = 2 * time.Second
+
+// drainBackgroundWait bounds how long Drain waits for estimate syncs already
+// running; Shutdown interrupts those still going.
+const drainBackgroundWait = 10 * time.Second
+
+// Drain prepares the hub for shutdown and blocks until clients are
+// disconnected. Readiness fails at once and new connections, rooms and joins
+// are refused. The clients of this process get a "server" event with action
+// "restarting" on their room channels that tells when connections close,
+// notice from now, and when the server is expected back, eta after that. Once the notice is over, room changes still
+// waiting for their broadcast window are published and all clients are
+// disconnected with model.DisconnectRestarting, which tells them to reconnect.
+// Before that, Drain waits for estimate syncs that are still running.
+//
+// Room changes are saved as they happen, so there is nothing else to flush.
+// In-memory rooms do not survive a restart of the process. Rooms in a shared
+// store outlive it and other processes take over its clients; the event is
+// delivered locally rather than published, so clients of other processes in
+// the same rooms do not see it. Calls after the first return at once.
+func (h *Hub) Drain(notice, eta time.Duration) {
+	if !h.draining.CompareAndSwap(false, true) {
+		return
+	}
+	disconnectAt := time.Now().Add(notice)
+	payload, err := json.Marshal(model.ServerRestartingPayload{
+		DisconnectAt: disconnectAt.UTC(),
+		BackAt:       disconnectAt.Add(eta).UTC(),
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msg("marshal restart notice")
+	}
+	rooms := h.localRooms()
+	h.logger.Info().
+		Int("rooms", len(rooms)).
+		Dur("notice", notice).
+		Dur("eta", eta).
+		Msg("draining")
+	for _, id := range rooms {
+		h.deliverLocalEvent(id, model.RoomEvent{
+			Type:    "server",
+			Action:  "restarting",
+			Payload: payload,
+		})
+	}
+
+	time.Sleep(notice)
+	if !h.waitBackground(drainBackgroundWait) {
+		h.logger.Warn().Dur("waited", drainBackgroundWait).Msg("estimate syncs still running")
+	}
+	h.flushBroadcasts()
+
+	for _, c := range h.node.Hub().Connections() {
+		c.Disconnect(disconnectRestarting)
+	}
+	deadline := time.Now().Add(drainDisconnectWait)
+	for h.node.Hub().NumClients() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	h.logger.Info().Int("remaining", h.node.Hub().NumClients()).Msg("clients disconnected")
+}
+
+// flushBroadcasts publishes the room changes that are still waiting for their
+// broadcast window.
+func (h *Hub) flushBroadcasts() {
+	var pending []string
+	h.mu.RLock()
+	for id, s := range h.streams {
+		s.mu.Lock()
+		if s.timer != nil {
+			pending = append(pending, id)
+		}
+		s.mu.Unlock()
+	}
+	h.mu.RUnlock()
+	for _, id := range pending {
+		h.publishRoomState(id)
+	}
+}
+
+// localRooms returns the IDs of the rooms that clients of this process are
+// subscribed to.
+func (h *Hub) localRooms() []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, c := range h.node.Hub().Connections() {
+		for _, ch := range c.Channels() {
+			id, ok := strings.CutPrefix(ch, "room:")
+			if ok && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// deliverLocalEvent sends a room event to the subscribers connected to this
+// process only, bypassing the broker that other processes share.
+func (h *Hub) deliverLocalEvent(roomID string, ev model.RoomEvent) {
+	stampEvent(&ev)
+	data, err := json.Marshal(model.RoomUpdate{Type: model.UpdateEvent, Revision: h.revision(roomID), Event: &ev})
+	if err != nil {
+		h.logger.Error().Err(err).Msg("marshal room event")
+		return
+	}
+	err = h.node.HandlePublication("room:"+roomID, &centrifuge.Publication{Data: data}, centrifuge.StreamPosition{}, false, nil)
+	if err != nil {
+		h.logger.Error().Err(err).Str("room", roomID).Msg("deliver room event")
+		return
+	}
+	h.observeBroadcast(string(model.UpdateEvent), len(data))
+}
@@ -0,0 +1,126 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"pockerplan/ppback/model"
+
+	"github.com/alicebob/miniredis/v2"
+	centrifugecli "github.com/centrifugal/centrifuge-go"
+)
+
+func TestDrain(t *testing.T) {
+	env := newTestEnv(t)
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	events := make(chan *model.RoomEvent, 10)
+	subscribeRoom(t, client, created.RoomID, func(data []byte) {
+		var update model.RoomUpdate
+		if json.Unmarshal(data, &update) == nil && update.Type == model.UpdateEvent {
+			events <- update.Event
+		}
+	})
+	reconnecting := make(chan centrifugecli.ConnectingEvent, 10)
+	client.OnConnecting(func(e centrifugecli.ConnectingEvent) { reconnecting <- e })
+
+	start := time.Now()
+	drained := make(chan struct{})
+	go func() {
+		env.hub.Drain(200*time.Millisecond, time.Minute)
+		close(drained)
+	}()
+
+	select {
+	case ev := <-events:
+		if ev.Type != "server" || ev.Action != "restarting" {
+			t.Fatalf("expected restart notice, got %+v", ev)
+		}
+		var p model.ServerRestartingPayload
+		if err := json.Unmarshal(ev.Payload, &p); err != nil {
+			t.Fatalf("unmarshal payload: %v", err)
+		}
+		if p.DisconnectAt.Before(start) || p.BackAt.Sub(p.DisconnectAt) != time.Minute {
+			t.Errorf("unexpected restart times %+v", p)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for restart notice")
+	}
+
+	// During the notice the connection still works, but nobody new gets in.
+	for method, req := range map[string]any{
+		"create_room": model.CreateRoomRequest{ScaleID: "fibonacci", UserName: "Bob", AvatarID: "dog"},
+		"join_room":   model.JoinRoomRequest{RoomID: created.RoomID, UserName: "Bob", AvatarID: "dog"},
+	} {
+		data, _ := json.Marshal(req)
+		_, err := client.RPC(context.Background(), method, data)
+		var rpcErr *centrifugecli.Error
+		if !errors.As(err, &rpcErr) || rpcErr.Code != model.CodeServerDraining {
+			t.Errorf("%s: expected code %d, got %v", method, model.CodeServerDraining, err)
+		}
+	}
+
+	select {
+	case <-drained:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for Drain")
+	}
+	if time.Since(start) < 200*time.Millisecond {
+		t.Error("expected Drain to wait for the notice")
+	}
+	select {
+	case e := <-reconnecting:
+		if e.Code != model.DisconnectRestarting {
+			t.Errorf("expected disconnect code %d, got %+v", model.DisconnectRestarting, e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for disconnect")
+	}
+	if n := env.hub.Node().Hub().NumClients(); n != 0 {
+		t.Errorf("expected no clients after drain, got %d", n)
+	}
+}
+
+func TestDrainSharedWarnsOwnClients(t *testing.T) {
+	mr := miniredis.RunT(t)
+	envA := newRedisTestEnv(t, mr, WithBroadcastWindow(0))
+	envB := newRedisTestEnv(t, mr, WithBroadcastWindow(0))
+
+	clientA := envA.newClient(t)
+	created := rpcCreateRoom(t, clientA, "fibonacci", "Alice", "cat")
+	clientB := envB.newClient(t)
+	rpcJoinRoom(t, clientB, created.RoomID, "Bob", "dog", "")
+
+	watch := func(client *centrifugecli.Client) <-chan *model.RoomEvent {
+		events := make(chan *model.RoomEvent, 10)
+		subscribeRoom(t, client, created.RoomID, func(data []byte) {
+			var update model.RoomUpdate
+			if json.Unmarshal(data, &update) == nil && update.Type == model.UpdateEvent {
+				events <- update.Event
+			}
+		})
+		return events
+	}
+	eventsA := watch(clientA)
+	eventsB := watch(clientB)
+
+	go envA.hub.Drain(200*time.Millisecond, time.Minute)
+
+	select {
+	case ev := <-eventsA:
+		if ev.Type != "server" || ev.Action != "restarting" {
+			t.Fatalf("expected restart notice, got %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for restart notice on the draining server")
+	}
+	select {
+	case ev := <-eventsB:
+		t.Errorf("client of the other server got %+v", ev)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
@@ -0,0 +1,60 @@
+package hub
+
+import (
+	"errors"
+
+	"pockerplan/ppback/campfire"
+	"pockerplan/ppback/model"
+	"pockerplan/ppback/room"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+// domainErrors assigns every room and campfire error its application code.
+var domainErrors = []struct {
+	err  error
+	code uint32
+}{
+	{room.ErrRoomNotFound, model.CodeRoomNotFound},
+	{room.ErrUserNotFound, model.CodeUserNotFound},
+	{room.ErrTicketNotFound, model.CodeTicketNotFound},
+	{room.ErrInvalidAdmin, model.CodeInvalidAdminSecret},
+	{room.ErrNotVoting, model.CodeNotVoting},
+	{room.ErrNoCurrentTicket, model.CodeNoCurrentTicket},
+	{room.ErrInvalidVote, model.CodeInvalidVote},
+	{campfire.ErrTreeNotFound, model.CodeTreeNotFound},
+	{campfire.ErrTreeAlreadyBurned, model.CodeTreeAlreadyBurned},
+	{campfire.ErrNoCampfire, model.CodeNoCampfire},
+}
+
+var errorTrackerFailed = &centrifuge.Error{Code: model.CodeTrackerFailed, Message: "issue tracker request failed"}
+
+var errorServerDraining = &centrifuge.Error{Code: model.CodeServerDraining, Message: "server is restarting", Temporary: true}
+
+// errorRoomBusy is returned when a shared room stays locked by other requests
+// for too long. Unlike domain errors it is temporary: the same call may succeed
+// when repeated.
+var errorRoomBusy = &centrifuge.Error{Code: model.CodeRoomBusy, Message: "room is busy", Temporary: true}
+
+// disconnectRestarting closes connections while the server drains. Clients
+// reconnect, to another instance or once this one is back.
+var disconnectRestarting = centrifuge.Disconnect{Code: model.DisconnectRestarting, Reason: "server restarting"}
+
+// rpcError converts an error returned inside WithRoom into the error sent to
+// the client. Errors that are already client errors pass through; unknown
+// errors become an internal error so that their details stay on the server.
+func rpcError(err error) error {
+	var ce *centrifuge.Error
+	if errors.As(err, &ce) {
+		return ce
+	}
+	if errors.Is(err, room.ErrLockTimeout) {
+		return errorRoomBusy
+	}
+	for _, d := range domainErrors {
+		if errors.Is(err, d.err) {
+			return &centrifuge.Error{Code: d.code, Message: d.err.Error()}
+		}
+	}
+	return centrifuge.ErrorInternal
+}
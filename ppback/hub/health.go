@@ -0,0 +1,79 @@
+package hub
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// loopStaleAfter is how many intervals a background loop may miss before it
+// is reported as stuck.
+const loopStaleAfter = 3
+
+// storePingTimeout bounds how long readiness waits for a shared room store.
+const storePingTimeout = time.Second
+
+// Check is the outcome of one readiness check.
+type Check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Readiness tells whether the server should receive new traffic.
+type Readiness struct {
+	Ready  bool    `json:"ready"`
+	Checks []Check `json:"checks"`
+}
+
+// Readiness checks that the Centrifuge node runs, that the hub is not
+// draining, that a shared room store answers and that the cleanup and
+// campfire loops, once started, still tick.
+func (h *Hub) Readiness() Readiness {
+	now := time.Now()
+	checks := []Check{{Name: "node", OK: h.running.Load()}}
+	if !checks[0].OK {
+		checks[0].Detail = "not running"
+	}
+
+	drain := Check{Name: "draining", OK: !h.draining.Load()}
+	if !drain.OK {
+		drain.Detail = "shutting down"
+	}
+	checks = append(checks, drain)
+
+	if h.rooms.Shared() {
+		ctx, cancel := context.WithTimeout(context.Background(), storePingTimeout)
+		store := Check{Name: "store", OK: true}
+		if err := h.rooms.Ping(ctx); err != nil {
+			store = Check{Name: "store", Detail: err.Error()}
+		}
+		cancel()
+		checks = append(checks, store)
+	}
+
+	if last, every := h.rooms.CleanupHeartbeat(); every > 0 {
+		checks = append(checks, loopCheck("cleanup_loop", now, last, every))
+	}
+	if every := time.Duration(h.campfireEvery.Load()); every > 0 {
+		last := time.Unix(0, h.campfireBeat.Load())
+		checks = append(checks, loopCheck("campfire_loop", now, last, every))
+	}
+
+	r := Readiness{Ready: true, Checks: checks}
+	for _, c := range checks {
+		r.Ready = r.Ready && c.OK
+	}
+	return r
+}
+
+// loopCheck reports a loop that ticks every interval as stuck when its last
+// tick is more than loopStaleAfter intervals ago.
+func loopCheck(name string, now, last time.Time, every time.Duration) Check {
+	age := now.Sub(last).Round(time.Millisecond)
+	return Check{
+		Name:   name,
+		OK:     age <= loopStaleAfter*every,
+		Detail: fmt.Sprintf("last run %v ago, every %v", age, every),
+	}
+}
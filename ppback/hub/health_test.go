@@ -0,0 +1,31 @@
+package hub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadiness(t *testing.T) {
+	env := newTestEnv(t)
+	r := env.hub.Readiness()
+	if !r.Ready || len(r.Checks) != 2 {
+		t.Fatalf("expected ready with node and draining checks, got %+v", r)
+	}
+
+	// A campfire loop that has missed its ticks fails readiness.
+	env.hub.campfireEvery.Store(int64(time.Second))
+	env.hub.campfireBeat.Store(time.Now().Add(-loopStaleAfter*time.Second - time.Second).UnixNano())
+	r = env.hub.Readiness()
+	if r.Ready {
+		t.Errorf("expected stuck campfire loop to fail readiness, got %+v", r)
+	}
+	env.hub.campfireBeat.Store(time.Now().UnixNano())
+	if r = env.hub.Readiness(); !r.Ready {
+		t.Errorf("expected ready after a tick, got %+v", r)
+	}
+
+	env.hub.Drain(0, 0)
+	if r = env.hub.Readiness(); r.Ready {
+		t.Error("expected draining hub not to be ready")
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
@@ -15,13 +18,51 @@ import (
 	"pockerplan/ppback/model"
 	"pockerplan/ppback/room"
 	"pockerplan/ppback/scale"
+	"pockerplan/ppback/tracker"
+	"pockerplan/ppback/webhook"
 
 	"github.com/centrifugal/centrifuge"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var errorNotFound = &centrifuge.Error{Code: 404, Message: "not found"}
+var tracer = otel.Tracer("pockerplan/ppback/hub")
+
+const (
+	// importTimeout bounds a single issue tracker search.
+	importTimeout = 20 * time.Second
+
+	// syncAttempts and syncTimeout bound writing one estimate back to the tracker.
+	syncAttempts = 3
+	syncTimeout  = 10 * time.Second
+
+	// Room channels keep recent publications so that a client reconnecting
+	// within historyTTL receives the updates it missed. Stream metadata lives
+	// as long as an idle room may, so the stream epoch survives quiet periods.
+	historySize    = 100
+	historyTTL     = 5 * time.Minute
+	historyMetaTTL = 24 * time.Hour
+
+	// defaultBroadcastWindow is how long room changes are collected before
+	// they are published together.
+	defaultBroadcastWindow = 50 * time.Millisecond
+
+	// defaultDisconnectGrace is how long a user whose last connection dropped
+	// is still shown online, so that a short network blip goes unnoticed.
+	defaultDisconnectGrace = 5 * time.Second
+
+	// defaultIdleTimeout is how long a connected user may go without any RPC
+	// or heartbeat before they are shown as away.
+	defaultIdleTimeout = 2 * time.Minute
+
+	// nudgeCooldown is the minimum time between two nudges to the same user.
+	nudgeCooldown = 30 * time.Second
+)
 
 // clientInfo stores the mapping from a centrifuge client to the app-level user/room.
 type clientInfo struct {
@@ -33,11 +74,104 @@ type clientInfo struct {
 type Hub struct {
 	node           *centrifuge.Node
 	rooms          *room.Manager
-	countdown      int
+	countdown      int // reveal countdown of new rooms, guarded by mu
 	ticketsEnabled bool
 	logger         zerolog.Logger
 	mu             sync.RWMutex
-	clients        map[string]clientInfo // centrifuge client ID -> clientInfo
+	clients        map[string]clientInfo      // centrifuge client ID -> clientInfo
+	streams        map[string]*roomStream     // room ID -> published state
+	window         time.Duration              // broadcast coalescing window; 0 publishes immediately
+	grace          time.Duration              // delay before a disconnected user is marked offline
+	nudged         map[clientInfo]time.Time   // last nudge sent to a room member
+	offline        map[clientInfo]*time.Timer // pending offline transitions
+	idle           time.Duration              // inactivity before a connected user is shown away; 0 disables
+	active         map[clientInfo]time.Time   // last activity written to a room member
+	closing        bool                       // set by Shutdown; disconnects no longer wait for the grace period
+	issues         tracker.IssueProvider      // nil when no issue tracker is configured
+	syncBackoff    time.Duration              // initial delay between estimate sync retries
+	background     sync.WaitGroup             // work that outlives its RPC, such as estimate syncs
+	bgCtx          context.Context            // cancelled by Shutdown to interrupt background work
+	bgCancel       context.CancelFunc
+	bgClosed       bool // set by Drain and Shutdown; no new background work is started, guarded by mu
+	webhooks       *webhook.Dispatcher
+	methodsMu      sync.RWMutex
+	methods        map[string]HandlerFunc // RPC method name -> handler wrapped in middleware
+	middleware     []Middleware
+	limiter        *rateLimiter
+	metricsReg     prometheus.Registerer
+	metrics        *metrics             // nil without WithMetrics
+	debugRooms     map[string]time.Time // room ID -> end of debug logging
+	nodeLogLevel   centrifuge.LogLevel
+	redisAddr      string // Redis for the broker and presence, empty for in-memory
+	redisPrefix    string
+	running        atomic.Bool  // node is running
+	draining       atomic.Bool  // set by Drain
+	campfireEvery  atomic.Int64 // campfire loop interval; 0 until started
+	campfireBeat   atomic.Int64 // unix nanoseconds of the last campfire tick
+}
+
+// Option configures optional Hub features.
+type Option func(*Hub)
+
+// WithIssueProvider enables importing tickets from an external issue tracker.
+func WithIssueProvider(p tracker.IssueProvider) Option {
+	return func(h *Hub) {
+		h.issues = p
+	}
+}
+
+// WithBroadcastWindow sets how long room changes are collected before one
+// publication carries them all. Zero publishes every change immediately.
+func WithBroadcastWindow(d time.Duration) Option {
+	return func(h *Hub) {
+		h.window = d
+	}
+}
+
+// WithDisconnectGrace sets how long a user stays online after their last
+// connection drops. Reconnecting within that time cancels the transition. Zero
+// marks users offline immediately.
+func WithDisconnectGrace(d time.Duration) Option {
+	return func(h *Hub) {
+		h.grace = d
+	}
+}
+
+// WithIdleTimeout sets how long a connected user may be inactive before they
+// are shown as away. Any RPC from the user's connection, including heartbeat,
+// counts as activity. Zero disables away detection.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(h *Hub) {
+		h.idle = d
+	}
+}
+
+// WithWebhooks sets the dispatcher used for outgoing webhooks. Without it the
+// hub creates a dispatcher with no endpoints and per-room webhooks disabled.
+func WithWebhooks(d *webhook.Dispatcher) Option {
+	return func(h *Hub) {
+		h.webhooks = d
+	}
+}
+
+// WithRedis makes the hub share its channels with other processes through
+// the Redis server at addr: publications and their history go through a Redis
+// broker and channel presence is kept in Redis. Keys start with prefix. Use it
+// together with a room.Manager on a room.RedisStore, so that every process
+// sees the same rooms.
+func WithRedis(addr, prefix string) Option {
+	return func(h *Hub) {
+		h.redisAddr = addr
+		h.redisPrefix = prefix
+	}
+}
+
+// WithCentrifugeLogLevel sets the level of the centrifuge node's own log
+// messages. The default is centrifuge.LogLevelInfo.
+func WithCentrifugeLogLevel(lvl centrifuge.LogLevel) Option {
+	return func(h *Hub) {
+		h.nodeLogLevel = lvl
+	}
 }
 
 // centrifugeLogLevel maps centrifuge log levels to zerolog levels.
@@ -59,9 +193,34 @@ func centrifugeLogLevel(lvl centrifuge.LogLevel) zerolog.Level {
 }
 
 // New creates and configures a new Hub.
-func New(rm *room.Manager, countdown int, ticketsEnabled bool, logger zerolog.Logger) (*Hub, error) {
+func New(rm *room.Manager, countdown int, ticketsEnabled bool, logger zerolog.Logger, opts ...Option) (*Hub, error) {
+	h := &Hub{
+		rooms:          rm,
+		countdown:      countdown,
+		ticketsEnabled: ticketsEnabled,
+		logger:         logger,
+		clients:        make(map[string]clientInfo),
+		streams:        make(map[string]*roomStream),
+		window:         defaultBroadcastWindow,
+		grace:          defaultDisconnectGrace,
+		idle:           defaultIdleTimeout,
+		offline:        make(map[clientInfo]*time.Timer),
+		nudged:         make(map[clientInfo]time.Time),
+		active:         make(map[clientInfo]time.Time),
+		syncBackoff:    time.Second,
+		methods:        make(map[string]HandlerFunc),
+		debugRooms:     make(map[string]time.Time),
+		nodeLogLevel:   centrifuge.LogLevelInfo,
+		limiter:        newRateLimiter(0, 0),
+	}
+	h.bgCtx, h.bgCancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(h)
+	}
+
 	node, err := centrifuge.New(centrifuge.Config{
-		LogLevel: centrifuge.LogLevelInfo,
+		HistoryMetaTTL: historyMetaTTL,
+		LogLevel:       h.nodeLogLevel,
 		LogHandler: func(e centrifuge.LogEntry) {
 			logger.WithLevel(centrifugeLogLevel(e.Level)).
 				Fields(e.Fields).
@@ -71,17 +230,38 @@ func New(rm *room.Manager, countdown int, ticketsEnabled bool, logger zerolog.Lo
 	if err != nil {
 		return nil, fmt.Errorf("create centrifuge node: %w", err)
 	}
+	h.node = node
+	if h.redisAddr != "" {
+		if err := h.useRedis(); err != nil {
+			return nil, err
+		}
+	}
+	if h.webhooks == nil {
+		h.webhooks = webhook.New(webhook.Config{}, logger)
+	}
 
-	h := &Hub{
-		node:           node,
-		rooms:          rm,
-		countdown:      countdown,
-		ticketsEnabled: ticketsEnabled,
-		logger:         logger,
-		clients:        make(map[string]clientInfo),
+	if h.metricsReg != nil {
+		if h.metrics, err = newMetrics(h, h.metricsReg); err != nil {
+			return nil, fmt.Errorf("register metrics: %w", err)
+		}
+	}
+
+	builtin := []Middleware{h.logCalls, h.logRoomDebug}
+	if h.metrics != nil {
+		builtin = append(builtin, h.measureCalls)
+	}
+	builtin = append(builtin, h.limitCalls, h.trackActivity)
+	h.middleware = append(builtin, h.middleware...)
+	for _, m := range h.builtinMethods() {
+		if err := h.Register(m); err != nil {
+			return nil, err
+		}
 	}
 
 	node.OnConnecting(func(ctx context.Context, e centrifuge.ConnectEvent) (centrifuge.ConnectReply, error) {
+		if h.draining.Load() {
+			return centrifuge.ConnectReply{}, disconnectRestarting
+		}
 		return centrifuge.ConnectReply{
 			Credentials: &centrifuge.Credentials{
 				UserID: "",
@@ -91,20 +271,65 @@ func New(rm *room.Manager, countdown int, ticketsEnabled bool, logger zerolog.Lo
 
 	node.OnConnect(func(client *centrifuge.Client) {
 		client.OnSubscribe(func(e centrifuge.SubscribeEvent, cb centrifuge.SubscribeCallback) {
+			// A user channel carries private events such as nudges. Only a
+			// connection registered for that user may read it.
+			if userID, ok := strings.CutPrefix(e.Channel, "user:"); ok {
+				h.mu.RLock()
+				info, registered := h.clients[client.ID()]
+				h.mu.RUnlock()
+				if !registered || info.UserID != userID {
+					cb(centrifuge.SubscribeReply{}, centrifuge.ErrorPermissionDenied)
+					return
+				}
+				cb(centrifuge.SubscribeReply{}, nil)
+				return
+			}
 			if !strings.HasPrefix(e.Channel, "room:") {
 				cb(centrifuge.SubscribeReply{}, centrifuge.ErrorPermissionDenied)
 				return
 			}
 			roomID := strings.TrimPrefix(e.Channel, "room:")
-			if _, err := rm.Get(roomID); err != nil {
+			var sd model.SubscribeRoomData
+			if len(e.Data) > 0 {
+				if err := json.Unmarshal(e.Data, &sd); err != nil {
+					cb(centrifuge.SubscribeReply{}, centrifuge.ErrorBadRequest)
+					return
+				}
+			}
+			reconnected := sd.UserID != "" && h.reconnect(client.ID(), sd.UserID, roomID)
+
+			snap, err := h.currentSnapshot(roomID)
+			if err != nil {
 				cb(centrifuge.SubscribeReply{}, centrifuge.ErrorPermissionDenied)
 				return
 			}
-			cb(centrifuge.SubscribeReply{}, nil)
+			// The subscriber starts from a full snapshot and applies the deltas
+			// published after it. With recovery on, a resubscribing client also
+			// gets the publications it missed; those at or below the snapshot
+			// revision are skipped by the client.
+			data, err := json.Marshal(model.RoomUpdate{Type: model.UpdateSnapshot, Revision: snap.Revision, Snapshot: snap})
+			if err != nil {
+				cb(centrifuge.SubscribeReply{}, centrifuge.ErrorInternal)
+				return
+			}
+			opts := centrifuge.SubscribeOptions{
+				EnableRecovery: true,
+				Data:           data,
+			}
+			if h.rooms.Shared() && sd.UserID != "" {
+				// Other processes look up the user's connections in the
+				// channel presence before marking them offline.
+				opts.EmitPresence = true
+				opts.ChannelInfo, _ = json.Marshal(sd)
+			}
+			cb(centrifuge.SubscribeReply{Options: opts}, nil)
+			if reconnected {
+				h.broadcastRoomState(roomID)
+			}
 		})
 
 		client.OnRPC(func(e centrifuge.RPCEvent, cb centrifuge.RPCCallback) {
-			reply, err := h.handleRPC(client, e.Method, e.Data)
+			reply, err := h.handleRPC(client.Context(), client.ID(), e.Method, e.Data)
 			if err != nil {
 				cb(centrifuge.RPCReply{}, err)
 				return
@@ -120,6 +345,27 @@ func New(rm *room.Manager, countdown int, ticketsEnabled bool, logger zerolog.Lo
 	return h, nil
 }
 
+// useRedis replaces the node's in-memory broker and presence manager with
+// Redis ones.
+func (h *Hub) useRedis() error {
+	shard, err := centrifuge.NewRedisShard(h.node, centrifuge.RedisShardConfig{Address: h.redisAddr})
+	if err != nil {
+		return fmt.Errorf("connect to redis: %w", err)
+	}
+	shards := []*centrifuge.RedisShard{shard}
+	broker, err := centrifuge.NewRedisBroker(h.node, centrifuge.RedisBrokerConfig{Prefix: h.redisPrefix, Shards: shards})
+	if err != nil {
+		return fmt.Errorf("create redis broker: %w", err)
+	}
+	h.node.SetBroker(broker)
+	presence, err := centrifuge.NewRedisPresenceManager(h.node, centrifuge.RedisPresenceManagerConfig{Prefix: h.redisPrefix, Shards: shards})
+	if err != nil {
+		return fmt.Errorf("create redis presence manager: %w", err)
+	}
+	h.node.SetPresenceManager(presence)
+	return nil
+}
+
 // Node returns the underlying centrifuge node.
 func (h *Hub) Node() *centrifuge.Node {
 	return h.node
@@ -127,26 +373,40 @@ func (h *Hub) Node() *centrifuge.Node {
 
 // Run starts the centrifuge node.
 func (h *Hub) Run() error {
-	return h.node.Run()
-}
-
-// Healthy returns true if the Centrifuge node is running.
-func (h *Hub) Healthy() bool {
-	return h.node != nil
+	if err := h.node.Run(); err != nil {
+		return err
+	}
+	h.running.Store(true)
+	return nil
 }
 
 // StartCampfireLoop ticks every interval, applies campfire decay/respawn to all
-// rooms, and broadcasts state for any room that changed.
+// rooms, marks idle users as away, and broadcasts state for any room that
+// changed.
 func (h *Hub) StartCampfireLoop(interval time.Duration, done <-chan struct{}) {
+	h.campfireBeat.Store(time.Now().UnixNano())
+	h.campfireEvery.Store(int64(interval))
 	go func() {
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
+				start := time.Now()
+				h.campfireBeat.Store(start.UnixNano())
 				for _, id := range h.rooms.NormalizeCampfireRooms() {
 					h.broadcastRoomState(id)
 				}
+				if h.idle > 0 {
+					for _, id := range h.rooms.MarkAwayUsers(h.idle) {
+						h.broadcastRoomState(id)
+					}
+					h.pruneActivity(start)
+				}
+				h.pruneStreams()
+				if h.metrics != nil {
+					h.metrics.campfireLoop.Observe(time.Since(start).Seconds())
+				}
 			case <-done:
 				return
 			}
@@ -154,18 +414,148 @@ func (h *Hub) StartCampfireLoop(interval time.Duration, done <-chan struct{}) {
 	}()
 }
 
-// Shutdown gracefully shuts down the centrifuge node.
+// Shutdown gracefully shuts down the centrifuge node and the webhook dispatcher.
+// Background work such as estimate syncs is cancelled and waited for first.
 func (h *Hub) Shutdown() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	return h.node.Shutdown(ctx)
+	// Interrupt background work still running after Drain; it records what
+	// it could not finish while the room store is still open.
+	h.bgCancel()
+	h.waitBackground(5 * time.Second)
+	h.mu.Lock()
+	h.closing = true
+	for key, t := range h.offline {
+		t.Stop()
+		delete(h.offline, key)
+	}
+	for _, s := range h.streams {
+		s.mu.Lock()
+		if s.timer != nil {
+			s.timer.Stop()
+			s.timer = nil
+		}
+		s.mu.Unlock()
+	}
+	h.mu.Unlock()
+	h.running.Store(false)
+	err := h.node.Shutdown(ctx)
+	if werr := h.webhooks.Close(ctx); werr != nil {
+		h.logger.Warn().Err(werr).Msg("webhook dispatcher did not stop in time")
+	}
+	return err
+}
+
+// Stats describes the hub's current load.
+type Stats struct {
+	Rooms         int `json:"rooms"`
+	Connections   int `json:"connections"`   // open WebSocket connections
+	Members       int `json:"members"`       // connections that joined a room
+	Subscriptions int `json:"subscriptions"` // room channel subscriptions
+}
+
+// SetCountdown changes the reveal countdown, in seconds, of rooms created
+// from now on. Existing rooms keep theirs.
+func (h *Hub) SetCountdown(seconds int) {
+	h.mu.Lock()
+	h.countdown = seconds
+	h.mu.Unlock()
 }
 
-// registerClient stores the mapping from centrifuge client ID to user/room.
+// Stats returns connection and room counters.
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	members := len(h.clients)
+	h.mu.RUnlock()
+	return Stats{
+		Rooms:         h.rooms.Count(),
+		Connections:   h.node.Hub().NumClients(),
+		Members:       members,
+		Subscriptions: h.node.Hub().NumSubscriptions(),
+	}
+}
+
+// CloseRoom deletes a room and unsubscribes everyone from its channel.
+func (h *Hub) CloseRoom(roomID string) error {
+	if _, err := h.rooms.Get(roomID); err != nil {
+		return err
+	}
+	h.rooms.Delete(roomID)
+
+	h.mu.Lock()
+	for id, ci := range h.clients {
+		if ci.RoomID == roomID {
+			delete(h.clients, id)
+		}
+	}
+	delete(h.streams, roomID)
+	delete(h.debugRooms, roomID)
+	for key := range h.nudged {
+		if key.RoomID == roomID {
+			delete(h.nudged, key)
+		}
+	}
+	for key, t := range h.offline {
+		if key.RoomID == roomID {
+			t.Stop()
+			delete(h.offline, key)
+		}
+	}
+	h.mu.Unlock()
+
+	h.logger.Info().Str("room_id", roomID).Msg("room closed by operator")
+	// All connections are anonymous, so the empty user ID covers every subscriber.
+	return h.node.Unsubscribe("", "room:"+roomID)
+}
+
+// registerClient stores the mapping from centrifuge client ID to user/room and
+// cancels a pending offline transition for the user.
 func (h *Hub) registerClient(clientID, userID, roomID string) {
+	info := clientInfo{UserID: userID, RoomID: roomID}
 	h.mu.Lock()
-	h.clients[clientID] = clientInfo{UserID: userID, RoomID: roomID}
+	h.clients[clientID] = info
+	t, pending := h.offline[info]
+	if pending {
+		t.Stop()
+		delete(h.offline, info)
+	}
 	h.mu.Unlock()
+
+	if pending {
+		h.logger.Debug().
+			Str("room_id", roomID).
+			Str("user_id", userID).
+			Msg("user reconnected within grace period")
+	}
+}
+
+// reconnect registers a subscribing client for a user already in the room and
+// marks the user online. It reports whether the user was offline before.
+func (h *Hub) reconnect(clientID, userID, roomID string) bool {
+	var wasOffline bool
+	err := h.rooms.WithRoom(roomID, func(r *model.Room) error {
+		var err error
+		wasOffline, err = room.ReconnectUser(r, userID)
+		if err != nil {
+			return err
+		}
+		if wasOffline {
+			u := r.Users[userID]
+			h.notify(r, webhook.EventUserJoined, webhook.UserData{UserID: u.ID, Name: u.Name})
+		}
+		return nil
+	})
+	if err != nil {
+		return false
+	}
+	h.registerClient(clientID, userID, roomID)
+	if wasOffline {
+		h.logger.Info().
+			Str("room_id", roomID).
+			Str("user_id", userID).
+			Msg("user reconnected")
+	}
+	return wasOffline
 }
 
 // unregisterClient removes the mapping and returns the info.
@@ -186,83 +576,368 @@ func (h *Hub) buildSnapshot(r *model.Room) *model.RoomSnapshot {
 	return snap
 }
 
-// broadcastRoomState publishes the current room state to all subscribers.
-func (h *Hub) broadcastRoomState(roomID string) {
+// roomStream tracks the pending publication of a room channel. The revision
+// and state last published are kept on the room itself, so that every process
+// sharing the room diffs against the same publication.
+type roomStream struct {
+	mu    sync.Mutex  // serializes publications, held while the room is locked
+	timer *time.Timer // pending coalesced publication, nil when none
+}
+
+// stream returns the publication state of a room, creating it if needed.
+func (h *Hub) stream(roomID string) *roomStream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.streams[roomID]
+	if !ok {
+		s = &roomStream{}
+		h.streams[roomID] = s
+	}
+	return s
+}
+
+// revision returns the last published revision of a room.
+func (h *Hub) revision(roomID string) uint64 {
+	var rev uint64
+	_ = h.rooms.WithRoom(roomID, func(r *model.Room) error {
+		rev = r.Revision
+		return nil
+	})
+	return rev
+}
+
+// pruneStreams drops the publication state of rooms that no longer exist.
+func (h *Hub) pruneStreams() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id := range h.streams {
+		if _, err := h.rooms.Get(id); err != nil {
+			delete(h.streams, id)
+		}
+	}
+}
+
+// currentSnapshot returns the room state for a client that is not following
+// the channel yet. It carries the last published revision; the state is at
+// least as new as that revision, so deltas published after it apply cleanly.
+func (h *Hub) currentSnapshot(roomID string) (*model.RoomSnapshot, error) {
 	var snap *model.RoomSnapshot
 	err := h.rooms.WithRoom(roomID, func(r *model.Room) error {
 		snap = h.buildSnapshot(r)
+		snap.Revision = r.Revision
 		return nil
 	})
 	if err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// broadcastRoomState schedules a publication of the room state. Calls within
+// the broadcast window are merged into one publication; queued room events
+// are carried along in the order they happened.
+func (h *Hub) broadcastRoomState(roomID string) {
+	if h.window <= 0 {
+		h.publishRoomState(roomID)
 		return
 	}
-	data, err := json.Marshal(snap)
+	s := h.stream(roomID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.timer == nil {
+		s.timer = time.AfterFunc(h.window, func() { h.publishRoomState(roomID) })
+	}
+}
+
+// publishRoomState publishes what changed in the room since the previous
+// publication. The first publication of a room carries a full snapshot; a
+// publication is skipped when nothing changed. The room stays locked while
+// publishing, so revisions reach the channel in order even when several
+// processes share the room.
+func (h *Hub) publishRoomState(roomID string) {
+	s := h.stream(roomID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Changes made from here on need a new publication.
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+
+	ctx, span := tracer.Start(context.Background(), "hub.publish_room_state",
+		trace.WithAttributes(attribute.String("room.id", roomID)))
+	defer span.End()
+
+	err := h.rooms.WithRoomContext(ctx, roomID, func(r *model.Room) error {
+		snap := h.buildSnapshot(r)
+		update := model.RoomUpdate{Revision: r.Revision + 1}
+		if r.Published == nil {
+			update.Type = model.UpdateSnapshot
+			update.Snapshot = snap
+			snap.Revision = update.Revision
+		} else {
+			delta, changed := model.Diff(r.Published, snap)
+			if !changed {
+				return nil
+			}
+			update.Type = model.UpdateDelta
+			update.Delta = delta
+		}
+		span.SetAttributes(attribute.String("update.type", string(update.Type)))
+		_, marshal := tracer.Start(ctx, "hub.marshal")
+		data, err := json.Marshal(update)
+		marshal.End()
+		if err != nil {
+			h.logger.Error().Err(err).Msg("marshal room update")
+			return nil
+		}
+		if err := h.publish(ctx, "room:"+roomID, data, centrifuge.WithHistory(historySize, historyTTL)); err != nil {
+			h.logger.Error().Err(err).Str("room", roomID).Msg("publish room state")
+			return nil
+		}
+		h.observeBroadcast(string(update.Type), len(data))
+
+		r.Revision = update.Revision
+		r.Published = snap
+		return nil
+	})
+	if errors.Is(err, room.ErrRoomNotFound) {
+		h.mu.Lock()
+		delete(h.streams, roomID)
+		h.mu.Unlock()
+	} else if err != nil {
+		h.logger.Error().Err(err).Str("room", roomID).Msg("publish room state")
+	}
+}
+
+// publishEvent stamps an event with an ID and time and publishes it on the room
+// channel right away. Events are not kept in history: a client that missed
+// one while offline has nothing to replay.
+func (h *Hub) publishEvent(ctx context.Context, roomID string, ev model.RoomEvent) {
+	stampEvent(&ev)
+	data, err := json.Marshal(model.RoomUpdate{Type: model.UpdateEvent, Revision: h.revision(roomID), Event: &ev})
 	if err != nil {
-		h.logger.Error().Err(err).Msg("marshal room snapshot")
+		h.logger.Error().Err(err).Msg("marshal room event")
 		return
 	}
-	_, err = h.node.Publish("room:"+roomID, data)
+	if err := h.publish(ctx, "room:"+roomID, data); err != nil {
+		h.logger.Error().Err(err).Str("room", roomID).Msg("publish room event")
+		return
+	}
+	h.observeBroadcast(string(model.UpdateEvent), len(data))
+}
+
+// publishUserEvent sends an event to a single user's private channel.
+func (h *Hub) publishUserEvent(ctx context.Context, userID string, ev model.RoomEvent) {
+	stampEvent(&ev)
+	data, err := json.Marshal(ev)
 	if err != nil {
-		h.logger.Error().Err(err).Str("room", roomID).Msg("publish room state")
+		h.logger.Error().Err(err).Msg("marshal user event")
+		return
 	}
+	if err := h.publish(ctx, "user:"+userID, data); err != nil {
+		h.logger.Error().Err(err).Str("user", userID).Msg("publish user event")
+		return
+	}
+	h.observeBroadcast("user_event", len(data))
 }
 
-// handleRPC dispatches RPC calls by method name.
-func (h *Hub) handleRPC(client *centrifuge.Client, method string, data []byte) ([]byte, error) {
-	switch method {
-	case "create_room":
-		return h.rpcCreateRoom(client, data)
-	case "join_room":
-		return h.rpcJoinRoom(client, data)
-	case "submit_vote":
-		return h.rpcSubmitVote(client, data)
-	case "remove_vote":
-		return h.rpcRemoveVote(client, data)
-	case "add_ticket":
-		return h.rpcAddTicket(data)
-	case "start_reveal":
-		return h.rpcStartReveal(data)
-	case "reveal_votes":
-		return h.rpcRevealVotes(data)
-	case "reset_votes":
-		return h.rpcResetVotes(data)
-	case "next_ticket":
-		return h.rpcNextTicket(data)
-	case "prev_ticket":
-		return h.rpcPrevTicket(data)
-	case "set_ticket":
-		return h.rpcSetTicket(data)
-	case "update_room_name":
-		return h.rpcUpdateRoomName(data)
-	case "start_free_vote":
-		return h.rpcStartFreeVote(data)
-	case "set_thinking":
-		return h.rpcSetThinking(client, data)
-	case "interact_player":
-		return h.rpcInteractPlayer(client, data)
-	case "theme_interact":
-		return h.rpcThemeInteract(client, data)
-	default:
-		return nil, centrifuge.ErrorMethodNotFound
+// publish publishes data on a channel and records the call as a span.
+func (h *Hub) publish(ctx context.Context, channel string, data []byte, opts ...centrifuge.PublishOption) error {
+	_, span := tracer.Start(ctx, "centrifuge.Publish", trace.WithAttributes(
+		attribute.String("channel", channel),
+		attribute.Int("size", len(data)),
+	))
+	defer span.End()
+	_, err := h.node.Publish(channel, data, opts...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// stampEvent gives an event its ID and time.
+func stampEvent(ev *model.RoomEvent) {
+	ev.ID = uuid.New().String()
+	ev.At = time.Now().UTC()
+}
+
+// notify queues a webhook event for the server-wide endpoints and the room's
+// own endpoint. It is called with the room locked and never blocks.
+func (h *Hub) notify(r *model.Room, eventType string, data any) {
+	var extra []webhook.Endpoint
+	if r.Webhook != nil {
+		extra = append(extra, webhook.Endpoint{URL: r.Webhook.URL, Secret: r.Webhook.Secret})
+	}
+	h.webhooks.Send(webhook.Event{Type: eventType, RoomID: r.ID, Data: data}, extra...)
+}
+
+// notifyVotingStarted emits voting.started when an admin action opened voting
+// on a different ticket or reopened it after a reveal.
+func (h *Hub) notifyVotingStarted(r *model.Room, prevState model.RoomState, prevTicketID string) {
+	if r.State != model.RoomStateVoting {
+		return
+	}
+	if prevState == model.RoomStateVoting && prevTicketID == r.CurrentTicketID {
+		return
+	}
+	data := webhook.TicketData{TicketID: r.CurrentTicketID}
+	if t := room.FindTicket(r, r.CurrentTicketID); t != nil {
+		data.ExternalKey = t.ExternalKey
+	}
+	h.notify(r, webhook.EventVotingStarted, data)
+}
+
+// notifyRevealed emits votes.revealed and, when the votes produce an
+// estimate, ticket.estimated for the current ticket.
+func (h *Hub) notifyRevealed(r *model.Room) {
+	t := room.FindTicket(r, r.CurrentTicketID)
+	if t == nil {
+		return
+	}
+	votes := make([]webhook.VoteData, 0, len(t.Votes))
+	for _, v := range t.Votes {
+		votes = append(votes, webhook.VoteData{UserID: v.UserID, Value: v.Value})
+	}
+	sort.Slice(votes, func(i, j int) bool { return votes[i].UserID < votes[j].UserID })
+	h.notify(r, webhook.EventVotesRevealed, webhook.TicketData{
+		TicketID:    t.ID,
+		ExternalKey: t.ExternalKey,
+		Votes:       votes,
+	})
+	estimate, err := room.FinalEstimate(r, t)
+	if err != nil {
+		h.logger.Warn().Err(err).
+			Str("room_id", r.ID).
+			Str("ticket_id", t.ID).
+			Msg("final estimate")
+	}
+	if estimate != "" {
+		h.notify(r, webhook.EventTicketEstimated, webhook.TicketData{
+			TicketID:    t.ID,
+			ExternalKey: t.ExternalKey,
+			Estimate:    estimate,
+		})
+	}
+}
+
+// Call invokes an RPC method for a caller without a WebSocket connection, such
+// as the REST API. It runs the same validation and authorization as RPCs sent
+// over WebSocket; methods that check the caller's identity are rejected.
+func (h *Hub) Call(ctx context.Context, method string, data []byte) ([]byte, error) {
+	return h.handleRPC(ctx, "", method, data)
+}
+
+// builtinMethods lists the RPC methods the hub serves.
+func (h *Hub) builtinMethods() []Method {
+	return []Method{
+		NewMethod("create_room", AuthAnonymous, func(req *model.CreateRoomRequest) bool {
+			if req.ScaleID == "" || req.UserName == "" || !avatar.Valid(req.AvatarID) {
+				return false
+			}
+			_, err := scale.Get(req.ScaleID)
+			return err == nil
+		}, h.rpcCreateRoom),
+		NewMethod("join_room", AuthAnonymous, func(req *model.JoinRoomRequest) bool {
+			return req.RoomID != "" && req.UserName != "" && avatar.Valid(req.AvatarID)
+		}, h.rpcJoinRoom),
+		NewMethod("get_room", AuthAnonymous, func(req *model.GetRoomRequest) bool {
+			return req.RoomID != ""
+		}, h.rpcGetRoom),
+		NewMethod("submit_vote", AuthMember, func(req *model.SubmitVoteRequest) bool {
+			return req.Value != ""
+		}, h.rpcSubmitVote),
+		NewMethod("remove_vote", AuthMember, nil, h.rpcRemoveVote),
+		NewMethod("add_ticket", AuthAdmin, func(req *model.AddTicketRequest) bool {
+			return req.Content != "" && utf8.RuneCountInString(req.Content) <= 10000
+		}, h.rpcAddTicket),
+		NewMethod("import_tickets", AuthAdmin, func(req *model.ImportTicketsRequest) bool {
+			return req.Query != ""
+		}, h.rpcImportTickets),
+		NewMethod("sync_estimates", AuthAdmin, nil, h.rpcSyncEstimates),
+		NewMethod("set_webhook", AuthAdmin, validWebhook, h.rpcSetWebhook),
+		NewMethod("start_reveal", AuthAdmin, nil, h.rpcStartReveal),
+		NewMethod("reveal_votes", AuthAdmin, nil, h.rpcRevealVotes),
+		NewMethod("reset_votes", AuthAdmin, nil, h.rpcResetVotes),
+		NewMethod("next_ticket", AuthAdmin, nil, h.rpcNextTicket),
+		NewMethod("prev_ticket", AuthAdmin, nil, h.rpcPrevTicket),
+		NewMethod("set_ticket", AuthAdmin, func(req *model.SetTicketRequest) bool {
+			return req.TicketID != ""
+		}, h.rpcSetTicket),
+		NewMethod("update_room_name", AuthAdmin, func(req *model.UpdateRoomNameRequest) bool {
+			return len(req.Name) <= 200
+		}, h.rpcUpdateRoomName),
+		NewMethod("start_free_vote", AuthAdmin, nil, h.rpcStartFreeVote),
+		NewMethod("set_thinking", AuthMember, nil, h.rpcSetThinking),
+		NewMethod("interact_player", AuthMember, func(req *model.InteractPlayerRequest) bool {
+			return req.TargetUserID != "" && req.Action != ""
+		}, h.rpcInteractPlayer),
+		NewMethod("theme_interact", AuthMember, func(req *model.ThemeInteractRequest) bool {
+			return req.Action != ""
+		}, h.rpcThemeInteract),
+		NewMethod("heartbeat", AuthAnonymous, nil, h.rpcHeartbeat),
+		NewMethod("nudge", AuthAdmin, nil, h.rpcNudge),
 	}
 }
 
-func (h *Hub) rpcCreateRoom(client *centrifuge.Client, data []byte) ([]byte, error) {
-	var req model.CreateRoomRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
+// markActive records activity for the user behind a registered connection and
+// broadcasts when the user is no longer away. The room is written at most once
+// per half idle timeout for each user: the stored stamp then stays recent
+// enough to keep an active user from being marked away, and a user who was
+// marked away has not been written for longer than that, so their next call
+// always reaches the room.
+func (h *Hub) markActive(clientID string) {
+	if clientID == "" {
+		return
 	}
-	if req.ScaleID == "" || req.UserName == "" || req.AvatarID == "" {
-		return nil, centrifuge.ErrorBadRequest
+	now := time.Now()
+	h.mu.Lock()
+	info, ok := h.clients[clientID]
+	if !ok || h.idle <= 0 || now.Sub(h.active[info]) < h.idle/2 {
+		h.mu.Unlock()
+		return
 	}
-	if _, err := scale.Get(req.ScaleID); err != nil {
-		return nil, centrifuge.ErrorBadRequest
+	h.active[info] = now
+	h.mu.Unlock()
+
+	var wasAway bool
+	err := h.rooms.WithRoom(info.RoomID, func(r *model.Room) error {
+		var err error
+		wasAway, err = room.MarkActive(r, info.UserID, now)
+		return err
+	})
+	if err != nil {
+		h.mu.Lock()
+		delete(h.active, info)
+		h.mu.Unlock()
+		return
 	}
-	if !avatar.Valid(req.AvatarID) {
-		return nil, centrifuge.ErrorBadRequest
+	if wasAway {
+		h.broadcastRoomState(info.RoomID)
 	}
+}
+
+// pruneActivity forgets activity stamps old enough that the next call writes
+// the room anyway.
+func (h *Hub) pruneActivity(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for key, at := range h.active {
+		if now.Sub(at) >= h.idle/2 {
+			delete(h.active, key)
+		}
+	}
+}
 
-	r, err := h.rooms.Create(req.ScaleID, h.countdown)
+func (h *Hub) rpcCreateRoom(c *Call, req *model.CreateRoomRequest) (any, error) {
+	if h.draining.Load() {
+		return nil, errorServerDraining
+	}
+	h.mu.RLock()
+	countdown := h.countdown
+	h.mu.RUnlock()
+	r, err := h.rooms.Create(req.ScaleID, countdown)
 	if err != nil {
 		return nil, centrifuge.ErrorInternal
 	}
@@ -281,16 +956,24 @@ func (h *Hub) rpcCreateRoom(client *centrifuge.Client, data []byte) ([]byte, err
 	}
 
 	var state model.RoomState
-	err = h.rooms.WithRoom(roomID, func(r *model.Room) error {
+	err = h.rooms.WithRoomContext(c.Context(), roomID, func(r *model.Room) error {
 		room.AddUser(r, u)
+		if c.ClientID == "" {
+			// Created through the REST API: the admin shows up as offline
+			// until they join over WebSocket.
+			room.RemoveUser(r, userID)
+		}
 		state = r.State
+		h.notify(r, webhook.EventRoomCreated, webhook.RoomData{Scale: r.Scale, CreatorID: userID})
 		return nil
 	})
 	if err != nil {
 		return nil, centrifuge.ErrorInternal
 	}
 
-	h.registerClient(client.ID(), userID, roomID)
+	if c.ClientID != "" {
+		h.registerClient(c.ClientID, userID, roomID)
+	}
 	h.broadcastRoomState(roomID)
 
 	h.logger.Info().
@@ -300,25 +983,21 @@ func (h *Hub) rpcCreateRoom(client *centrifuge.Client, data []byte) ([]byte, err
 		Str("scale", req.ScaleID).
 		Msg("room created")
 
-	resp := model.CreateRoomResponse{
+	return model.CreateRoomResponse{
 		RoomID:      roomID,
 		AdminSecret: adminSecret,
 		UserID:      userID,
 		State:       state,
-	}
-	return json.Marshal(resp)
+	}, nil
 }
 
-func (h *Hub) rpcJoinRoom(client *centrifuge.Client, data []byte) ([]byte, error) {
-	var req model.JoinRoomRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	if req.RoomID == "" || req.UserName == "" || req.AvatarID == "" {
-		return nil, centrifuge.ErrorBadRequest
+func (h *Hub) rpcJoinRoom(c *Call, req *model.JoinRoomRequest) (any, error) {
+	if c.ClientID == "" {
+		// Joining marks the user online, which only makes sense for a live connection.
+		return nil, centrifuge.ErrorPermissionDenied
 	}
-	if !avatar.Valid(req.AvatarID) {
-		return nil, centrifuge.ErrorBadRequest
+	if h.draining.Load() {
+		return nil, errorServerDraining
 	}
 
 	userID := req.UserID
@@ -327,7 +1006,7 @@ func (h *Hub) rpcJoinRoom(client *centrifuge.Client, data []byte) ([]byte, error
 	}
 
 	var snap *model.RoomSnapshot
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
+	err := h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
 		existing, exists := r.Users[userID]
 		u := &model.User{
 			ID:       userID,
@@ -340,22 +1019,19 @@ func (h *Hub) rpcJoinRoom(client *centrifuge.Client, data []byte) ([]byte, error
 			u.JoinedAt = existing.JoinedAt
 		}
 		room.AddUser(r, u)
-		// Build join response without draining PendingEvents so the subsequent
-		// broadcastRoomState delivers them to existing subscribers.
-		saved := r.PendingEvents
-		r.PendingEvents = nil
+		// A reconnect or a second tab of an online user is not a new arrival.
+		if !exists || !existing.Connected {
+			h.notify(r, webhook.EventUserJoined, webhook.UserData{UserID: u.ID, Name: u.Name})
+		}
 		snap = h.buildSnapshot(r)
-		r.PendingEvents = saved
+		snap.Revision = r.Revision
 		return nil
 	})
 	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
-		}
-		return nil, centrifuge.ErrorInternal
+		return nil, err
 	}
 
-	h.registerClient(client.ID(), userID, req.RoomID)
+	h.registerClient(c.ClientID, userID, req.RoomID)
 	h.broadcastRoomState(req.RoomID)
 
 	h.logger.Info().
@@ -364,85 +1040,42 @@ func (h *Hub) rpcJoinRoom(client *centrifuge.Client, data []byte) ([]byte, error
 		Str("user_name", req.UserName).
 		Msg("user joined room")
 
-	resp := model.JoinRoomResponse{
+	return model.JoinRoomResponse{
 		UserID: userID,
 		State:  snap,
-	}
-	return json.Marshal(resp)
+	}, nil
 }
 
-func (h *Hub) rpcSubmitVote(client *centrifuge.Client, data []byte) ([]byte, error) {
-	var req model.SubmitVoteRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	if req.RoomID == "" || req.UserID == "" || req.Value == "" {
-		return nil, centrifuge.ErrorBadRequest
-	}
-
-	// Verify the caller is the user they claim to be.
-	h.mu.RLock()
-	info, ok := h.clients[client.ID()]
-	h.mu.RUnlock()
-	if !ok || info.UserID != req.UserID || info.RoomID != req.RoomID {
-		return nil, centrifuge.ErrorPermissionDenied
-	}
+func (h *Hub) rpcGetRoom(_ *Call, req *model.GetRoomRequest) (any, error) {
+	return h.currentSnapshot(req.RoomID)
+}
 
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
+func (h *Hub) rpcSubmitVote(c *Call, req *model.SubmitVoteRequest) (any, error) {
+	err := h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
 		return room.SubmitVote(r, req.UserID, req.Value)
 	})
 	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
-		}
-		return nil, &centrifuge.Error{Code: 400, Message: err.Error()}
+		return nil, err
 	}
 
 	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
+	return nil, nil
 }
 
-func (h *Hub) rpcRemoveVote(client *centrifuge.Client, data []byte) ([]byte, error) {
-	var req model.RemoveVoteRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	if req.RoomID == "" || req.UserID == "" {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	h.mu.RLock()
-	info, ok := h.clients[client.ID()]
-	h.mu.RUnlock()
-	if !ok || info.UserID != req.UserID || info.RoomID != req.RoomID {
-		return nil, centrifuge.ErrorPermissionDenied
-	}
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
+func (h *Hub) rpcRemoveVote(c *Call, req *model.RemoveVoteRequest) (any, error) {
+	err := h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
 		return room.RemoveVote(r, req.UserID)
 	})
 	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
-		}
-		return nil, &centrifuge.Error{Code: 400, Message: err.Error()}
+		return nil, err
 	}
 	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
+	return nil, nil
 }
 
-func (h *Hub) rpcAddTicket(data []byte) ([]byte, error) {
-	var req model.AddTicketRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	if req.RoomID == "" || req.AdminSecret == "" || req.Content == "" || utf8.RuneCountInString(req.Content) > 10000 {
-		return nil, centrifuge.ErrorBadRequest
-	}
-
+func (h *Hub) rpcAddTicket(c *Call, req *model.AddTicketRequest) (any, error) {
 	ticketID := uuid.New().String()
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
-		if r.AdminSecret != req.AdminSecret {
-			return room.ErrInvalidAdmin
-		}
+	err := h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
 		room.AddTicket(r, &model.Ticket{
 			ID:      ticketID,
 			Content: req.Content,
@@ -450,396 +1083,550 @@ func (h *Hub) rpcAddTicket(data []byte) ([]byte, error) {
 		return nil
 	})
 	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
-		}
-		if errors.Is(err, room.ErrInvalidAdmin) {
-			return nil, centrifuge.ErrorPermissionDenied
-		}
-		return nil, centrifuge.ErrorInternal
+		return nil, err
 	}
 
 	h.broadcastRoomState(req.RoomID)
-	resp := model.AddTicketResponse{TicketID: ticketID}
-	return json.Marshal(resp)
+	return model.AddTicketResponse{TicketID: ticketID}, nil
 }
 
-func (h *Hub) rpcStartReveal(data []byte) ([]byte, error) {
-	var req model.AdminActionRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	if req.RoomID == "" || req.AdminSecret == "" {
-		return nil, centrifuge.ErrorBadRequest
+func (h *Hub) rpcImportTickets(c *Call, req *model.ImportTicketsRequest) (any, error) {
+	if h.issues == nil {
+		return nil, centrifuge.ErrorNotAvailable
 	}
 
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
-		if r.AdminSecret != req.AdminSecret {
-			return room.ErrInvalidAdmin
+	// The AuthAdmin check has already found the room and verified the
+	// secret, so the tracker is only queried for a room admin. The search
+	// stops when the caller goes away and is traced under the call.
+	ctx, cancel := context.WithTimeout(c.Context(), importTimeout)
+	defer cancel()
+	tickets, err := h.issues.FetchIssues(ctx, req.Query)
+	if err != nil {
+		h.logger.Error().Err(err).
+			Str("room_id", req.RoomID).
+			Str("provider", h.issues.Name()).
+			Msg("fetch issues")
+		return nil, errorTrackerFailed
+	}
+
+	ticketIDs := make([]string, 0, len(tickets))
+	err = h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
+		for _, t := range tickets {
+			// Skip issues that were already imported into this room.
+			if room.FindTicketByExternalKey(r, t.ExternalKey) != nil {
+				continue
+			}
+			t.ID = uuid.New().String()
+			room.AddTicket(r, t)
+			ticketIDs = append(ticketIDs, t.ID)
 		}
-		return room.StartCountdown(r)
+		return nil
 	})
 	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
-		}
-		if errors.Is(err, room.ErrInvalidAdmin) {
-			return nil, centrifuge.ErrorPermissionDenied
-		}
-		return nil, &centrifuge.Error{Code: 400, Message: err.Error()}
+		return nil, err
 	}
 
+	h.logger.Info().
+		Str("room_id", req.RoomID).
+		Str("provider", h.issues.Name()).
+		Int("imported", len(ticketIDs)).
+		Msg("tickets imported")
+
 	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
+	return model.ImportTicketsResponse{TicketIDs: ticketIDs}, nil
 }
 
-func (h *Hub) rpcRevealVotes(data []byte) ([]byte, error) {
-	var req model.AdminActionRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
+// errSyncInterrupted is recorded on tickets whose estimate was not written
+// because the server shut down.
+var errSyncInterrupted = errors.New("server shut down before the estimate was written")
+
+// estimateSync is a single estimate to write back to the issue tracker.
+type estimateSync struct {
+	TicketID string
+	Key      string
+	Value    string
+}
+
+func (h *Hub) rpcSyncEstimates(c *Call, req *model.AdminActionRequest) (any, error) {
+	if h.issues == nil {
+		return nil, centrifuge.ErrorNotAvailable
 	}
-	if req.RoomID == "" || req.AdminSecret == "" {
-		return nil, centrifuge.ErrorBadRequest
+	if h.draining.Load() {
+		return nil, errorServerDraining
 	}
 
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
-		if r.AdminSecret != req.AdminSecret {
-			return room.ErrInvalidAdmin
+	var jobs []estimateSync
+	var failed bool
+	err := h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
+		for _, t := range r.Tickets {
+			if t.ExternalKey == "" || t.SyncStatus == model.SyncStatusPending {
+				continue
+			}
+			value, err := room.FinalEstimate(r, t)
+			if err != nil {
+				// Without its scale the room cannot agree on an estimate;
+				// say so on the ticket instead of skipping it silently.
+				t.SyncStatus = model.SyncStatusFailed
+				t.SyncError = err.Error()
+				failed = true
+				continue
+			}
+			if value == "" {
+				continue
+			}
+			if t.SyncStatus == model.SyncStatusSynced && t.SyncedValue == value {
+				continue
+			}
+			t.SyncStatus = model.SyncStatusPending
+			t.SyncError = ""
+			jobs = append(jobs, estimateSync{TicketID: t.ID, Key: t.ExternalKey, Value: value})
 		}
-		return room.RevealVotes(r)
+		return nil
 	})
 	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
-		}
-		if errors.Is(err, room.ErrInvalidAdmin) {
-			return nil, centrifuge.ErrorPermissionDenied
+		return nil, err
+	}
+
+	if len(jobs) > 0 || failed {
+		h.broadcastRoomState(req.RoomID)
+	}
+	if len(jobs) > 0 {
+		started := h.goBackground(func(ctx context.Context) {
+			h.syncEstimates(ctx, req.RoomID, jobs)
+		})
+		if !started {
+			// The server started shutting down since the check above: record
+			// the estimates as failed instead of leaving them pending.
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			h.syncEstimates(ctx, req.RoomID, jobs)
 		}
-		return nil, &centrifuge.Error{Code: 400, Message: err.Error()}
 	}
 
-	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
+	return model.SyncEstimatesResponse{Queued: len(jobs)}, nil
 }
 
-func (h *Hub) rpcResetVotes(data []byte) ([]byte, error) {
-	var req model.AdminActionRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	if req.RoomID == "" || req.AdminSecret == "" {
-		return nil, centrifuge.ErrorBadRequest
-	}
+// syncEstimates writes each estimate to the tracker, retrying temporary
+// failures with exponential backoff, and records the outcome on the ticket.
+// Once ctx is cancelled the remaining estimates are recorded as failed, so an
+// admin can sync them again.
+func (h *Hub) syncEstimates(ctx context.Context, roomID string, jobs []estimateSync) {
+	for _, job := range jobs {
+		err := h.pushEstimate(ctx, job)
 
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
-		if r.AdminSecret != req.AdminSecret {
-			return room.ErrInvalidAdmin
+		status, syncErr := model.SyncStatusSynced, ""
+		if err != nil {
+			status, syncErr = model.SyncStatusFailed, err.Error()
+			h.logger.Warn().Err(err).
+				Str("room_id", roomID).
+				Str("ticket_id", job.TicketID).
+				Str("key", job.Key).
+				Msg("sync estimate")
 		}
-		return room.ResetVotes(r)
-	})
-	if err != nil {
+		err = h.rooms.WithRoom(roomID, func(r *model.Room) error {
+			return room.SetSyncStatus(r, job.TicketID, status, job.Value, syncErr)
+		})
 		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
-		}
-		if errors.Is(err, room.ErrInvalidAdmin) {
-			return nil, centrifuge.ErrorPermissionDenied
+			return
 		}
-		return nil, &centrifuge.Error{Code: 400, Message: err.Error()}
+		h.broadcastRoomState(roomID)
 	}
-
-	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
 }
 
-func (h *Hub) rpcNextTicket(data []byte) ([]byte, error) {
-	var req model.AdminActionRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	if req.RoomID == "" || req.AdminSecret == "" {
-		return nil, centrifuge.ErrorBadRequest
-	}
-
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
-		if r.AdminSecret != req.AdminSecret {
-			return room.ErrInvalidAdmin
+func (h *Hub) pushEstimate(ctx context.Context, job estimateSync) error {
+	backoff := h.syncBackoff
+	var err error
+	for attempt := 1; attempt <= syncAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return errSyncInterrupted
 		}
-		return room.NextTicketByIndex(r)
-	})
-	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
+		attemptCtx, cancel := context.WithTimeout(ctx, syncTimeout)
+		err = h.issues.UpdateEstimate(attemptCtx, job.Key, job.Value)
+		cancel()
+		if err == nil || !tracker.Temporary(err) || attempt == syncAttempts {
+			break
 		}
-		if errors.Is(err, room.ErrInvalidAdmin) {
-			return nil, centrifuge.ErrorPermissionDenied
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return errSyncInterrupted
 		}
-		return nil, &centrifuge.Error{Code: 400, Message: err.Error()}
+		backoff *= 2
+	}
+	if err != nil && ctx.Err() != nil {
+		return errSyncInterrupted
 	}
+	return err
+}
 
-	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
+// goBackground runs fn in a goroutine that Drain and Shutdown wait for. fn
+// must return soon after its context is cancelled. It reports false, without
+// running fn, once the hub has started shutting down.
+func (h *Hub) goBackground(fn func(ctx context.Context)) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.bgClosed {
+		return false
+	}
+	h.background.Go(func() { fn(h.bgCtx) })
+	return true
 }
 
-func (h *Hub) rpcPrevTicket(data []byte) ([]byte, error) {
-	var req model.AdminActionRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
+// waitBackground waits up to timeout for background work to finish and
+// reports whether it did. No new work may start once it is called.
+func (h *Hub) waitBackground(timeout time.Duration) bool {
+	h.mu.Lock()
+	h.bgClosed = true
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// validWebhook accepts an empty URL, which removes the webhook, or an absolute
+// http(s) URL.
+func validWebhook(req *model.SetWebhookRequest) bool {
+	if len(req.URL) > 2000 || len(req.Secret) > 200 {
+		return false
 	}
-	if req.RoomID == "" || req.AdminSecret == "" {
-		return nil, centrifuge.ErrorBadRequest
+	if req.URL == "" {
+		return true
 	}
+	u, err := url.Parse(req.URL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
 
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
-		if r.AdminSecret != req.AdminSecret {
-			return room.ErrInvalidAdmin
+func (h *Hub) rpcSetWebhook(c *Call, req *model.SetWebhookRequest) (any, error) {
+	if req.URL != "" {
+		if !h.webhooks.RoomWebhooks() {
+			return nil, centrifuge.ErrorNotAvailable
 		}
-		return room.PrevTicket(r)
-	})
-	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
+		if !h.webhooks.RoomEndpointAllowed(req.URL) {
+			return nil, centrifuge.ErrorBadRequest
 		}
-		if errors.Is(err, room.ErrInvalidAdmin) {
-			return nil, centrifuge.ErrorPermissionDenied
+	}
+	return nil, h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
+		if req.URL == "" {
+			r.Webhook = nil
+			return nil
 		}
-		return nil, &centrifuge.Error{Code: 400, Message: err.Error()}
+		r.Webhook = &model.WebhookConfig{URL: req.URL, Secret: req.Secret}
+		return nil
+	})
+}
+
+func (h *Hub) rpcStartReveal(c *Call, req *model.AdminActionRequest) (any, error) {
+	err := h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
+		return room.StartCountdown(r)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
+	return nil, nil
 }
 
-func (h *Hub) rpcSetTicket(data []byte) ([]byte, error) {
-	var req model.SetTicketRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	if req.RoomID == "" || req.AdminSecret == "" || req.TicketID == "" {
-		return nil, centrifuge.ErrorBadRequest
-	}
-
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
-		if r.AdminSecret != req.AdminSecret {
-			return room.ErrInvalidAdmin
+func (h *Hub) rpcRevealVotes(c *Call, req *model.AdminActionRequest) (any, error) {
+	err := h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
+		if err := room.RevealVotes(r); err != nil {
+			return err
 		}
-		return room.NavigateToTicket(r, req.TicketID)
+		h.notifyRevealed(r)
+		return nil
 	})
 	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
-		}
-		if errors.Is(err, room.ErrInvalidAdmin) {
-			return nil, centrifuge.ErrorPermissionDenied
-		}
-		return nil, &centrifuge.Error{Code: 400, Message: err.Error()}
+		return nil, err
 	}
 
 	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
+	return nil, nil
 }
 
-func (h *Hub) rpcUpdateRoomName(data []byte) ([]byte, error) {
-	var req model.UpdateRoomNameRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	if req.RoomID == "" || req.AdminSecret == "" || len(req.Name) > 200 {
-		return nil, centrifuge.ErrorBadRequest
-	}
-
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
-		if r.AdminSecret != req.AdminSecret {
-			return room.ErrInvalidAdmin
+// navigate runs an admin action that may open voting on another ticket and
+// emits voting.started when it does.
+func (h *Hub) navigate(ctx context.Context, roomID string, action func(r *model.Room) error) (any, error) {
+	err := h.rooms.WithRoomContext(ctx, roomID, func(r *model.Room) error {
+		prevState, prevTicketID := r.State, r.CurrentTicketID
+		if err := action(r); err != nil {
+			return err
 		}
-		room.SetName(r, req.Name)
+		h.notifyVotingStarted(r, prevState, prevTicketID)
 		return nil
 	})
 	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
-		}
-		if errors.Is(err, room.ErrInvalidAdmin) {
-			return nil, centrifuge.ErrorPermissionDenied
-		}
-		return nil, centrifuge.ErrorInternal
+		return nil, err
 	}
 
-	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
+	h.broadcastRoomState(roomID)
+	return nil, nil
 }
 
-func (h *Hub) rpcStartFreeVote(data []byte) ([]byte, error) {
-	var req model.AdminActionRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	if req.RoomID == "" || req.AdminSecret == "" {
-		return nil, centrifuge.ErrorBadRequest
-	}
+func (h *Hub) rpcResetVotes(c *Call, req *model.AdminActionRequest) (any, error) {
+	return h.navigate(c.Context(), req.RoomID, room.ResetVotes)
+}
 
+func (h *Hub) rpcNextTicket(c *Call, req *model.AdminActionRequest) (any, error) {
+	return h.navigate(c.Context(), req.RoomID, room.NextTicketByIndex)
+}
+
+func (h *Hub) rpcPrevTicket(c *Call, req *model.AdminActionRequest) (any, error) {
+	return h.navigate(c.Context(), req.RoomID, room.PrevTicket)
+}
+
+func (h *Hub) rpcSetTicket(c *Call, req *model.SetTicketRequest) (any, error) {
+	return h.navigate(c.Context(), req.RoomID, func(r *model.Room) error {
+		return room.NavigateToTicket(r, req.TicketID)
+	})
+}
+
+func (h *Hub) rpcStartFreeVote(c *Call, req *model.AdminActionRequest) (any, error) {
 	ticketID := uuid.New().String()
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
-		if r.AdminSecret != req.AdminSecret {
-			return room.ErrInvalidAdmin
-		}
+	return h.navigate(c.Context(), req.RoomID, func(r *model.Room) error {
 		return room.StartFreeVote(r, ticketID)
 	})
+}
+
+func (h *Hub) rpcUpdateRoomName(c *Call, req *model.UpdateRoomNameRequest) (any, error) {
+	err := h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
+		room.SetName(r, req.Name)
+		return nil
+	})
 	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
-		}
-		if errors.Is(err, room.ErrInvalidAdmin) {
-			return nil, centrifuge.ErrorPermissionDenied
-		}
-		return nil, &centrifuge.Error{Code: 400, Message: err.Error()}
+		return nil, err
 	}
 
 	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
+	return nil, nil
 }
 
-func (h *Hub) rpcSetThinking(client *centrifuge.Client, data []byte) ([]byte, error) {
-	var req model.SetThinkingRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	if req.RoomID == "" || req.UserID == "" {
-		return nil, centrifuge.ErrorBadRequest
+func (h *Hub) rpcSetThinking(c *Call, req *model.SetThinkingRequest) (any, error) {
+	err := h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
+		return room.SetUserThinking(r, req.UserID, req.Thinking)
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	h.broadcastRoomState(req.RoomID)
+	return nil, nil
+}
+
+// rpcHeartbeat lets a client report user activity without changing anything.
+// The activity itself is recorded by the trackActivity middleware.
+func (h *Hub) rpcHeartbeat(c *Call, _ *struct{}) (any, error) {
 	h.mu.RLock()
-	info, ok := h.clients[client.ID()]
+	_, ok := h.clients[c.ClientID]
 	h.mu.RUnlock()
-	if !ok || info.UserID != req.UserID || info.RoomID != req.RoomID {
+	if !ok {
 		return nil, centrifuge.ErrorPermissionDenied
 	}
+	return nil, nil
+}
 
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
-		return room.SetUserThinking(r, req.UserID, req.Thinking)
+// rpcNudge reminds users that the room is waiting for their vote. It targets
+// one user, or every connected user who has not voted on the current ticket.
+// Users nudged within nudgeCooldown are skipped.
+func (h *Hub) rpcNudge(c *Call, req *model.NudgeRequest) (any, error) {
+	var targets []string
+	err := h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
+		if req.TargetUserID != "" {
+			if _, ok := r.Users[req.TargetUserID]; !ok {
+				return room.ErrUserNotFound
+			}
+			targets = []string{req.TargetUserID}
+			return nil
+		}
+		var err error
+		targets, err = room.PendingVoters(r)
+		return err
 	})
 	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
+		return nil, err
+	}
+
+	now := time.Now()
+	nudged := make([]string, 0, len(targets))
+	h.mu.Lock()
+	for key, at := range h.nudged {
+		if now.Sub(at) >= nudgeCooldown {
+			delete(h.nudged, key)
 		}
-		if errors.Is(err, room.ErrUserNotFound) {
-			return nil, errorNotFound
+	}
+	for _, id := range targets {
+		key := clientInfo{UserID: id, RoomID: req.RoomID}
+		if _, recent := h.nudged[key]; recent {
+			continue
 		}
-		return nil, centrifuge.ErrorInternal
+		h.nudged[key] = now
+		nudged = append(nudged, id)
 	}
+	h.mu.Unlock()
 
-	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
-}
-
-func (h *Hub) rpcInteractPlayer(client *centrifuge.Client, data []byte) ([]byte, error) {
-	var req model.InteractPlayerRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
+	if req.TargetUserID != "" && len(nudged) == 0 {
+		return nil, centrifuge.ErrorTooManyRequests
 	}
-	if req.RoomID == "" || req.UserID == "" || req.TargetUserID == "" || req.Action == "" {
-		return nil, centrifuge.ErrorBadRequest
+	for _, id := range nudged {
+		h.publishUserEvent(c.Context(), id, model.RoomEvent{
+			Type:   "nudge",
+			Action: "vote",
+			FromID: c.UserID,
+			ToID:   id,
+		})
 	}
 
-	h.mu.RLock()
-	info, ok := h.clients[client.ID()]
-	h.mu.RUnlock()
-	if !ok || info.UserID != req.UserID || info.RoomID != req.RoomID {
-		return nil, centrifuge.ErrorPermissionDenied
-	}
+	return model.NudgeResponse{Nudged: nudged}, nil
+}
 
-	err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
+func (h *Hub) rpcInteractPlayer(c *Call, req *model.InteractPlayerRequest) (any, error) {
+	err := h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
 		if _, ok := r.Users[req.TargetUserID]; !ok {
 			return room.ErrUserNotFound
 		}
-		r.PendingEvents = append(r.PendingEvents, model.RoomEvent{
-			Type:   "player_interaction",
-			Action: req.Action,
-			FromID: req.UserID,
-			ToID:   req.TargetUserID,
-		})
 		return nil
 	})
 	if err != nil {
-		if errors.Is(err, room.ErrRoomNotFound) {
-			return nil, errorNotFound
-		}
-		if errors.Is(err, room.ErrUserNotFound) {
-			return nil, errorNotFound
-		}
-		return nil, centrifuge.ErrorInternal
+		return nil, err
 	}
 
-	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
+	h.publishEvent(c.Context(), req.RoomID, model.RoomEvent{
+		Type:   "player_interaction",
+		Action: req.Action,
+		FromID: req.UserID,
+		ToID:   req.TargetUserID,
+	})
+	return nil, nil
 }
 
-func (h *Hub) rpcThemeInteract(client *centrifuge.Client, data []byte) ([]byte, error) {
-	var req model.ThemeInteractRequest
-	if err := json.Unmarshal(data, &req); err != nil {
-		return nil, centrifuge.ErrorBadRequest
-	}
-	if req.RoomID == "" || req.UserID == "" || req.Action == "" {
-		return nil, centrifuge.ErrorBadRequest
-	}
-
-	h.mu.RLock()
-	info, ok := h.clients[client.ID()]
-	h.mu.RUnlock()
-	if !ok || info.UserID != req.UserID || info.RoomID != req.RoomID {
-		return nil, centrifuge.ErrorPermissionDenied
-	}
-
+func (h *Hub) rpcThemeInteract(c *Call, req *model.ThemeInteractRequest) (any, error) {
 	switch req.Action {
 	case "feed_fire":
 		var ffReq model.FeedFireRequest
 		if err := json.Unmarshal(req.Data, &ffReq); err != nil {
 			return nil, centrifuge.ErrorBadRequest
 		}
-		err := h.rooms.WithRoom(req.RoomID, func(r *model.Room) error {
-			return campfire.FeedFire(r, req.UserID, ffReq.TreeID, ffReq.FromX, ffReq.FromY)
+		var ev model.RoomEvent
+		err := h.rooms.WithRoomContext(c.Context(), req.RoomID, func(r *model.Room) error {
+			var err error
+			ev, err = campfire.FeedFire(r, req.UserID, ffReq.TreeID, ffReq.FromX, ffReq.FromY)
+			return err
 		})
 		if err != nil {
-			if errors.Is(err, room.ErrRoomNotFound) {
-				return nil, errorNotFound
-			}
-			return nil, &centrifuge.Error{Code: 400, Message: err.Error()}
+			return nil, err
 		}
+		h.publishEvent(c.Context(), req.RoomID, ev)
 	default:
 		return nil, centrifuge.ErrorMethodNotFound
 	}
 
 	h.broadcastRoomState(req.RoomID)
-	return []byte(`{}`), nil
+	return nil, nil
 }
 
 // handleDisconnect marks the user as disconnected and broadcasts updated state.
 // It checks whether the user still has other active connections (e.g. multiple
 // tabs or a reconnect) before marking them offline.
 func (h *Hub) handleDisconnect(clientID string) {
+	h.limiter.forget(clientID)
 	info, ok := h.unregisterClient(clientID)
 	if !ok {
 		return
 	}
 
 	// Check if the user still has another active connection to this room.
-	h.mu.RLock()
-	stillConnected := false
+	h.mu.Lock()
+	if h.userConnected(info) {
+		h.mu.Unlock()
+		h.logger.Debug().
+			Str("room_id", info.RoomID).
+			Str("user_id", info.UserID).
+			Msg("client disconnected but user still has active connections")
+		return
+	}
+	if h.grace <= 0 || h.closing {
+		h.mu.Unlock()
+		h.markOffline(info)
+		return
+	}
+
+	// Keep the user online for the grace period; registerClient cancels the
+	// timer when they come back.
+	if t, ok := h.offline[info]; ok {
+		t.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(h.grace, func() {
+		h.mu.Lock()
+		pending := h.offline[info] == t && !h.userConnected(info)
+		if h.offline[info] == t {
+			delete(h.offline, info)
+		}
+		h.mu.Unlock()
+		if pending {
+			h.markOffline(info)
+		}
+	})
+	h.offline[info] = t
+	h.mu.Unlock()
+
+	h.logger.Debug().
+		Str("room_id", info.RoomID).
+		Str("user_id", info.UserID).
+		Dur("grace", h.grace).
+		Msg("user disconnected, waiting for reconnect")
+}
+
+// userConnected reports whether any client is registered for the user in the
+// room. The caller must hold h.mu.
+func (h *Hub) userConnected(info clientInfo) bool {
 	for _, ci := range h.clients {
-		if ci.UserID == info.UserID && ci.RoomID == info.RoomID {
-			stillConnected = true
-			break
+		if ci == info {
+			return true
 		}
 	}
-	h.mu.RUnlock()
+	return false
+}
 
-	if stillConnected {
+// connectedElsewhere reports whether the user is subscribed to the room
+// through another process sharing the rooms, for example after reconnecting
+// to a different replica during the grace period.
+func (h *Hub) connectedElsewhere(info clientInfo) bool {
+	if !h.rooms.Shared() {
+		return false
+	}
+	res, err := h.node.Presence("room:" + info.RoomID)
+	if err != nil {
+		h.logger.Warn().Err(err).Str("room_id", info.RoomID).Msg("get room presence")
+		return false
+	}
+	for _, ci := range res.Presence {
+		var sd model.SubscribeRoomData
+		if json.Unmarshal(ci.ChanInfo, &sd) == nil && sd.UserID == info.UserID {
+			return true
+		}
+	}
+	return false
+}
+
+// markOffline marks the user offline and broadcasts the change, unless the
+// user is still connected through another process.
+func (h *Hub) markOffline(info clientInfo) {
+	if h.connectedElsewhere(info) {
 		h.logger.Debug().
 			Str("room_id", info.RoomID).
 			Str("user_id", info.UserID).
-			Msg("client disconnected but user still has active connections")
+			Msg("client disconnected but user is connected to another server")
 		return
 	}
-
 	h.logger.Info().
 		Str("room_id", info.RoomID).
 		Str("user_id", info.UserID).
@@ -847,6 +1634,9 @@ func (h *Hub) handleDisconnect(clientID string) {
 
 	err := h.rooms.WithRoom(info.RoomID, func(r *model.Room) error {
 		room.RemoveUser(r, info.UserID)
+		if u, ok := r.Users[info.UserID]; ok {
+			h.notify(r, webhook.EventUserLeft, webhook.UserData{UserID: u.ID, Name: u.Name})
+		}
 		return nil
 	})
 	if err != nil {
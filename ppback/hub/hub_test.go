@@ -3,14 +3,20 @@ package hub
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"pockerplan/ppback/model"
 	"pockerplan/ppback/room"
+	"pockerplan/ppback/tracker"
+	"pockerplan/ppback/webhook"
 
 	"github.com/centrifugal/centrifuge"
 	centrifugecli "github.com/centrifugal/centrifuge-go"
@@ -24,11 +30,16 @@ type testEnv struct {
 	wsURL string
 }
 
-func newTestEnv(t *testing.T) *testEnv {
+func newTestEnv(t *testing.T, opts ...Option) *testEnv {
+	t.Helper()
+	return newTestEnvWithRooms(t, room.NewManager(), opts...)
+}
+
+// newTestEnvWithRooms starts a hub on the given room manager.
+func newTestEnvWithRooms(t *testing.T, rm *room.Manager, opts ...Option) *testEnv {
 	t.Helper()
-	rm := room.NewManager()
 	logger := zerolog.Nop()
-	h, err := New(rm, 3, false, logger)
+	h, err := New(rm, 3, false, logger, opts...)
 	if err != nil {
 		t.Fatalf("create hub: %v", err)
 	}
@@ -53,6 +64,19 @@ func newTestEnv(t *testing.T) *testEnv {
 	return &testEnv{hub: h, rooms: rm, srv: srv, wsURL: wsURL}
 }
 
+// user returns a copy of a room member, read under the room lock.
+func (e *testEnv) user(t *testing.T, roomID, userID string) model.User {
+	t.Helper()
+	var u model.User
+	if err := e.rooms.WithRoom(roomID, func(r *model.Room) error {
+		u = *r.Users[userID]
+		return nil
+	}); err != nil {
+		t.Fatalf("get room: %v", err)
+	}
+	return u
+}
+
 func (e *testEnv) newClient(t *testing.T) *centrifugecli.Client {
 	t.Helper()
 	client := centrifugecli.NewJsonClient(e.wsURL, centrifugecli.Config{})
@@ -63,6 +87,32 @@ func (e *testEnv) newClient(t *testing.T) *centrifugecli.Client {
 	return client
 }
 
+// subscribeRoom subscribes to a room channel and waits until the subscription
+// is active, passing publications to onPub.
+func subscribeRoom(t *testing.T, client *centrifugecli.Client, roomID string, onPub func([]byte)) {
+	t.Helper()
+	subscribeChannel(t, client, "room:"+roomID, onPub)
+}
+
+func subscribeChannel(t *testing.T, client *centrifugecli.Client, channel string, onPub func([]byte)) {
+	t.Helper()
+	sub, err := client.NewSubscription(channel)
+	if err != nil {
+		t.Fatalf("new subscription: %v", err)
+	}
+	subscribed := make(chan struct{})
+	sub.OnSubscribed(func(centrifugecli.SubscribedEvent) { close(subscribed) })
+	sub.OnPublication(func(e centrifugecli.PublicationEvent) { onPub(e.Data) })
+	if err := sub.Subscribe(); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	select {
+	case <-subscribed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for subscription")
+	}
+}
+
 func rpcCreateRoom(t *testing.T, client *centrifugecli.Client, scaleID, userName, avatarID string) model.CreateRoomResponse {
 	t.Helper()
 	data, _ := json.Marshal(model.CreateRoomRequest{
@@ -141,6 +191,25 @@ func TestCreateRoom(t *testing.T) {
 	}
 }
 
+func TestSetCountdown(t *testing.T) {
+	env := newTestEnv(t)
+	client := env.newClient(t)
+
+	before := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+	env.hub.SetCountdown(7)
+	after := rpcCreateRoom(t, client, "fibonacci", "Bob", "dog")
+
+	for id, want := range map[string]int{before.RoomID: 3, after.RoomID: 7} {
+		r, err := env.rooms.Get(id)
+		if err != nil {
+			t.Fatalf("room not found: %v", err)
+		}
+		if r.Countdown != want {
+			t.Errorf("expected countdown %d, got %d", want, r.Countdown)
+		}
+	}
+}
+
 func TestCreateRoomValidation(t *testing.T) {
 	env := newTestEnv(t)
 	client := env.newClient(t)
@@ -346,7 +415,7 @@ func TestUpdateRoomNameEmpty(t *testing.T) {
 }
 
 func TestUpdateRoomNameBroadcasts(t *testing.T) {
-	env := newTestEnv(t)
+	env := newTestEnv(t, WithBroadcastWindow(0))
 	client := env.newClient(t)
 	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
 
@@ -375,12 +444,15 @@ func TestUpdateRoomNameBroadcasts(t *testing.T) {
 
 	select {
 	case d := <-published:
-		var snap model.RoomSnapshot
-		if err := json.Unmarshal(d, &snap); err != nil {
+		var update model.RoomUpdate
+		if err := json.Unmarshal(d, &update); err != nil {
 			t.Fatalf("unmarshal: %v", err)
 		}
-		if snap.Name != "Sprint 42" {
-			t.Errorf("expected name 'Sprint 42' in broadcast, got %q", snap.Name)
+		if update.Type != model.UpdateDelta || update.Delta.Name == nil || *update.Delta.Name != "Sprint 42" {
+			t.Errorf("expected name 'Sprint 42' in delta, got %s", d)
+		}
+		if update.Delta.Users != nil || update.Delta.Tickets != nil {
+			t.Errorf("expected only the name in delta, got %s", d)
 		}
 	case <-time.After(2 * time.Second):
 		t.Error("timed out waiting for broadcast")
@@ -462,7 +534,7 @@ func TestUnknownMethod(t *testing.T) {
 }
 
 func TestDisconnect(t *testing.T) {
-	env := newTestEnv(t)
+	env := newTestEnv(t, WithDisconnectGrace(0))
 
 	client := centrifugecli.NewJsonClient(env.wsURL, centrifugecli.Config{})
 	if err := client.Connect(); err != nil {
@@ -485,6 +557,91 @@ func TestDisconnect(t *testing.T) {
 	}
 }
 
+func TestDisconnectGrace(t *testing.T) {
+	env := newTestEnv(t, WithDisconnectGrace(300*time.Millisecond))
+
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	client.Close()
+	time.Sleep(100 * time.Millisecond)
+	if !env.user(t, created.RoomID, created.UserID).Connected {
+		t.Error("expected the user to stay connected during the grace period")
+	}
+
+	time.Sleep(400 * time.Millisecond)
+	if env.user(t, created.RoomID, created.UserID).Connected {
+		t.Error("expected disconnected after the grace period")
+	}
+}
+
+func TestReconnectWithinGrace(t *testing.T) {
+	env := newTestEnv(t, WithDisconnectGrace(300*time.Millisecond))
+
+	client1 := env.newClient(t)
+	created := rpcCreateRoom(t, client1, "fibonacci", "Alice", "cat")
+	client1.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	client2 := env.newClient(t)
+	rpcJoinRoom(t, client2, created.RoomID, "Alice", "cat", created.UserID)
+
+	time.Sleep(400 * time.Millisecond)
+	if !env.user(t, created.RoomID, created.UserID).Connected {
+		t.Error("expected reconnecting to cancel the offline transition")
+	}
+}
+
+func TestIdleUserIsAway(t *testing.T) {
+	env := newTestEnv(t, WithIdleTimeout(100*time.Millisecond), WithBroadcastWindow(0))
+	done := make(chan struct{})
+	defer close(done)
+	env.hub.StartCampfireLoop(20*time.Millisecond, done)
+
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	time.Sleep(300 * time.Millisecond)
+	if !env.user(t, created.RoomID, created.UserID).Away {
+		t.Fatal("expected idle user to be away")
+	}
+
+	if _, err := client.RPC(context.Background(), "heartbeat", []byte(`{}`)); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	if env.user(t, created.RoomID, created.UserID).Away {
+		t.Error("expected heartbeat to clear away")
+	}
+}
+
+func TestActivityWritesAreThrottled(t *testing.T) {
+	env := newTestEnv(t, WithIdleTimeout(time.Minute))
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	heartbeat := func() {
+		if _, err := client.RPC(context.Background(), "heartbeat", []byte(`{}`)); err != nil {
+			t.Fatalf("heartbeat: %v", err)
+		}
+	}
+	heartbeat()
+	stamp := env.user(t, created.RoomID, created.UserID).LastActiveAt
+	heartbeat()
+	if got := env.user(t, created.RoomID, created.UserID).LastActiveAt; !got.Equal(stamp) {
+		t.Errorf("expected no room write within half the idle timeout, stamp moved from %v to %v", stamp, got)
+	}
+}
+
+func TestHeartbeatRequiresJoin(t *testing.T) {
+	env := newTestEnv(t)
+	client := env.newClient(t)
+
+	_, err := client.RPC(context.Background(), "heartbeat", []byte(`{}`))
+	if err == nil {
+		t.Fatal("expected error for a connection that has not joined a room")
+	}
+}
+
 func TestReconnect(t *testing.T) {
 	env := newTestEnv(t)
 
@@ -513,7 +670,7 @@ func TestReconnect(t *testing.T) {
 }
 
 func TestSubscribeBroadcast(t *testing.T) {
-	env := newTestEnv(t)
+	env := newTestEnv(t, WithBroadcastWindow(0))
 	client := env.newClient(t)
 	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
 
@@ -543,18 +700,401 @@ func TestSubscribeBroadcast(t *testing.T) {
 
 	select {
 	case data := <-published:
-		var snap model.RoomSnapshot
-		if err := json.Unmarshal(data, &snap); err != nil {
+		var update model.RoomUpdate
+		if err := json.Unmarshal(data, &update); err != nil {
 			t.Fatalf("unmarshal: %v", err)
 		}
-		if len(snap.Tickets) != 1 {
-			t.Errorf("expected 1 ticket, got %d", len(snap.Tickets))
+		if update.Type != model.UpdateDelta || len(update.Delta.Tickets) != 1 {
+			t.Errorf("expected 1 ticket in delta, got %s", data)
 		}
 	case <-time.After(2 * time.Second):
 		t.Error("timed out waiting for broadcast")
 	}
 }
 
+func TestSubscribeReceivesSnapshot(t *testing.T) {
+	env := newTestEnv(t)
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	sub, err := client.NewSubscription("room:" + created.RoomID)
+	if err != nil {
+		t.Fatalf("new subscription: %v", err)
+	}
+	subscribed := make(chan []byte, 1)
+	sub.OnSubscribed(func(e centrifugecli.SubscribedEvent) {
+		subscribed <- e.Data
+	})
+	published := make(chan []byte, 10)
+	sub.OnPublication(func(e centrifugecli.PublicationEvent) {
+		published <- e.Data
+	})
+	if err := sub.Subscribe(); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	var initial model.RoomUpdate
+	select {
+	case data := <-subscribed:
+		if err := json.Unmarshal(data, &initial); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for subscription")
+	}
+	if initial.Type != model.UpdateSnapshot || initial.Snapshot == nil || len(initial.Snapshot.Users) != 1 {
+		t.Fatalf("expected a snapshot with the creator, got %+v", initial)
+	}
+	if initial.Snapshot.Revision != initial.Revision {
+		t.Errorf("snapshot revision %d differs from update revision %d", initial.Snapshot.Revision, initial.Revision)
+	}
+
+	// Setting the same name twice publishes once.
+	for range 2 {
+		data, _ := json.Marshal(model.UpdateRoomNameRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, Name: "Sprint 42"})
+		if _, err := client.RPC(context.Background(), "update_room_name", data); err != nil {
+			t.Fatalf("update_room_name: %v", err)
+		}
+	}
+	select {
+	case data := <-published:
+		var update model.RoomUpdate
+		if err := json.Unmarshal(data, &update); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if update.Revision != initial.Revision+1 {
+			t.Errorf("expected revision %d, got %d", initial.Revision+1, update.Revision)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for broadcast")
+	}
+	select {
+	case data := <-published:
+		t.Errorf("expected no publication for an unchanged room, got %s", data)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestSubscribeWithUserReconnects(t *testing.T) {
+	env := newTestEnv(t, WithDisconnectGrace(0))
+
+	client1 := centrifugecli.NewJsonClient(env.wsURL, centrifugecli.Config{})
+	if err := client1.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	created := rpcCreateRoom(t, client1, "fibonacci", "Alice", "cat")
+	client1.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client2 := centrifugecli.NewJsonClient(env.wsURL, centrifugecli.Config{})
+	if err := client2.Connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	data, _ := json.Marshal(model.SubscribeRoomData{UserID: created.UserID})
+	sub, err := client2.NewSubscription("room:"+created.RoomID, centrifugecli.SubscriptionConfig{Data: data})
+	if err != nil {
+		t.Fatalf("new subscription: %v", err)
+	}
+	subscribed := make(chan []byte, 1)
+	sub.OnSubscribed(func(e centrifugecli.SubscribedEvent) {
+		subscribed <- e.Data
+	})
+	if err := sub.Subscribe(); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	select {
+	case data := <-subscribed:
+		var update model.RoomUpdate
+		if err := json.Unmarshal(data, &update); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if !update.Snapshot.Users[0].Connected {
+			t.Error("expected the subscribe snapshot to show the user online")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for subscription")
+	}
+
+	if !env.user(t, created.RoomID, created.UserID).Connected {
+		t.Error("expected connected after subscribing with user ID")
+	}
+
+	// The subscribing connection is registered, so closing it marks the user offline.
+	client2.Close()
+	time.Sleep(200 * time.Millisecond)
+	if env.user(t, created.RoomID, created.UserID).Connected {
+		t.Error("expected disconnected after the connection closed")
+	}
+}
+
+func TestSubscribeRecoversMissedUpdates(t *testing.T) {
+	env := newTestEnv(t, WithBroadcastWindow(0))
+	admin := env.newClient(t)
+	created := rpcCreateRoom(t, admin, "fibonacci", "Alice", "cat")
+
+	client := env.newClient(t)
+	sub, err := client.NewSubscription("room:" + created.RoomID)
+	if err != nil {
+		t.Fatalf("new subscription: %v", err)
+	}
+	subscribed := make(chan centrifugecli.SubscribedEvent, 2)
+	sub.OnSubscribed(func(e centrifugecli.SubscribedEvent) {
+		subscribed <- e
+	})
+	published := make(chan []byte, 10)
+	sub.OnPublication(func(e centrifugecli.PublicationEvent) {
+		published <- e.Data
+	})
+	if err := sub.Subscribe(); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	select {
+	case e := <-subscribed:
+		if !e.Recoverable {
+			t.Fatal("expected a recoverable subscription")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for subscription")
+	}
+
+	if err := client.Disconnect(); err != nil {
+		t.Fatalf("disconnect: %v", err)
+	}
+	data, _ := json.Marshal(model.UpdateRoomNameRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, Name: "Missed"})
+	if _, err := admin.RPC(context.Background(), "update_room_name", data); err != nil {
+		t.Fatalf("update_room_name: %v", err)
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatalf("reconnect: %v", err)
+	}
+
+	select {
+	case e := <-subscribed:
+		if !e.WasRecovering || !e.Recovered {
+			t.Errorf("expected recovery, got wasRecovering=%v recovered=%v", e.WasRecovering, e.Recovered)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for resubscription")
+	}
+	select {
+	case d := <-published:
+		var update model.RoomUpdate
+		if err := json.Unmarshal(d, &update); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if update.Delta == nil || update.Delta.Name == nil || *update.Delta.Name != "Missed" {
+			t.Errorf("expected the missed rename, got %s", d)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("missed publication was not recovered")
+	}
+}
+
+func TestBroadcastCoalescing(t *testing.T) {
+	env := newTestEnv(t, WithBroadcastWindow(200*time.Millisecond))
+	admin := env.newClient(t)
+	created := rpcCreateRoom(t, admin, "fibonacci", "Alice", "cat")
+	player := env.newClient(t)
+	joined := rpcJoinRoom(t, player, created.RoomID, "Bob", "dog", "")
+	time.Sleep(300 * time.Millisecond) // let the join publication go out
+
+	published := make(chan []byte, 10)
+	subscribeRoom(t, admin, created.RoomID, func(data []byte) { published <- data })
+
+	thinking, _ := json.Marshal(model.SetThinkingRequest{RoomID: created.RoomID, UserID: joined.UserID, Thinking: true})
+	if _, err := player.RPC(context.Background(), "set_thinking", thinking); err != nil {
+		t.Fatalf("set_thinking: %v", err)
+	}
+	for _, action := range []string{"first", "second", "third"} {
+		data, _ := json.Marshal(model.InteractPlayerRequest{RoomID: created.RoomID, UserID: joined.UserID, TargetUserID: created.UserID, Action: action})
+		if _, err := player.RPC(context.Background(), "interact_player", data); err != nil {
+			t.Fatalf("interact_player: %v", err)
+		}
+	}
+
+	// Events go out immediately and in order; the state changes follow in
+	// one merged delta once the window closes.
+	var actions []string
+	var deltas []*model.RoomDelta
+	timeout := time.After(2 * time.Second)
+	for len(deltas) == 0 {
+		select {
+		case data := <-published:
+			var update model.RoomUpdate
+			if err := json.Unmarshal(data, &update); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			switch update.Type {
+			case model.UpdateEvent:
+				actions = append(actions, update.Event.Action)
+			case model.UpdateDelta:
+				deltas = append(deltas, update.Delta)
+			}
+		case <-timeout:
+			t.Fatal("timed out waiting for broadcast")
+		}
+	}
+	if strings.Join(actions, ",") != "first,second,third" {
+		t.Errorf("expected events in order before the delta, got %v", actions)
+	}
+	if len(deltas[0].Users) != 1 || !deltas[0].Users[0].Thinking {
+		t.Errorf("expected the thinking flag in the merged delta, got %+v", deltas[0])
+	}
+	select {
+	case data := <-published:
+		t.Errorf("expected a single state publication, got another: %s", data)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestInteractPlayerPublishesEvent(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.newClient(t)
+	created := rpcCreateRoom(t, admin, "fibonacci", "Alice", "cat")
+	player := env.newClient(t)
+	joined := rpcJoinRoom(t, player, created.RoomID, "Bob", "dog", "")
+
+	events := make(chan *model.RoomEvent, 10)
+	subscribeRoom(t, admin, created.RoomID, func(data []byte) {
+		var update model.RoomUpdate
+		if json.Unmarshal(data, &update) == nil && update.Type == model.UpdateEvent {
+			events <- update.Event
+		}
+	})
+
+	data, _ := json.Marshal(model.InteractPlayerRequest{RoomID: created.RoomID, UserID: joined.UserID, TargetUserID: created.UserID, Action: "paper_throw"})
+	if _, err := player.RPC(context.Background(), "interact_player", data); err != nil {
+		t.Fatalf("interact_player: %v", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.ID == "" || ev.At.IsZero() {
+			t.Errorf("expected event ID and time, got %+v", ev)
+		}
+		if ev.Action != "paper_throw" || ev.FromID != joined.UserID || ev.ToID != created.UserID {
+			t.Errorf("unexpected event %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestNudge(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.newClient(t)
+	created := rpcCreateRoom(t, admin, "fibonacci", "Alice", "cat")
+	player := env.newClient(t)
+	joined := rpcJoinRoom(t, player, created.RoomID, "Bob", "dog", "")
+
+	events := make(chan model.RoomEvent, 10)
+	subscribeChannel(t, player, "user:"+joined.UserID, func(data []byte) {
+		var ev model.RoomEvent
+		if json.Unmarshal(data, &ev) == nil {
+			events <- ev
+		}
+	})
+
+	adminReq, _ := json.Marshal(model.AdminActionRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret})
+	if _, err := admin.RPC(context.Background(), "start_free_vote", adminReq); err != nil {
+		t.Fatalf("start_free_vote: %v", err)
+	}
+	voteData, _ := json.Marshal(model.SubmitVoteRequest{RoomID: created.RoomID, UserID: created.UserID, Value: "5"})
+	if _, err := admin.RPC(context.Background(), "submit_vote", voteData); err != nil {
+		t.Fatalf("submit_vote: %v", err)
+	}
+
+	nudge := func(target string) (model.NudgeResponse, error) {
+		data, _ := json.Marshal(model.NudgeRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, TargetUserID: target})
+		res, err := admin.RPC(context.Background(), "nudge", data)
+		var resp model.NudgeResponse
+		if err == nil {
+			_ = json.Unmarshal(res.Data, &resp)
+		}
+		return resp, err
+	}
+
+	resp, err := nudge("")
+	if err != nil {
+		t.Fatalf("nudge: %v", err)
+	}
+	if len(resp.Nudged) != 1 || resp.Nudged[0] != joined.UserID {
+		t.Fatalf("expected only the user who has not voted to be nudged, got %v", resp.Nudged)
+	}
+	select {
+	case ev := <-events:
+		if ev.Type != "nudge" || ev.FromID != created.UserID || ev.ToID != joined.UserID || ev.ID == "" {
+			t.Errorf("unexpected event %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for nudge")
+	}
+
+	// The cooldown skips the user in a room-wide nudge and rejects a targeted one.
+	if resp, err := nudge(""); err != nil || len(resp.Nudged) != 0 {
+		t.Errorf("expected no one nudged during the cooldown, got %v, %v", resp.Nudged, err)
+	}
+	if _, err := nudge(joined.UserID); err == nil {
+		t.Error("expected an error for a targeted nudge during the cooldown")
+	}
+
+	// A targeted nudge reaches users who already voted.
+	if resp, err := nudge(created.UserID); err != nil || len(resp.Nudged) != 1 {
+		t.Errorf("expected the admin to be nudged, got %v, %v", resp.Nudged, err)
+	}
+}
+
+func TestSubscribeUserChannelDenied(t *testing.T) {
+	env := newTestEnv(t)
+	admin := env.newClient(t)
+	created := rpcCreateRoom(t, admin, "fibonacci", "Alice", "cat")
+
+	other := env.newClient(t)
+	sub, err := other.NewSubscription("user:" + created.UserID)
+	if err != nil {
+		t.Fatalf("new subscription: %v", err)
+	}
+	errCh := make(chan error, 1)
+	sub.OnError(func(e centrifugecli.SubscriptionErrorEvent) {
+		errCh <- e.Error
+	})
+	if err := sub.Subscribe(); err != nil {
+		return
+	}
+	select {
+	case <-errCh:
+	case <-time.After(2 * time.Second):
+		t.Error("expected another connection to be denied the user channel")
+	}
+}
+
+func TestDomainErrorCodes(t *testing.T) {
+	env := newTestEnv(t)
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	tests := []struct {
+		name   string
+		method string
+		req    any
+		code   uint32
+	}{
+		{"unknown room", "get_room", model.GetRoomRequest{RoomID: "missing"}, model.CodeRoomNotFound},
+		{"wrong secret", "reveal_votes", model.AdminActionRequest{RoomID: created.RoomID, AdminSecret: "wrong"}, model.CodeInvalidAdminSecret},
+		{"not voting", "submit_vote", model.SubmitVoteRequest{RoomID: created.RoomID, UserID: created.UserID, Value: "5"}, model.CodeNotVoting},
+		{"unknown ticket", "set_ticket", model.SetTicketRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, TicketID: "missing"}, model.CodeTicketNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := json.Marshal(tt.req)
+			_, err := client.RPC(context.Background(), tt.method, data)
+			var rpcErr *centrifugecli.Error
+			if !errors.As(err, &rpcErr) || rpcErr.Code != tt.code {
+				t.Errorf("expected code %d, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
 func TestSubscribeInvalidChannel(t *testing.T) {
 	env := newTestEnv(t)
 	client := env.newClient(t)
@@ -773,3 +1313,559 @@ func TestSubscribeNonexistentRoom(t *testing.T) {
 		t.Error("expected error for nonexistent room channel")
 	}
 }
+
+type fakeIssueProvider struct {
+	tickets []*model.Ticket
+	err     error
+	block   bool // FetchIssues waits for its context to end
+	fetches atomic.Int32
+}
+
+func (p *fakeIssueProvider) Name() string { return "fake" }
+
+func (p *fakeIssueProvider) UpdateEstimate(ctx context.Context, key, estimate string) error {
+	return nil
+}
+
+func (p *fakeIssueProvider) FetchIssues(ctx context.Context, query string) ([]*model.Ticket, error) {
+	p.fetches.Add(1)
+	if p.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	out := make([]*model.Ticket, 0, len(p.tickets))
+	for _, t := range p.tickets {
+		cp := *t
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+func TestImportTickets(t *testing.T) {
+	provider := &fakeIssueProvider{tickets: []*model.Ticket{
+		{Content: "# PROJ-1 Login", ExternalKey: "PROJ-1", ExternalURL: "https://jira.example.com/browse/PROJ-1"},
+		{Content: "# PROJ-2 Logout", ExternalKey: "PROJ-2", ExternalURL: "https://jira.example.com/browse/PROJ-2"},
+	}}
+	env := newTestEnv(t, WithIssueProvider(provider))
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	data, _ := json.Marshal(model.ImportTicketsRequest{
+		RoomID:      created.RoomID,
+		AdminSecret: created.AdminSecret,
+		Query:       "sprint = 12",
+	})
+	result, err := client.RPC(context.Background(), "import_tickets", data)
+	if err != nil {
+		t.Fatalf("import_tickets: %v", err)
+	}
+	var resp model.ImportTicketsResponse
+	if err := json.Unmarshal(result.Data, &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(resp.TicketIDs) != 2 {
+		t.Fatalf("expected 2 imported tickets, got %d", len(resp.TicketIDs))
+	}
+
+	r, _ := env.rooms.Get(created.RoomID)
+	if len(r.Tickets) != 2 {
+		t.Fatalf("expected 2 tickets in room, got %d", len(r.Tickets))
+	}
+	if r.Tickets[0].ExternalKey != "PROJ-1" || r.Tickets[0].Status != model.TicketStatusPending {
+		t.Errorf("unexpected first ticket: %+v", r.Tickets[0])
+	}
+
+	// A repeated import skips issues that are already in the room.
+	result, err = client.RPC(context.Background(), "import_tickets", data)
+	if err != nil {
+		t.Fatalf("import_tickets (repeat): %v", err)
+	}
+	if err := json.Unmarshal(result.Data, &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(resp.TicketIDs) != 0 {
+		t.Errorf("expected no duplicates, got %d", len(resp.TicketIDs))
+	}
+}
+
+func TestImportTicketsErrors(t *testing.T) {
+	t.Run("not configured", func(t *testing.T) {
+		env := newTestEnv(t)
+		client := env.newClient(t)
+		created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+		data, _ := json.Marshal(model.ImportTicketsRequest{
+			RoomID: created.RoomID, AdminSecret: created.AdminSecret, Query: "q",
+		})
+		if _, err := client.RPC(context.Background(), "import_tickets", data); err == nil {
+			t.Error("expected error without issue provider")
+		}
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		provider := &fakeIssueProvider{}
+		env := newTestEnv(t, WithIssueProvider(provider))
+		client := env.newClient(t)
+		created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+		data, _ := json.Marshal(model.ImportTicketsRequest{
+			RoomID: created.RoomID, AdminSecret: "wrong", Query: "q",
+		})
+		if _, err := client.RPC(context.Background(), "import_tickets", data); err == nil {
+			t.Error("expected permission denied")
+		}
+		data, _ = json.Marshal(model.ImportTicketsRequest{
+			RoomID: "missing", AdminSecret: created.AdminSecret, Query: "q",
+		})
+		if _, err := client.RPC(context.Background(), "import_tickets", data); err == nil {
+			t.Error("expected room not found")
+		}
+		if n := provider.fetches.Load(); n != 0 {
+			t.Errorf("expected no tracker search for unauthorized calls, got %d", n)
+		}
+	})
+
+	t.Run("caller gone", func(t *testing.T) {
+		env := newTestEnv(t, WithIssueProvider(&fakeIssueProvider{block: true}))
+		client := env.newClient(t)
+		created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+		data, _ := json.Marshal(model.ImportTicketsRequest{
+			RoomID: created.RoomID, AdminSecret: created.AdminSecret, Query: "q",
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := env.hub.Call(ctx, "import_tickets", data); err == nil {
+			t.Error("expected an error when the caller goes away")
+		}
+		if d := time.Since(start); d > 2*time.Second {
+			t.Errorf("expected the search to stop with the caller, took %v", d)
+		}
+	})
+
+	t.Run("tracker failure", func(t *testing.T) {
+		env := newTestEnv(t, WithIssueProvider(&fakeIssueProvider{err: errors.New("boom")}))
+		client := env.newClient(t)
+		created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+		data, _ := json.Marshal(model.ImportTicketsRequest{
+			RoomID: created.RoomID, AdminSecret: created.AdminSecret, Query: "q",
+		})
+		if _, err := client.RPC(context.Background(), "import_tickets", data); err == nil {
+			t.Error("expected error when tracker fails")
+		}
+	})
+}
+
+// addExternalTicket adds a ticket linked to the given external key via the
+// room manager and opens voting on it.
+func addExternalTicket(t *testing.T, env *testEnv, roomID, key string) string {
+	t.Helper()
+	ticketID := "ticket-" + key
+	err := env.rooms.WithRoom(roomID, func(r *model.Room) error {
+		room.AddTicket(r, &model.Ticket{ID: ticketID, Content: key, ExternalKey: key})
+		return room.NavigateToTicket(r, ticketID)
+	})
+	if err != nil {
+		t.Fatalf("add ticket: %v", err)
+	}
+	return ticketID
+}
+
+func waitSyncStatus(t *testing.T, env *testEnv, roomID, ticketID string, want model.SyncStatus) *model.Ticket {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		var got model.Ticket
+		env.rooms.WithRoom(roomID, func(r *model.Room) error {
+			for _, tk := range r.Tickets {
+				if tk.ID == ticketID {
+					got = *tk
+				}
+			}
+			return nil
+		})
+		if got.SyncStatus == want {
+			return &got
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for sync status %q, got %q", want, got.SyncStatus)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSyncEstimatesUnknownScale(t *testing.T) {
+	provider := &fakeIssueProvider{}
+	env := newTestEnv(t, WithIssueProvider(provider))
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+	addExternalTicket(t, env, created.RoomID, "PROJ-1")
+	err := env.rooms.WithRoom(created.RoomID, func(r *model.Room) error {
+		if err := room.SubmitVote(r, created.UserID, "5"); err != nil {
+			return err
+		}
+		if err := room.RevealVotes(r); err != nil {
+			return err
+		}
+		r.Scale = "removed" // e.g. a custom scale dropped on config reload
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("vote and reveal: %v", err)
+	}
+
+	data, _ := json.Marshal(model.AdminActionRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret})
+	if _, err := client.RPC(context.Background(), "sync_estimates", data); err != nil {
+		t.Fatalf("sync_estimates: %v", err)
+	}
+	failed := waitSyncStatus(t, env, created.RoomID, "ticket-PROJ-1", model.SyncStatusFailed)
+	if !strings.Contains(failed.SyncError, "removed") {
+		t.Errorf("expected the missing scale in the sync error, got %q", failed.SyncError)
+	}
+}
+
+func TestSyncEstimates(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	bodies := map[string]string{}
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/rest/api/2/issue/")
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		calls[key]++
+		n := calls[key]
+		bodies[key] = string(body)
+		mu.Unlock()
+		switch {
+		case key == "PROJ-1" && n == 1:
+			// First attempt fails with a retryable error.
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case key == "PROJ-2":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer stub.Close()
+
+	provider, err := tracker.New(tracker.Config{Kind: "jira", BaseURL: stub.URL, Token: "t"})
+	if err != nil {
+		t.Fatalf("provider: %v", err)
+	}
+	env := newTestEnv(t, WithIssueProvider(provider))
+	env.hub.syncBackoff = time.Millisecond
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	for _, key := range []string{"PROJ-1", "PROJ-2"} {
+		addExternalTicket(t, env, created.RoomID, key)
+		err := env.rooms.WithRoom(created.RoomID, func(r *model.Room) error {
+			if err := room.SubmitVote(r, created.UserID, "5"); err != nil {
+				return err
+			}
+			return room.RevealVotes(r)
+		})
+		if err != nil {
+			t.Fatalf("vote and reveal %s: %v", key, err)
+		}
+	}
+
+	data, _ := json.Marshal(model.AdminActionRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret})
+	result, err := client.RPC(context.Background(), "sync_estimates", data)
+	if err != nil {
+		t.Fatalf("sync_estimates: %v", err)
+	}
+	var resp model.SyncEstimatesResponse
+	if err := json.Unmarshal(result.Data, &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Queued != 2 {
+		t.Fatalf("expected 2 queued, got %d", resp.Queued)
+	}
+
+	synced := waitSyncStatus(t, env, created.RoomID, "ticket-PROJ-1", model.SyncStatusSynced)
+	if synced.SyncedValue != "5" {
+		t.Errorf("expected synced value 5, got %q", synced.SyncedValue)
+	}
+	failed := waitSyncStatus(t, env, created.RoomID, "ticket-PROJ-2", model.SyncStatusFailed)
+	if failed.SyncError == "" {
+		t.Error("expected sync error to be recorded")
+	}
+
+	mu.Lock()
+	if calls["PROJ-1"] != 2 {
+		t.Errorf("expected PROJ-1 to be retried once, got %d calls", calls["PROJ-1"])
+	}
+	if calls["PROJ-2"] != 1 {
+		t.Errorf("expected no retry on 403, got %d calls", calls["PROJ-2"])
+	}
+	if bodies["PROJ-1"] != `{"fields":{"customfield_10016":5}}` {
+		t.Errorf("unexpected body %s", bodies["PROJ-1"])
+	}
+	mu.Unlock()
+
+	// Failures are surfaced in the snapshot.
+	joined := rpcJoinRoom(t, env.newClient(t), created.RoomID, "Bob", "dog", "")
+	var found bool
+	for _, ts := range joined.State.Tickets {
+		if ts.ExternalKey == "PROJ-2" {
+			found = true
+			if ts.SyncStatus != model.SyncStatusFailed || ts.SyncError == "" {
+				t.Errorf("expected failed sync in snapshot, got %+v", ts)
+			}
+		}
+	}
+	if !found {
+		t.Error("ticket PROJ-2 missing from snapshot")
+	}
+
+	// Already synced estimates are not pushed again.
+	result, err = client.RPC(context.Background(), "sync_estimates", data)
+	if err != nil {
+		t.Fatalf("sync_estimates (repeat): %v", err)
+	}
+	json.Unmarshal(result.Data, &resp)
+	if resp.Queued != 1 {
+		t.Errorf("expected only the failed ticket to be queued, got %d", resp.Queued)
+	}
+}
+
+func TestShutdownInterruptsEstimateSync(t *testing.T) {
+	requested := make(chan struct{}, 1)
+	release := make(chan struct{})
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+		// The tracker hangs until the test ends.
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer stub.Close()
+	defer close(release)
+
+	provider, err := tracker.New(tracker.Config{Kind: "jira", BaseURL: stub.URL, Token: "t"})
+	if err != nil {
+		t.Fatalf("provider: %v", err)
+	}
+	env := newTestEnv(t, WithIssueProvider(provider))
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+	ticketID := addExternalTicket(t, env, created.RoomID, "PROJ-1")
+	if err := env.rooms.WithRoom(created.RoomID, func(r *model.Room) error {
+		if err := room.SubmitVote(r, created.UserID, "5"); err != nil {
+			return err
+		}
+		return room.RevealVotes(r)
+	}); err != nil {
+		t.Fatalf("vote and reveal: %v", err)
+	}
+
+	data, _ := json.Marshal(model.AdminActionRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret})
+	if _, err := client.RPC(context.Background(), "sync_estimates", data); err != nil {
+		t.Fatalf("sync_estimates: %v", err)
+	}
+	select {
+	case <-requested:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the tracker request")
+	}
+
+	start := time.Now()
+	if err := env.hub.Shutdown(); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("expected Shutdown to interrupt the sync, took %v", d)
+	}
+	// Shutdown waited for the sync, so its outcome is already recorded.
+	tk := waitSyncStatus(t, env, created.RoomID, ticketID, model.SyncStatusFailed)
+	if tk.SyncError != errSyncInterrupted.Error() {
+		t.Errorf("expected interrupted sync error, got %q", tk.SyncError)
+	}
+}
+
+type webhookReceiver struct {
+	srv    *httptest.Server
+	events chan webhook.Event
+}
+
+func newWebhookReceiver(t *testing.T, secret string) *webhookReceiver {
+	t.Helper()
+	wr := &webhookReceiver{events: make(chan webhook.Event, 32)}
+	wr.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if secret != "" && !webhook.Verify(secret, body, r.Header.Get(webhook.HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var ev webhook.Event
+		if err := json.Unmarshal(body, &ev); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		wr.events <- ev
+	}))
+	t.Cleanup(wr.srv.Close)
+	return wr
+}
+
+// expect waits for the next event and checks its type.
+func (wr *webhookReceiver) expect(t *testing.T, eventType string) webhook.Event {
+	t.Helper()
+	select {
+	case ev := <-wr.events:
+		if ev.Type != eventType {
+			t.Fatalf("expected event %s, got %s", eventType, ev.Type)
+		}
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", eventType)
+		return webhook.Event{}
+	}
+}
+
+func TestServerWideWebhook(t *testing.T) {
+	wr := newWebhookReceiver(t, "global")
+	d := webhook.New(webhook.Config{Endpoints: []webhook.Endpoint{{URL: wr.srv.URL, Secret: "global"}}}, zerolog.Nop())
+	env := newTestEnv(t, WithWebhooks(d))
+	client := env.newClient(t)
+
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+	ev := wr.expect(t, webhook.EventRoomCreated)
+	if ev.RoomID != created.RoomID {
+		t.Errorf("expected room %s, got %s", created.RoomID, ev.RoomID)
+	}
+}
+
+func TestRoomWebhookLifecycle(t *testing.T) {
+	wr := newWebhookReceiver(t, "room-secret")
+	// The receiver listens on loopback, which rooms may only reach when
+	// private hosts are allowed.
+	d := webhook.New(webhook.Config{RoomHosts: []string{"127.0.0.1"}, AllowPrivateRoomHosts: true}, zerolog.Nop())
+	env := newTestEnv(t, WithDisconnectGrace(0), WithWebhooks(d))
+	admin := env.newClient(t)
+	created := rpcCreateRoom(t, admin, "fibonacci", "Alice", "cat")
+
+	setData, _ := json.Marshal(model.SetWebhookRequest{
+		RoomID:      created.RoomID,
+		AdminSecret: created.AdminSecret,
+		URL:         wr.srv.URL,
+		Secret:      "room-secret",
+	})
+	if _, err := admin.RPC(context.Background(), "set_webhook", setData); err != nil {
+		t.Fatalf("set_webhook: %v", err)
+	}
+
+	user := env.newClient(t)
+	joined := rpcJoinRoom(t, user, created.RoomID, "Bob", "dog", "")
+	ev := wr.expect(t, webhook.EventUserJoined)
+	if data, _ := ev.Data.(map[string]any); data["userId"] != joined.UserID {
+		t.Errorf("unexpected user.joined data %v", ev.Data)
+	}
+	// A second tab of an online user does not announce them again; the next
+	// event must be voting.started.
+	tab := env.newClient(t)
+	rpcJoinRoom(t, tab, created.RoomID, "Bob", "dog", joined.UserID)
+
+	addData, _ := json.Marshal(model.AddTicketRequest{
+		RoomID: created.RoomID, AdminSecret: created.AdminSecret, Content: "Task",
+	})
+	if _, err := admin.RPC(context.Background(), "add_ticket", addData); err != nil {
+		t.Fatalf("add_ticket: %v", err)
+	}
+	adminData, _ := json.Marshal(model.AdminActionRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret})
+	if _, err := admin.RPC(context.Background(), "next_ticket", adminData); err != nil {
+		t.Fatalf("next_ticket: %v", err)
+	}
+	wr.expect(t, webhook.EventVotingStarted)
+
+	for _, v := range []struct {
+		client *centrifugecli.Client
+		userID string
+	}{{admin, created.UserID}, {user, joined.UserID}} {
+		voteData, _ := json.Marshal(model.SubmitVoteRequest{RoomID: created.RoomID, UserID: v.userID, Value: "8"})
+		if _, err := v.client.RPC(context.Background(), "submit_vote", voteData); err != nil {
+			t.Fatalf("submit_vote: %v", err)
+		}
+	}
+	if _, err := admin.RPC(context.Background(), "reveal_votes", adminData); err != nil {
+		t.Fatalf("reveal_votes: %v", err)
+	}
+	revealed := wr.expect(t, webhook.EventVotesRevealed)
+	if data, _ := revealed.Data.(map[string]any); len(data["votes"].([]any)) != 2 {
+		t.Errorf("expected 2 votes in payload, got %v", revealed.Data)
+	}
+	estimated := wr.expect(t, webhook.EventTicketEstimated)
+	if data, _ := estimated.Data.(map[string]any); data["estimate"] != "8" {
+		t.Errorf("expected estimate 8, got %v", estimated.Data)
+	}
+
+	tab.Close()
+	user.Close()
+	wr.expect(t, webhook.EventUserLeft)
+}
+
+func TestSetWebhookValidation(t *testing.T) {
+	env := newTestEnv(t, WithWebhooks(webhook.New(webhook.Config{RoomHosts: []string{"*"}}, zerolog.Nop())))
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	tests := []struct {
+		name string
+		req  model.SetWebhookRequest
+	}{
+		{"wrong secret", model.SetWebhookRequest{RoomID: created.RoomID, AdminSecret: "wrong", URL: "https://example.com/hook"}},
+		{"bad scheme", model.SetWebhookRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, URL: "ftp://example.com"}},
+		{"no host", model.SetWebhookRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, URL: "https://"}},
+		{"loopback", model.SetWebhookRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, URL: "http://127.0.0.1:8080/hook"}},
+		{"private", model.SetWebhookRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, URL: "http://10.0.0.5/hook"}},
+		{"metadata", model.SetWebhookRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, URL: "http://169.254.169.254/latest"}},
+		{"ipv6 loopback", model.SetWebhookRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, URL: "http://[::1]/hook"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := json.Marshal(tt.req)
+			if _, err := client.RPC(context.Background(), "set_webhook", data); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	// An empty URL removes the webhook.
+	data, _ := json.Marshal(model.SetWebhookRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, URL: "https://example.com/hook"})
+	if _, err := client.RPC(context.Background(), "set_webhook", data); err != nil {
+		t.Fatalf("set_webhook: %v", err)
+	}
+	data, _ = json.Marshal(model.SetWebhookRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret})
+	if _, err := client.RPC(context.Background(), "set_webhook", data); err != nil {
+		t.Fatalf("clear webhook: %v", err)
+	}
+	r, _ := env.rooms.Get(created.RoomID)
+	if r.Webhook != nil {
+		t.Error("expected webhook to be removed")
+	}
+}
+
+func TestRoomWebhooksDisabledByDefault(t *testing.T) {
+	env := newTestEnv(t)
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	data, _ := json.Marshal(model.SetWebhookRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, URL: "https://example.com/hook"})
+	_, err := client.RPC(context.Background(), "set_webhook", data)
+	var rpcErr *centrifugecli.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != centrifuge.ErrorNotAvailable.Code {
+		t.Fatalf("expected not available, got %v", err)
+	}
+
+	// Clearing is still accepted.
+	data, _ = json.Marshal(model.SetWebhookRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret})
+	if _, err := client.RPC(context.Background(), "set_webhook", data); err != nil {
+		t.Fatalf("clear webhook: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package hub
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics holds the hub's Prometheus collectors. Labels only take values from
+// fixed sets: registered method names, error codes and update types.
+type metrics struct {
+	rpcCalls       *prometheus.CounterVec   // method, result
+	rpcDuration    *prometheus.HistogramVec // method
+	broadcastBytes *prometheus.HistogramVec // type
+	campfireLoop   prometheus.Histogram
+}
+
+// WithMetrics registers the hub's metrics with reg: rooms, connections, RPC
+// counts and latencies, broadcast sizes, cleanup removals and campfire loop
+// duration. Without it the hub collects no metrics.
+func WithMetrics(reg prometheus.Registerer) Option {
+	return func(h *Hub) {
+		h.metricsReg = reg
+	}
+}
+
+// newMetrics creates the hub's collectors and registers them with reg.
+func newMetrics(h *Hub, reg prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		rpcCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pockerplan_rpc_calls_total",
+			Help: "RPC calls by method and result (ok or the error code).",
+		}, []string{"method", "result"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pockerplan_rpc_duration_seconds",
+			Help:    "RPC handling time by method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		broadcastBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pockerplan_broadcast_bytes",
+			Help:    "Size of publications by update type.",
+			Buckets: prometheus.ExponentialBuckets(128, 4, 8),
+		}, []string{"type"}),
+		campfireLoop: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "pockerplan_campfire_loop_duration_seconds",
+			Help:    "Time one pass of the campfire loop takes.",
+			Buckets: prometheus.DefBuckets,
+		}),
+	}
+	collectors := []prometheus.Collector{
+		m.rpcCalls,
+		m.rpcDuration,
+		m.broadcastBytes,
+		m.campfireLoop,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "pockerplan_rooms_active",
+			Help: "Rooms currently kept in the room store.",
+		}, func() float64 { return float64(h.rooms.Count()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "pockerplan_clients_connected",
+			Help: "Open WebSocket connections.",
+		}, func() float64 { return float64(h.node.Hub().NumClients()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "pockerplan_cleanup_removed_rooms_total",
+			Help: "Inactive rooms removed by cleanup.",
+		}, func() float64 { return float64(h.rooms.Removed()) }),
+	}
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// measureCalls counts every RPC and records how long it took.
+func (h *Hub) measureCalls(next HandlerFunc) HandlerFunc {
+	return func(c *Call, data []byte) ([]byte, error) {
+		start := time.Now()
+		reply, err := next(c, data)
+		h.metrics.rpcDuration.WithLabelValues(c.Method).Observe(time.Since(start).Seconds())
+		h.metrics.rpcCalls.WithLabelValues(c.Method, rpcResult(err)).Inc()
+		return reply, err
+	}
+}
+
+// rpcResult is the result label of an RPC: "ok" or the code of the error sent
+// to the client.
+func rpcResult(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	var ce *centrifuge.Error
+	if !errors.As(err, &ce) {
+		ce = centrifuge.ErrorInternal
+	}
+	return strconv.FormatUint(uint64(ce.Code), 10)
+}
+
+// observeBroadcast records the size of a publication.
+func (h *Hub) observeBroadcast(kind string, size int) {
+	if h.metrics != nil {
+		h.metrics.broadcastBytes.WithLabelValues(kind).Observe(float64(size))
+	}
+}
@@ -0,0 +1,70 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"pockerplan/ppback/model"
+	"pockerplan/ppback/room"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	env := newTestEnv(t, WithMetrics(reg), WithBroadcastWindow(0))
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	data, _ := json.Marshal(model.GetRoomRequest{RoomID: "missing"})
+	if _, err := client.RPC(context.Background(), "get_room", data); err == nil {
+		t.Fatal("expected error for unknown room")
+	}
+	data, _ = json.Marshal(model.AdminActionRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret})
+	if _, err := client.RPC(context.Background(), "start_free_vote", data); err != nil {
+		t.Fatalf("start_free_vote: %v", err)
+	}
+
+	m := env.hub.metrics
+	if got := testutil.ToFloat64(m.rpcCalls.WithLabelValues("create_room", "ok")); got != 1 {
+		t.Errorf("expected 1 successful create_room, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.rpcCalls.WithLabelValues("get_room", "1000")); got != 1 {
+		t.Errorf("expected 1 get_room with code 1000, got %v", got)
+	}
+	if n := testutil.CollectAndCount(m.broadcastBytes); n == 0 {
+		t.Error("expected broadcast sizes to be recorded")
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	values := make(map[string]float64)
+	for _, f := range families {
+		if len(f.GetMetric()) == 1 {
+			mf := f.GetMetric()[0]
+			values[f.GetName()] = mf.GetGauge().GetValue() + mf.GetCounter().GetValue()
+		}
+	}
+	if values["pockerplan_rooms_active"] != 1 {
+		t.Errorf("expected 1 active room, got %v", values["pockerplan_rooms_active"])
+	}
+	if values["pockerplan_clients_connected"] != 1 {
+		t.Errorf("expected 1 connected client, got %v", values["pockerplan_clients_connected"])
+	}
+	if _, ok := values["pockerplan_cleanup_removed_rooms_total"]; !ok {
+		t.Error("expected cleanup counter to be registered")
+	}
+}
+
+func TestMetricsRegisterTwice(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	newTestEnv(t, WithMetrics(reg))
+	if _, err := New(room.NewManager(), 3, false, zerolog.Nop(), WithMetrics(reg)); err == nil {
+		t.Error("expected error registering metrics twice")
+	}
+}
@@ -0,0 +1,158 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"pockerplan/ppback/model"
+	"pockerplan/ppback/room"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/centrifugal/centrifuge"
+	centrifugecli "github.com/centrifugal/centrifuge-go"
+)
+
+// newRedisTestEnv starts a hub whose rooms, publications and presence are in
+// the given Redis, like one of several server instances.
+func newRedisTestEnv(t *testing.T, mr *miniredis.Miniredis, opts ...Option) *testEnv {
+	t.Helper()
+	store, err := room.NewRedisStore(mr.Addr(), "test")
+	if err != nil {
+		t.Fatalf("new redis store: %v", err)
+	}
+	rm := room.NewManagerWithStore(store, time.Hour)
+	t.Cleanup(rm.Close)
+	return newTestEnvWithRooms(t, rm, append(opts, WithRedis(mr.Addr(), "test"))...)
+}
+
+func TestRedisSharesRoomsBetweenHubs(t *testing.T) {
+	mr := miniredis.RunT(t)
+	envA := newRedisTestEnv(t, mr, WithBroadcastWindow(0))
+	envB := newRedisTestEnv(t, mr, WithBroadcastWindow(0))
+
+	clientA := envA.newClient(t)
+	created := rpcCreateRoom(t, clientA, "fibonacci", "Alice", "cat")
+	updatesA := make(chan model.RoomUpdate, 20)
+	subscribeRoom(t, clientA, created.RoomID, func(data []byte) {
+		var u model.RoomUpdate
+		if json.Unmarshal(data, &u) == nil {
+			updatesA <- u
+		}
+	})
+
+	clientB := envB.newClient(t)
+	joined := rpcJoinRoom(t, clientB, created.RoomID, "Bob", "dog", "")
+	if len(joined.State.Users) != 2 {
+		t.Fatalf("expected Bob to see both users, got %+v", joined.State.Users)
+	}
+
+	// Bob joined through B; Alice, connected to A, sees him arrive.
+	var rev uint64
+	select {
+	case u := <-updatesA:
+		if u.Type != model.UpdateDelta || len(u.Delta.Users) != 1 || u.Delta.Users[0].ID != joined.UserID {
+			t.Fatalf("expected delta adding Bob, got %+v", u)
+		}
+		rev = u.Revision
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for Bob's join on A")
+	}
+
+	// A change made through A continues the revisions B published.
+	data, _ := json.Marshal(model.UpdateRoomNameRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, Name: "Sprint 12"})
+	if _, err := clientA.RPC(context.Background(), "update_room_name", data); err != nil {
+		t.Fatalf("update_room_name: %v", err)
+	}
+	select {
+	case u := <-updatesA:
+		if u.Revision != rev+1 || u.Delta == nil || u.Delta.Name == nil || *u.Delta.Name != "Sprint 12" {
+			t.Fatalf("expected rename at revision %d, got %+v", rev+1, u)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for rename")
+	}
+
+	if n := envB.hub.Stats().Rooms; n != 1 {
+		t.Errorf("expected B to count 1 room, got %d", n)
+	}
+	if !envB.hub.Readiness().Ready {
+		t.Errorf("expected B ready, got %+v", envB.hub.Readiness())
+	}
+}
+
+func TestRedisUserStaysOnlineOnAnotherHub(t *testing.T) {
+	mr := miniredis.RunT(t)
+	envA := newRedisTestEnv(t, mr, WithDisconnectGrace(100*time.Millisecond))
+	envB := newRedisTestEnv(t, mr, WithDisconnectGrace(100*time.Millisecond))
+
+	clientA := envA.newClient(t)
+	created := rpcCreateRoom(t, clientA, "fibonacci", "Alice", "cat")
+
+	// The same user opens the room on B, then loses the connection to A.
+	clientB := envB.newClient(t)
+	subData, _ := json.Marshal(model.SubscribeRoomData{UserID: created.UserID})
+	sub, err := clientB.NewSubscription("room:"+created.RoomID, centrifugecli.SubscriptionConfig{Data: subData})
+	if err != nil {
+		t.Fatalf("new subscription: %v", err)
+	}
+	if err := sub.Subscribe(); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for sub.State() != centrifugecli.SubStateSubscribed {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for subscription")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	clientA.Close()
+	time.Sleep(300 * time.Millisecond)
+	if !envA.user(t, created.RoomID, created.UserID).Connected {
+		t.Error("expected the user to stay online while connected to B")
+	}
+
+	clientB.Close()
+	time.Sleep(300 * time.Millisecond)
+	if envA.user(t, created.RoomID, created.UserID).Connected {
+		t.Error("expected the user offline after leaving B too")
+	}
+}
+
+func TestRedisReadinessChecksStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	env := newRedisTestEnv(t, mr)
+
+	mr.Close()
+	r := env.hub.Readiness()
+	if r.Ready {
+		t.Fatal("expected not ready with Redis stopped")
+	}
+	for _, c := range r.Checks {
+		if c.Name == "store" && c.OK {
+			t.Errorf("expected store check to fail, got %+v", c)
+		}
+	}
+}
+
+func TestRedisLockTimeoutIsRoomBusy(t *testing.T) {
+	mr := miniredis.RunT(t)
+	env := newRedisTestEnv(t, mr)
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	// Another process holds the room lock.
+	mr.Set("test:room:{"+created.RoomID+"}:lock", "other")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	data, _ := json.Marshal(model.UpdateRoomNameRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret, Name: "Sprint 12"})
+	_, err := env.hub.Call(ctx, "update_room_name", data)
+	var ce *centrifuge.Error
+	if !errors.As(err, &ce) || ce.Code != model.CodeRoomBusy || !ce.Temporary {
+		t.Fatalf("expected temporary room busy error, got %v", err)
+	}
+}
@@ -0,0 +1,314 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"pockerplan/ppback/model"
+	"pockerplan/ppback/room"
+
+	"github.com/centrifugal/centrifuge"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Auth is the caller identity an RPC method requires. The hub checks it before
+// the method's handler runs.
+type Auth int
+
+const (
+	// AuthAnonymous methods accept any caller.
+	AuthAnonymous Auth = iota
+	// AuthMember methods require roomId and userId in the request, and a
+	// connection registered as that user in that room.
+	AuthMember
+	// AuthAdmin methods require roomId and the room's adminSecret in the
+	// request.
+	AuthAdmin
+)
+
+// Call describes a single RPC invocation.
+type Call struct {
+	Method   string
+	ClientID string // empty for calls made through Hub.Call
+	RoomID   string // roomId from the request
+	UserID   string // user the connection is registered as in RoomID, if any
+
+	ctx context.Context
+}
+
+// Context returns the context of the call. It carries the call's trace span.
+func (c *Call) Context() context.Context {
+	return c.ctx
+}
+
+// HandlerFunc handles the raw request data of an RPC and returns the raw
+// reply. Errors should be client errors (*centrifuge.Error); anything else
+// is reported to the client as an internal error.
+type HandlerFunc func(c *Call, data []byte) ([]byte, error)
+
+// Middleware wraps every registered RPC handler. It runs before the method's
+// auth check, so it sees rejected calls too.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Method is an RPC method that can be registered with the hub.
+type Method struct {
+	Name    string
+	Auth    Auth
+	Handler HandlerFunc
+}
+
+// NewMethod builds a Method with a typed request. The request data is decoded
+// into Req and passed to validate, which reports whether the request is well
+// formed; validate may be nil. The fields checked by the auth requirement do
+// not need validating again. A nil reply is sent as an empty JSON object.
+func NewMethod[Req any](name string, auth Auth, validate func(*Req) bool, handle func(c *Call, req *Req) (any, error)) Method {
+	return Method{
+		Name: name,
+		Auth: auth,
+		Handler: func(c *Call, data []byte) ([]byte, error) {
+			var req Req
+			if len(data) > 0 {
+				if err := json.Unmarshal(data, &req); err != nil {
+					return nil, centrifuge.ErrorBadRequest
+				}
+			}
+			if validate != nil && !validate(&req) {
+				return nil, centrifuge.ErrorBadRequest
+			}
+			resp, err := handle(c, &req)
+			if err != nil {
+				return nil, rpcError(err)
+			}
+			if resp == nil {
+				return []byte(`{}`), nil
+			}
+			return json.Marshal(resp)
+		},
+	}
+}
+
+// WithMiddleware adds middleware to every RPC method, including the built-in
+// ones. The first middleware is the outermost.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(h *Hub) {
+		h.middleware = append(h.middleware, mw...)
+	}
+}
+
+// WithRPCRateLimit limits how many RPCs a single connection may send: rate
+// per second on average, with bursts of up to burst calls. Calls over the
+// limit fail with ErrorTooManyRequests. Calls made through Call are not
+// limited. A zero rate disables the limit.
+func WithRPCRateLimit(rate float64, burst int) Option {
+	return func(h *Hub) {
+		h.limiter.set(rate, burst)
+	}
+}
+
+// SetRPCRateLimit changes the RPC rate limit of a running hub, as
+// WithRPCRateLimit does at creation. Every connection starts over with a full
+// burst.
+func (h *Hub) SetRPCRateLimit(rate float64, burst int) {
+	h.limiter.set(rate, burst)
+}
+
+// Register adds an RPC method. It fails if a method with the same name is
+// already registered. Registered methods are served over WebSocket and
+// through Call.
+func (h *Hub) Register(m Method) error {
+	if m.Name == "" || m.Handler == nil {
+		return fmt.Errorf("register method %q: name and handler are required", m.Name)
+	}
+
+	handler := h.authorize(m.Auth, m.Handler)
+	for i := len(h.middleware) - 1; i >= 0; i-- {
+		handler = h.middleware[i](handler)
+	}
+
+	h.methodsMu.Lock()
+	defer h.methodsMu.Unlock()
+	if _, ok := h.methods[m.Name]; ok {
+		return fmt.Errorf("register method %q: already registered", m.Name)
+	}
+	h.methods[m.Name] = handler
+	return nil
+}
+
+// handleRPC dispatches an RPC to its registered method and traces it. clientID
+// is empty for calls made through Call.
+func (h *Hub) handleRPC(ctx context.Context, clientID string, method string, data []byte) ([]byte, error) {
+	h.methodsMu.RLock()
+	handler, ok := h.methods[method]
+	h.methodsMu.RUnlock()
+	if !ok {
+		return nil, centrifuge.ErrorMethodNotFound
+	}
+
+	ctx, span := tracer.Start(ctx, "rpc "+method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("rpc.method", method)))
+	defer span.End()
+
+	c := &Call{Method: method, ClientID: clientID, ctx: ctx}
+	reply, err := handler(c, data)
+	span.SetAttributes(attribute.String("room.id", c.RoomID), attribute.String("user.id", c.UserID))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return reply, err
+}
+
+// callIdentity holds the request fields the auth check looks at. Every
+// request type uses the same names for them.
+type callIdentity struct {
+	RoomID      string `json:"roomId"`
+	UserID      string `json:"userId"`
+	AdminSecret string `json:"adminSecret"`
+}
+
+// authorize wraps a handler with the check for its auth requirement and fills
+// in the caller's room and user.
+func (h *Hub) authorize(auth Auth, next HandlerFunc) HandlerFunc {
+	return func(c *Call, data []byte) ([]byte, error) {
+		var id callIdentity
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &id); err != nil {
+				return nil, centrifuge.ErrorBadRequest
+			}
+		}
+		c.RoomID = id.RoomID
+		if c.ClientID != "" {
+			h.mu.RLock()
+			info, ok := h.clients[c.ClientID]
+			h.mu.RUnlock()
+			if ok && info.RoomID == id.RoomID {
+				c.UserID = info.UserID
+			}
+		}
+
+		switch auth {
+		case AuthMember:
+			if id.RoomID == "" || id.UserID == "" {
+				return nil, centrifuge.ErrorBadRequest
+			}
+			// Verify the caller is the user they claim to be.
+			if c.UserID == "" || c.UserID != id.UserID {
+				return nil, centrifuge.ErrorPermissionDenied
+			}
+		case AuthAdmin:
+			if id.RoomID == "" || id.AdminSecret == "" {
+				return nil, centrifuge.ErrorBadRequest
+			}
+			err := h.rooms.WithRoomContext(c.Context(), id.RoomID, func(r *model.Room) error {
+				if r.AdminSecret != id.AdminSecret {
+					return room.ErrInvalidAdmin
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, rpcError(err)
+			}
+		}
+		return next(c, data)
+	}
+}
+
+// logCalls logs every RPC at debug level, and failed ones with their error.
+func (h *Hub) logCalls(next HandlerFunc) HandlerFunc {
+	return func(c *Call, data []byte) ([]byte, error) {
+		start := time.Now()
+		reply, err := next(c, data)
+		ev := h.logger.Debug()
+		if err != nil {
+			ev = ev.Err(err)
+		}
+		ev.Str("method", c.Method).
+			Str("client", c.ClientID).
+			Str("room_id", c.RoomID).
+			Str("user_id", c.UserID).
+			Dur("duration", time.Since(start)).
+			Msg("rpc")
+		return reply, err
+	}
+}
+
+// limitCalls rejects RPCs from connections that exceed the rate limit.
+func (h *Hub) limitCalls(next HandlerFunc) HandlerFunc {
+	return func(c *Call, data []byte) ([]byte, error) {
+		if c.ClientID != "" && !h.limiter.allow(c.ClientID, time.Now()) {
+			return nil, centrifuge.ErrorTooManyRequests
+		}
+		return next(c, data)
+	}
+}
+
+// trackActivity records every RPC from a registered connection as user
+// activity, so that the user is not shown as away.
+func (h *Hub) trackActivity(next HandlerFunc) HandlerFunc {
+	return func(c *Call, data []byte) ([]byte, error) {
+		h.markActive(c.ClientID)
+		return next(c, data)
+	}
+}
+
+// rateLimiter is a token bucket per connection. A zero rate allows every call.
+type rateLimiter struct {
+	rate    float64
+	burst   float64
+	mu      sync.Mutex
+	buckets map[string]*bucket // client ID -> bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	l := &rateLimiter{}
+	l.set(rate, burst)
+	return l
+}
+
+// set changes the limit and drops all buckets.
+func (l *rateLimiter) set(rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = max(rate, 0)
+	l.burst = float64(max(burst, 1))
+	l.buckets = make(map[string]*bucket)
+}
+
+// allow takes a token from the client's bucket and reports whether there was
+// one.
+func (l *rateLimiter) allow(clientID string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate == 0 {
+		return true
+	}
+	b, ok := l.buckets[clientID]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[clientID] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// forget drops the bucket of a disconnected client.
+func (l *rateLimiter) forget(clientID string) {
+	l.mu.Lock()
+	delete(l.buckets, clientID)
+	l.mu.Unlock()
+}
@@ -0,0 +1,203 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"pockerplan/ppback/model"
+
+	"github.com/centrifugal/centrifuge"
+	centrifugecli "github.com/centrifugal/centrifuge-go"
+)
+
+type echoRequest struct {
+	RoomID string `json:"roomId"`
+	UserID string `json:"userId"`
+	Text   string `json:"text"`
+}
+
+type echoResponse struct {
+	Text   string `json:"text"`
+	Caller string `json:"caller"`
+}
+
+func echoMethod(name string, auth Auth) Method {
+	return NewMethod(name, auth, func(req *echoRequest) bool {
+		return req.Text != ""
+	}, func(c *Call, req *echoRequest) (any, error) {
+		return echoResponse{Text: req.Text, Caller: c.UserID}, nil
+	})
+}
+
+func rpcCode(err error) uint32 {
+	var rpcErr *centrifugecli.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code
+	}
+	return 0
+}
+
+func TestRegisterMethod(t *testing.T) {
+	env := newTestEnv(t)
+	if err := env.hub.Register(echoMethod("echo", AuthMember)); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	data, _ := json.Marshal(echoRequest{RoomID: created.RoomID, UserID: created.UserID, Text: "hi"})
+	result, err := client.RPC(context.Background(), "echo", data)
+	if err != nil {
+		t.Fatalf("echo: %v", err)
+	}
+	var resp echoResponse
+	if err := json.Unmarshal(result.Data, &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Text != "hi" || resp.Caller != created.UserID {
+		t.Errorf("unexpected reply %+v", resp)
+	}
+
+	data, _ = json.Marshal(echoRequest{RoomID: created.RoomID, UserID: created.UserID})
+	_, err = client.RPC(context.Background(), "echo", data)
+	if rpcCode(err) != centrifuge.ErrorBadRequest.Code {
+		t.Errorf("expected bad request for invalid request, got %v", err)
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	env := newTestEnv(t)
+	err := env.hub.Register(echoMethod("submit_vote", AuthAnonymous))
+	if err == nil || !strings.Contains(err.Error(), "already registered") {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+	if err := env.hub.Register(Method{Name: "broken"}); err == nil {
+		t.Error("expected error for method without handler")
+	}
+}
+
+func TestMethodAuth(t *testing.T) {
+	env := newTestEnv(t)
+	if err := env.hub.Register(echoMethod("echo_member", AuthMember)); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := env.hub.Register(echoMethod("echo_admin", AuthAdmin)); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	admin := env.newClient(t)
+	created := rpcCreateRoom(t, admin, "fibonacci", "Alice", "cat")
+	other := env.newClient(t)
+	joined := rpcJoinRoom(t, other, created.RoomID, "Bob", "dog", "")
+
+	tests := []struct {
+		name   string
+		client *centrifugecli.Client
+		method string
+		req    map[string]string
+		code   uint32
+	}{
+		{"member", other, "echo_member", map[string]string{"roomId": created.RoomID, "userId": joined.UserID, "text": "x"}, 0},
+		{"member impersonation", other, "echo_member", map[string]string{"roomId": created.RoomID, "userId": created.UserID, "text": "x"}, centrifuge.ErrorPermissionDenied.Code},
+		{"member missing user", other, "echo_member", map[string]string{"roomId": created.RoomID, "text": "x"}, centrifuge.ErrorBadRequest.Code},
+		{"admin", other, "echo_admin", map[string]string{"roomId": created.RoomID, "adminSecret": created.AdminSecret, "text": "x"}, 0},
+		{"admin wrong secret", admin, "echo_admin", map[string]string{"roomId": created.RoomID, "adminSecret": "wrong", "text": "x"}, model.CodeInvalidAdminSecret},
+		{"admin unknown room", admin, "echo_admin", map[string]string{"roomId": "missing", "adminSecret": "x", "text": "x"}, model.CodeRoomNotFound},
+		{"admin missing secret", admin, "echo_admin", map[string]string{"roomId": created.RoomID, "text": "x"}, centrifuge.ErrorBadRequest.Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := json.Marshal(tt.req)
+			_, err := tt.client.RPC(context.Background(), tt.method, data)
+			if code := rpcCode(err); code != tt.code {
+				t.Errorf("expected code %d, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var calls []string
+	record := func(tag string) Middleware {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c *Call, data []byte) ([]byte, error) {
+				calls = append(calls, tag+":"+c.Method)
+				return next(c, data)
+			}
+		}
+	}
+	env := newTestEnv(t, WithMiddleware(record("outer"), record("inner")))
+
+	if _, err := env.hub.Call(context.Background(), "get_room", []byte(`{"roomId":"missing"}`)); err == nil {
+		t.Fatal("expected error for unknown room")
+	}
+	want := []string{"outer:get_room", "inner:get_room"}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, calls)
+	}
+}
+
+func TestRPCRateLimit(t *testing.T) {
+	env := newTestEnv(t, WithRPCRateLimit(0.001, 2))
+	client := env.newClient(t)
+
+	data := []byte(`{"roomId":"missing"}`)
+	for i := 0; i < 2; i++ {
+		if _, err := client.RPC(context.Background(), "get_room", data); rpcCode(err) != model.CodeRoomNotFound {
+			t.Fatalf("call %d: expected room not found, got %v", i, err)
+		}
+	}
+	if _, err := client.RPC(context.Background(), "get_room", data); rpcCode(err) != centrifuge.ErrorTooManyRequests.Code {
+		t.Errorf("expected too many requests, got %v", err)
+	}
+
+	// Calls without a connection are not limited.
+	if _, err := env.hub.Call(context.Background(), "get_room", data); errors.Is(err, centrifuge.ErrorTooManyRequests) {
+		t.Error("expected Call not to be rate limited")
+	}
+}
+
+func TestSetRPCRateLimit(t *testing.T) {
+	env := newTestEnv(t)
+	client := env.newClient(t)
+	data := []byte(`{"roomId":"missing"}`)
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.RPC(context.Background(), "get_room", data); rpcCode(err) != model.CodeRoomNotFound {
+			t.Fatalf("call %d: expected no limit, got %v", i, err)
+		}
+	}
+	env.hub.SetRPCRateLimit(0.001, 1)
+	if _, err := client.RPC(context.Background(), "get_room", data); rpcCode(err) != model.CodeRoomNotFound {
+		t.Fatalf("expected a full burst after the change, got %v", err)
+	}
+	if _, err := client.RPC(context.Background(), "get_room", data); rpcCode(err) != centrifuge.ErrorTooManyRequests.Code {
+		t.Errorf("expected too many requests, got %v", err)
+	}
+	env.hub.SetRPCRateLimit(0, 0)
+	if _, err := client.RPC(context.Background(), "get_room", data); rpcCode(err) != model.CodeRoomNotFound {
+		t.Errorf("expected limit to be lifted, got %v", err)
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	l := newRateLimiter(10, 1)
+	now := time.Now()
+	if !l.allow("c", now) {
+		t.Fatal("expected first call to be allowed")
+	}
+	if l.allow("c", now) {
+		t.Error("expected second call to be limited")
+	}
+	if !l.allow("c", now.Add(100*time.Millisecond)) {
+		t.Error("expected a token after refill")
+	}
+	if !l.allow("d", now) {
+		t.Error("expected separate bucket per client")
+	}
+}
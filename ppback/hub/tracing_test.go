@@ -0,0 +1,95 @@
+package hub
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"pockerplan/ppback/model"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spans receives the spans of every test in the package. Tracers are created
+// once per package from the global provider, so the provider is installed
+// once in TestMain and each test swaps in its own recorder with recordSpans.
+var spans spanSink
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(&spans)))
+	os.Exit(m.Run())
+}
+
+// recordSpans returns a recorder receiving the spans ended until the test
+// finishes.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	spans.rec.Store(sr)
+	t.Cleanup(func() { spans.rec.Store(nil) })
+	return sr
+}
+
+// spanSink is a span processor forwarding to the current test's recorder.
+type spanSink struct {
+	rec atomic.Pointer[tracetest.SpanRecorder]
+}
+
+func (s *spanSink) OnStart(ctx context.Context, span sdktrace.ReadWriteSpan) {
+	if sr := s.rec.Load(); sr != nil {
+		sr.OnStart(ctx, span)
+	}
+}
+
+func (s *spanSink) OnEnd(span sdktrace.ReadOnlySpan) {
+	if sr := s.rec.Load(); sr != nil {
+		sr.OnEnd(span)
+	}
+}
+
+func (s *spanSink) Shutdown(context.Context) error   { return nil }
+func (s *spanSink) ForceFlush(context.Context) error { return nil }
+
+func TestRPCTracing(t *testing.T) {
+	sr := recordSpans(t)
+
+	env := newTestEnv(t, WithBroadcastWindow(0))
+	client := env.newClient(t)
+	created := rpcCreateRoom(t, client, "fibonacci", "Alice", "cat")
+
+	data, _ := json.Marshal(model.AdminActionRequest{RoomID: created.RoomID, AdminSecret: created.AdminSecret})
+	if _, err := env.hub.Call(context.Background(), "start_free_vote", data); err != nil {
+		t.Fatalf("start_free_vote: %v", err)
+	}
+
+	ended := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range sr.Ended() {
+		if _, seen := ended[s.Name()]; !seen || s.Name() == "rpc start_free_vote" {
+			ended[s.Name()] = s
+		}
+	}
+	rpc, ok := ended["rpc start_free_vote"]
+	if !ok {
+		t.Fatal("expected a span for the RPC")
+	}
+	children := make(map[string]int)
+	for _, s := range sr.Ended() {
+		if s.Parent().SpanID() == rpc.SpanContext().SpanID() {
+			children[s.Name()]++
+		}
+	}
+	// The admin check and the action each take the room lock.
+	for _, name := range []string{"room.lock_wait", "room.lock_hold"} {
+		if children[name] != 2 {
+			t.Errorf("expected 2 %q spans under the RPC, got %v", name, children)
+		}
+	}
+	for _, name := range []string{"hub.publish_room_state", "hub.marshal", "centrifuge.Publish"} {
+		if _, ok := ended[name]; !ok {
+			t.Errorf("expected a %q span", name)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package model
+
+import (
+	"bytes"
+	"slices"
+)
+
+// Diff returns the changes turning prev into next and whether there are any.
+// Both snapshots are left untouched.
+func Diff(prev, next *RoomSnapshot) (*RoomDelta, bool) {
+	d := &RoomDelta{}
+
+	if prev.Name != next.Name {
+		d.Name = &next.Name
+	}
+	if prev.State != next.State {
+		d.State = &next.State
+	}
+	if prev.Countdown != next.Countdown {
+		d.Countdown = &next.Countdown
+	}
+	if prev.CurrentTicketID != next.CurrentTicketID {
+		d.CurrentTicketID = &next.CurrentTicketID
+	}
+	if next.ThemeState != nil && !themeStateEqual(prev.ThemeState, next.ThemeState) {
+		d.ThemeState = next.ThemeState
+	}
+
+	prevUsers := make(map[string]*User, len(prev.Users))
+	for _, u := range prev.Users {
+		prevUsers[u.ID] = u
+	}
+	for _, u := range next.Users {
+		if p, ok := prevUsers[u.ID]; !ok || !userEqual(p, u) {
+			d.Users = append(d.Users, u)
+		}
+	}
+	d.RemovedUsers = removedIDs(prev.Users, next.Users, func(u *User) string { return u.ID })
+	d.UserOrder = changedOrder(prev.Users, next.Users, d.RemovedUsers, func(u *User) string { return u.ID })
+
+	prevTickets := make(map[string]*TicketSnapshot, len(prev.Tickets))
+	for _, t := range prev.Tickets {
+		prevTickets[t.ID] = t
+	}
+	for _, t := range next.Tickets {
+		p, ok := prevTickets[t.ID]
+		if ok && ticketEqual(p, t) {
+			continue
+		}
+		td := &TicketDelta{TicketSnapshot: *t}
+		if !ok || p.Content != t.Content {
+			td.Content = &t.Content
+		}
+		d.Tickets = append(d.Tickets, td)
+	}
+	d.RemovedTickets = removedIDs(prev.Tickets, next.Tickets, func(t *TicketSnapshot) string { return t.ID })
+	d.TicketOrder = changedOrder(prev.Tickets, next.Tickets, d.RemovedTickets, func(t *TicketSnapshot) string { return t.ID })
+
+	changed := d.Name != nil || d.State != nil || d.Countdown != nil || d.CurrentTicketID != nil ||
+		d.ThemeState != nil || len(d.Users) > 0 || len(d.RemovedUsers) > 0 || d.UserOrder != nil ||
+		len(d.Tickets) > 0 || len(d.RemovedTickets) > 0 || d.TicketOrder != nil
+	return d, changed
+}
+
+// Apply returns a new snapshot with delta applied on top of snap. Snap is not
+// modified. The revision is left to the caller.
+func Apply(snap *RoomSnapshot, d *RoomDelta) *RoomSnapshot {
+	next := *snap
+	if d.Name != nil {
+		next.Name = *d.Name
+	}
+	if d.State != nil {
+		next.State = *d.State
+	}
+	if d.Countdown != nil {
+		next.Countdown = *d.Countdown
+	}
+	if d.CurrentTicketID != nil {
+		next.CurrentTicketID = *d.CurrentTicketID
+	}
+	if d.ThemeState != nil {
+		next.ThemeState = d.ThemeState
+	}
+
+	next.Users = upsert(snap.Users, d.Users, d.RemovedUsers, d.UserOrder,
+		func(u *User) string { return u.ID },
+		func(u *User) string { return u.ID },
+		func(_, u *User) *User { return u })
+	next.Tickets = upsert(snap.Tickets, d.Tickets, d.RemovedTickets, d.TicketOrder,
+		func(t *TicketSnapshot) string { return t.ID },
+		func(t *TicketDelta) string { return t.ID },
+		func(old *TicketSnapshot, t *TicketDelta) *TicketSnapshot {
+			merged := t.TicketSnapshot
+			switch {
+			case t.Content != nil:
+				merged.Content = *t.Content
+			case old != nil:
+				merged.Content = old.Content
+			}
+			return &merged
+		})
+	return &next
+}
+
+// removedIDs lists IDs present in prev but not in next.
+func removedIDs[T any](prev, next []T, id func(T) string) []string {
+	present := make(map[string]bool, len(next))
+	for _, v := range next {
+		present[id(v)] = true
+	}
+	var removed []string
+	for _, v := range prev {
+		if !present[id(v)] {
+			removed = append(removed, id(v))
+		}
+	}
+	return removed
+}
+
+// changedOrder returns the IDs of next in order, or nil when the order is what
+// Apply produces anyway: prev without removed items, new items appended.
+func changedOrder[T any](prev, next []T, removed []string, id func(T) string) []string {
+	implied := make([]string, 0, len(next))
+	known := make(map[string]bool, len(prev))
+	for _, v := range prev {
+		known[id(v)] = true
+		if !slices.Contains(removed, id(v)) {
+			implied = append(implied, id(v))
+		}
+	}
+	actual := make([]string, 0, len(next))
+	for _, v := range next {
+		actual = append(actual, id(v))
+		if !known[id(v)] {
+			implied = append(implied, id(v))
+		}
+	}
+	if slices.Equal(implied, actual) {
+		return nil
+	}
+	return actual
+}
+
+// upsert applies removals, replacements, additions and an optional order to
+// items, returning a new slice.
+func upsert[T, C any](items []T, changed []C, removed, order []string, id func(T) string, changedID func(C) string, merge func(old T, v C) T) []T {
+	byID := make(map[string]int, len(items))
+	out := make([]T, 0, len(items)+len(changed))
+	for _, v := range items {
+		if slices.Contains(removed, id(v)) {
+			continue
+		}
+		byID[id(v)] = len(out)
+		out = append(out, v)
+	}
+	var zero T
+	for _, v := range changed {
+		if i, ok := byID[changedID(v)]; ok {
+			out[i] = merge(out[i], v)
+			continue
+		}
+		byID[changedID(v)] = len(out)
+		out = append(out, merge(zero, v))
+	}
+	if order == nil {
+		return out
+	}
+	sorted := make([]T, 0, len(order))
+	for _, oid := range order {
+		if i, ok := byID[oid]; ok {
+			sorted = append(sorted, out[i])
+		}
+	}
+	return sorted
+}
+
+func userEqual(a, b *User) bool {
+	return a.Name == b.Name && a.AvatarID == b.AvatarID && a.IsAdmin == b.IsAdmin &&
+		a.Connected == b.Connected && a.Away == b.Away && a.Thinking == b.Thinking
+}
+
+func ticketEqual(a, b *TicketSnapshot) bool {
+	return a.Content == b.Content && a.Status == b.Status && slices.Equal(a.Votes, b.Votes) &&
+		a.ExternalKey == b.ExternalKey && a.ExternalURL == b.ExternalURL &&
+		a.SyncStatus == b.SyncStatus && a.SyncError == b.SyncError && a.SyncedValue == b.SyncedValue
+}
+
+func themeStateEqual(a, b *ThemeState) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Theme == b.Theme && bytes.Equal(a.Data, b.Data)
+}
@@ -0,0 +1,37 @@
+package model
+
+// Application error codes returned in RPC and REST API errors. A code never
+// changes its meaning; new errors get new codes. Codes below 400 are
+// Centrifuge protocol errors (e.g. 103 permission denied, 107 bad request).
+const (
+	// The referenced object does not exist.
+	CodeRoomNotFound   uint32 = 1000
+	CodeUserNotFound   uint32 = 1001
+	CodeTicketNotFound uint32 = 1002
+	CodeTreeNotFound   uint32 = 1003
+
+	// The admin secret does not match the room.
+	CodeInvalidAdminSecret uint32 = 1100
+
+	// The request is valid but not allowed in the current room state.
+	CodeNotVoting         uint32 = 1200
+	CodeNoCurrentTicket   uint32 = 1201
+	CodeInvalidVote       uint32 = 1202
+	CodeTreeAlreadyBurned uint32 = 1203
+	CodeNoCampfire        uint32 = 1204
+
+	// The external issue tracker failed.
+	CodeTrackerFailed uint32 = 1300
+
+	// The server is shutting down and accepts no new rooms or members.
+	CodeServerDraining uint32 = 1400
+	// The room stayed locked by other requests for too long; retry.
+	CodeRoomBusy uint32 = 1401
+)
+
+// Disconnect codes sent when the server closes a connection. Clients
+// reconnect after codes 4000-4499 and give up after codes 4500-4999.
+const (
+	// The server restarts; reconnect, to another instance or once it is back.
+	DisconnectRestarting uint32 = 4000
+)
@@ -41,6 +41,13 @@ type FeedFirePayload struct {
 	FromY  float64 `json:"fromY"`
 }
 
+// ServerRestartingPayload is embedded in the "server" RoomEvent with action
+// "restarting" that every room receives before the server shuts down.
+type ServerRestartingPayload struct {
+	DisconnectAt time.Time `json:"disconnectAt"` // when connections are closed
+	BackAt       time.Time `json:"backAt"`       // when the server is expected to accept connections again
+}
+
 // ThemeInteractRequest is the RPC request body for "theme_interact".
 type ThemeInteractRequest struct {
 	RoomID string          `json:"roomId"`
@@ -74,36 +81,62 @@ const (
 	TicketStatusSkipped  TicketStatus = "skipped"
 )
 
+// SyncStatus tracks writing a ticket's agreed estimate back to the issue tracker.
+type SyncStatus string
+
+const (
+	SyncStatusPending SyncStatus = "pending"
+	SyncStatusSynced  SyncStatus = "synced"
+	SyncStatusFailed  SyncStatus = "failed"
+)
+
 type Vote struct {
 	UserID string `json:"userId"`
 	Value  string `json:"value"`
 }
 
 type Ticket struct {
-	ID      string          `json:"id"`
-	Content string          `json:"content"`
-	Status  TicketStatus    `json:"status"`
-	Votes   map[string]Vote `json:"votes"`
+	ID          string          `json:"id"`
+	Content     string          `json:"content"`
+	Status      TicketStatus    `json:"status"`
+	Votes       map[string]Vote `json:"votes"`
+	ExternalKey string          `json:"externalKey,omitempty"` // issue key in the external tracker, e.g. "PROJ-12"
+	ExternalURL string          `json:"externalUrl,omitempty"`
+	SyncStatus  SyncStatus      `json:"syncStatus,omitempty"`
+	SyncError   string          `json:"syncError,omitempty"`
+	SyncedValue string          `json:"syncedValue,omitempty"` // estimate last written to the tracker
 }
 
 type User struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	AvatarID  string    `json:"avatarId"`
-	IsAdmin   bool      `json:"isAdmin"`
-	Connected bool      `json:"connected"`
-	Thinking  bool      `json:"thinking"`
-	JoinedAt  time.Time `json:"-"`
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	AvatarID     string    `json:"avatarId"`
+	IsAdmin      bool      `json:"isAdmin"`
+	Connected    bool      `json:"connected"`
+	Away         bool      `json:"away"` // connected but idle for longer than the idle timeout
+	Thinking     bool      `json:"thinking"`
+	JoinedAt     time.Time `json:"-"`
+	LastActiveAt time.Time `json:"-"`
 }
 
+// RoomEvent is a one-off interaction published on the room channel on its own.
+// It is not part of the room state.
 type RoomEvent struct {
-	Type    string          `json:"type"`             // "player_interaction" or "theme_interaction"
+	ID      string          `json:"id"`
+	At      time.Time       `json:"at"`
+	Type    string          `json:"type"`             // "player_interaction", "theme_interaction", "nudge" or "server"
 	Action  string          `json:"action"`           // "paper_throw", "feed_fire", etc.
 	FromID  string          `json:"fromId"`
 	ToID    string          `json:"toId"`
 	Payload json.RawMessage `json:"payload,omitempty"`
 }
 
+// WebhookConfig is a per-room outgoing webhook set by the room admin.
+type WebhookConfig struct {
+	URL    string
+	Secret string
+}
+
 type Room struct {
 	ID              string           `json:"id"`
 	Name            string           `json:"name"`
@@ -114,10 +147,12 @@ type Room struct {
 	Users           map[string]*User `json:"users"`
 	Tickets         []*Ticket        `json:"tickets"`
 	CurrentTicketID string           `json:"currentTicketId"`
-	PendingEvents   []RoomEvent      `json:"pendingEvents,omitempty"`
 	CreatedAt       time.Time        `json:"createdAt"`
 	LastActivityAt  time.Time        `json:"lastActivityAt"`
 	ThemeState      *ThemeState      `json:"themeState,omitempty"`
+	Webhook         *WebhookConfig   `json:"-"`
+	Revision        uint64           `json:"-"` // revision of the last publication on the room channel
+	Published       *RoomSnapshot    `json:"-"` // state last published on the room channel, nil before the first
 }
 
 // RPC request types
@@ -147,6 +182,17 @@ type JoinRoomResponse struct {
 	State  *RoomSnapshot `json:"state"`
 }
 
+type GetRoomRequest struct {
+	RoomID string `json:"roomId"`
+}
+
+// SubscribeRoomData is the optional data of a room channel subscription. A
+// known user ID re-registers the connection for that user, so a client that
+// reconnects is shown online again without calling join_room.
+type SubscribeRoomData struct {
+	UserID string `json:"userId"`
+}
+
 type SubmitVoteRequest struct {
 	RoomID string `json:"roomId"`
 	UserID string `json:"userId"`
@@ -185,6 +231,27 @@ type SetTicketRequest struct {
 	TicketID    string `json:"ticketId"`
 }
 
+type ImportTicketsRequest struct {
+	RoomID      string `json:"roomId"`
+	AdminSecret string `json:"adminSecret"`
+	Query       string `json:"query"`
+}
+
+type ImportTicketsResponse struct {
+	TicketIDs []string `json:"ticketIds"`
+}
+
+type SyncEstimatesResponse struct {
+	Queued int `json:"queued"`
+}
+
+type SetWebhookRequest struct {
+	RoomID      string `json:"roomId"`
+	AdminSecret string `json:"adminSecret"`
+	URL         string `json:"url"` // empty removes the webhook
+	Secret      string `json:"secret"`
+}
+
 type SetThinkingRequest struct {
 	RoomID   string `json:"roomId"`
 	UserID   string `json:"userId"`
@@ -198,10 +265,23 @@ type InteractPlayerRequest struct {
 	Action       string `json:"action"` // e.g. "paper_throw"
 }
 
+// NudgeRequest is the RPC request body for "nudge". Without TargetUserID every
+// connected user who has not voted on the current ticket is nudged.
+type NudgeRequest struct {
+	RoomID       string `json:"roomId"`
+	AdminSecret  string `json:"adminSecret"`
+	TargetUserID string `json:"targetUserId,omitempty"`
+}
+
+type NudgeResponse struct {
+	Nudged []string `json:"nudged"` // user IDs that received the nudge
+}
+
 // RoomSnapshot is the sanitized room state sent to clients.
 // When state is "voting", vote values are hidden.
 type RoomSnapshot struct {
 	ID              string            `json:"id"`
+	Revision        uint64            `json:"revision"`
 	Name            string            `json:"name"`
 	Scale           string            `json:"scale"`
 	State           RoomState         `json:"state"`
@@ -210,15 +290,63 @@ type RoomSnapshot struct {
 	Tickets         []*TicketSnapshot `json:"tickets"`
 	CurrentTicketID string            `json:"currentTicketId"`
 	TicketsEnabled  bool              `json:"ticketsEnabled"`
-	Events          []RoomEvent       `json:"events,omitempty"`
 	ThemeState      *ThemeState       `json:"themeState,omitempty"`
 }
 
 type TicketSnapshot struct {
-	ID      string       `json:"id"`
-	Content string       `json:"content"`
-	Status  TicketStatus `json:"status"`
-	Votes   []VoteInfo   `json:"votes"`
+	ID          string       `json:"id"`
+	Content     string       `json:"content"`
+	Status      TicketStatus `json:"status"`
+	Votes       []VoteInfo   `json:"votes"`
+	ExternalKey string       `json:"externalKey,omitempty"`
+	ExternalURL string       `json:"externalUrl,omitempty"`
+	SyncStatus  SyncStatus   `json:"syncStatus,omitempty"`
+	SyncError   string       `json:"syncError,omitempty"`
+	SyncedValue string       `json:"syncedValue,omitempty"`
+}
+
+// Room update types published on room channels.
+const (
+	UpdateSnapshot = "snapshot"
+	UpdateDelta    = "delta"
+	UpdateEvent    = "event"
+)
+
+// RoomUpdate is the payload of every publication on a room channel. Each state
+// publication increments the room revision by one: a "delta" update applies on
+// top of the state at Revision-1, a "snapshot" update replaces the state. An
+// "event" update carries a RoomEvent, leaves the state alone and repeats the
+// last published revision.
+type RoomUpdate struct {
+	Type     string        `json:"type"`
+	Revision uint64        `json:"revision"`
+	Snapshot *RoomSnapshot `json:"snapshot,omitempty"`
+	Delta    *RoomDelta    `json:"delta,omitempty"`
+	Event    *RoomEvent    `json:"event,omitempty"`
+}
+
+// RoomDelta lists what changed between two snapshots. Nil fields are
+// unchanged. Users and tickets are sent whole when they change, except that
+// ticket content is omitted unless it changed.
+type RoomDelta struct {
+	Name            *string           `json:"name,omitempty"`
+	State           *RoomState        `json:"state,omitempty"`
+	Countdown       *int              `json:"countdown,omitempty"`
+	CurrentTicketID *string           `json:"currentTicketId,omitempty"`
+	ThemeState      *ThemeState       `json:"themeState,omitempty"`
+	Users           []*User           `json:"users,omitempty"`        // added or changed
+	RemovedUsers    []string          `json:"removedUsers,omitempty"` // user IDs
+	UserOrder       []string          `json:"userOrder,omitempty"`    // full order, set when it is not implied
+	Tickets         []*TicketDelta    `json:"tickets,omitempty"`      // added or changed
+	RemovedTickets  []string          `json:"removedTickets,omitempty"`
+	TicketOrder     []string          `json:"ticketOrder,omitempty"`
+}
+
+// TicketDelta is a changed ticket in a RoomDelta. Content is nil when it did
+// not change; it shadows the embedded field in JSON.
+type TicketDelta struct {
+	TicketSnapshot
+	Content *string `json:"content,omitempty"`
 }
 
 // VoteInfo represents a vote in a snapshot.
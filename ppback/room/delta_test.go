@@ -0,0 +1,150 @@
+package room
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"pockerplan/ppback/model"
+)
+
+func deltaTestRoom() *model.Room {
+	r := newTestRoom()
+	now := time.Now()
+	AddUser(r, &model.User{ID: "u1", Name: "Alice", JoinedAt: now})
+	AddUser(r, &model.User{ID: "u2", Name: "Bob", JoinedAt: now.Add(time.Second)})
+	AddTicket(r, &model.Ticket{ID: "t1", Content: "# First", Status: model.TicketStatusPending, Votes: map[string]model.Vote{}})
+	AddTicket(r, &model.Ticket{ID: "t2", Content: "# Second", Status: model.TicketStatusPending, Votes: map[string]model.Vote{}})
+	return r
+}
+
+// roundTrip checks that applying the delta of prev→next to prev yields next,
+// also after a JSON round trip of the delta.
+func roundTrip(t *testing.T, prev, next *model.RoomSnapshot) *model.RoomDelta {
+	t.Helper()
+	d, changed := model.Diff(prev, next)
+	if !changed {
+		t.Fatal("expected a change")
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded model.RoomDelta
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	got := model.Apply(prev, &decoded)
+	want, _ := json.Marshal(next)
+	gotJSON, _ := json.Marshal(got)
+	if string(gotJSON) != string(want) {
+		t.Fatalf("apply mismatch:\n got %s\nwant %s", gotJSON, want)
+	}
+	return d
+}
+
+func TestDiffNoChange(t *testing.T) {
+	r := deltaTestRoom()
+	a, b := Snapshot(r), Snapshot(r)
+	if d, changed := model.Diff(a, b); changed {
+		t.Errorf("expected no change, got %+v", d)
+	}
+}
+
+func TestDiffName(t *testing.T) {
+	r := deltaTestRoom()
+	prev := Snapshot(r)
+	SetName(r, "Sprint 42")
+	d := roundTrip(t, prev, Snapshot(r))
+
+	if d.Name == nil || *d.Name != "Sprint 42" {
+		t.Errorf("expected name in delta, got %+v", d.Name)
+	}
+	if d.Users != nil || d.Tickets != nil || d.State != nil {
+		t.Errorf("expected only the name to change, got %+v", d)
+	}
+}
+
+func TestDiffVoteOmitsUnchangedContent(t *testing.T) {
+	r := deltaTestRoom()
+	SetCurrentTicket(r, "t1")
+	prev := Snapshot(r)
+	if err := SubmitVote(r, "u1", "5"); err != nil {
+		t.Fatal(err)
+	}
+	d := roundTrip(t, prev, Snapshot(r))
+
+	if len(d.Tickets) != 1 || d.Tickets[0].ID != "t1" {
+		t.Fatalf("expected only t1 in delta, got %+v", d.Tickets)
+	}
+	if d.Tickets[0].Content != nil {
+		t.Errorf("expected unchanged content to be omitted, got %q", *d.Tickets[0].Content)
+	}
+	if d.Tickets[0].Votes[0].Value != "" {
+		t.Error("vote value must stay hidden in deltas while voting")
+	}
+}
+
+func TestDiffUsersAddedAndRemoved(t *testing.T) {
+	r := deltaTestRoom()
+	prev := Snapshot(r)
+	delete(r.Users, "u1")
+	AddUser(r, &model.User{ID: "u3", Name: "Carol", JoinedAt: time.Now().Add(time.Minute)})
+	d := roundTrip(t, prev, Snapshot(r))
+
+	if !reflect.DeepEqual(d.RemovedUsers, []string{"u1"}) {
+		t.Errorf("expected u1 removed, got %v", d.RemovedUsers)
+	}
+	if len(d.Users) != 1 || d.Users[0].ID != "u3" {
+		t.Errorf("expected u3 added, got %+v", d.Users)
+	}
+	if d.UserOrder != nil {
+		t.Errorf("expected implied order, got %v", d.UserOrder)
+	}
+}
+
+func TestDiffKeepsEmptyContent(t *testing.T) {
+	r := deltaTestRoom()
+	prev := Snapshot(r)
+	if err := StartFreeVote(r, "free"); err != nil {
+		t.Fatal(err)
+	}
+	d := roundTrip(t, prev, Snapshot(r))
+
+	var free *model.TicketDelta
+	for _, td := range d.Tickets {
+		if td.ID == "free" {
+			free = td
+		}
+	}
+	if free == nil || free.Content == nil || *free.Content != "" {
+		t.Errorf("expected the new ticket with explicit empty content, got %+v", free)
+	}
+}
+
+func TestDiffTicketOrder(t *testing.T) {
+	prev := Snapshot(deltaTestRoom())
+	next := *prev
+	next.Tickets = []*model.TicketSnapshot{prev.Tickets[1], prev.Tickets[0]}
+	d := roundTrip(t, prev, &next)
+
+	if !reflect.DeepEqual(d.TicketOrder, []string{"t2", "t1"}) {
+		t.Errorf("expected explicit ticket order, got %v", d.TicketOrder)
+	}
+}
+
+func TestApplyDoesNotModifyInput(t *testing.T) {
+	r := deltaTestRoom()
+	prev := Snapshot(r)
+	before, _ := json.Marshal(prev)
+	SetName(r, "Renamed")
+	RemoveUser(r, "u2")
+	d, _ := model.Diff(prev, Snapshot(r))
+	model.Apply(prev, d)
+
+	after, _ := json.Marshal(prev)
+	if string(before) != string(after) {
+		t.Errorf("apply modified its input:\n%s\n%s", before, after)
+	}
+}
@@ -1,21 +1,36 @@
 package room
 
 import (
+	"context"
 	"pockerplan/ppback/campfire"
 	"pockerplan/ppback/model"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("pockerplan/ppback/room")
+
 const defaultTTL = 24 * time.Hour
 
-// Manager provides thread-safe room CRUD and TTL-based cleanup.
+// Manager provides thread-safe room CRUD and TTL-based cleanup on top of a
+// Store. Errors of the store other than ErrRoomNotFound are returned by the
+// methods that return an error; the others treat them as no rooms.
 type Manager struct {
-	mu    sync.RWMutex
-	rooms map[string]*model.Room
-	ttl   time.Duration
+	store   Store
+	mu      sync.RWMutex // guards ttl
+	ttl     time.Duration
+	removed atomic.Uint64 // rooms removed by Cleanup since start
+
+	cleanupEvery atomic.Int64 // StartCleanup interval; 0 until started
+	cleanupBeat  atomic.Int64 // unix nanoseconds of the last cleanup tick
 }
 
 // NewManager creates a new room manager with the default TTL.
@@ -25,10 +40,12 @@ func NewManager() *Manager {
 
 // NewManagerWithTTL creates a new room manager with the given room TTL.
 func NewManagerWithTTL(ttl time.Duration) *Manager {
-	return &Manager{
-		rooms: make(map[string]*model.Room),
-		ttl:   ttl,
-	}
+	return NewManagerWithStore(newMemoryStore(), ttl)
+}
+
+// NewManagerWithStore creates a room manager keeping its rooms in store.
+func NewManagerWithStore(store Store, ttl time.Duration) *Manager {
+	return &Manager{store: store, ttl: ttl}
 }
 
 // Create creates a new room with the given scale and returns it along with the admin secret.
@@ -48,90 +65,203 @@ func (m *Manager) Create(scaleID string, countdown int) (*model.Room, error) {
 
 	campfire.Init(r)
 
-	m.mu.Lock()
-	m.rooms[r.ID] = r
-	m.mu.Unlock()
-
+	if err := m.store.Add(context.Background(), r); err != nil {
+		return nil, err
+	}
 	return r, nil
 }
 
-// Get returns the room with the given ID.
+// Get returns the room with the given ID. The room must only be read; use
+// WithRoom to change it.
 func (m *Manager) Get(id string) (*model.Room, error) {
-	m.mu.RLock()
-	r, ok := m.rooms[id]
-	m.mu.RUnlock()
-	if !ok {
-		return nil, ErrRoomNotFound
-	}
-	return r, nil
+	return m.store.Get(context.Background(), id)
 }
 
-// WithRoom executes fn while holding the write lock. This ensures all
-// mutations to a room are serialized.
+// WithRoom executes fn while holding the room's lock and saves the changes.
+// This ensures all mutations to a room are serialized.
 func (m *Manager) WithRoom(id string, fn func(r *model.Room) error) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	r, ok := m.rooms[id]
-	if !ok {
-		return ErrRoomNotFound
+	return m.WithRoomContext(context.Background(), id, fn)
+}
+
+// WithRoomContext is WithRoom for a traced operation. When ctx carries a span,
+// the time spent waiting for the lock and holding it is recorded as child
+// spans.
+func (m *Manager) WithRoomContext(ctx context.Context, id string, fn func(r *model.Room) error) error {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return m.store.Update(ctx, id, fn)
+	}
+
+	attrs := trace.WithAttributes(attribute.String("room.id", id))
+	_, wait := tracer.Start(ctx, "room.lock_wait", attrs)
+	var hold trace.Span
+	err := m.store.Update(ctx, id, func(r *model.Room) error {
+		wait.End()
+		_, hold = tracer.Start(ctx, "room.lock_hold", attrs)
+		return fn(r)
+	})
+	span := hold
+	if span == nil {
+		// The room was not found or the lock not taken.
+		span = wait
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 	}
-	return fn(r)
+	span.End()
+	return err
 }
 
-// NormalizeCampfireRooms runs campfire decay/respawn on all rooms while
-// holding the write lock. Returns the IDs of rooms whose state changed.
-func (m *Manager) NormalizeCampfireRooms() []string {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// updateAll runs fn on every room, each under its own lock, and returns the
+// IDs of the rooms for which fn reported a change.
+func (m *Manager) updateAll(fn func(r *model.Room) bool) []string {
 	var changed []string
-	for id, r := range m.rooms {
-		if campfire.Normalize(r) {
+	for _, id := range m.ids() {
+		var ok bool
+		err := m.store.Update(context.Background(), id, func(r *model.Room) error {
+			ok = fn(r)
+			return nil
+		})
+		if err == nil && ok {
 			changed = append(changed, id)
 		}
 	}
 	return changed
 }
 
-// Delete removes a room.
-func (m *Manager) Delete(id string) {
+// ids returns the IDs of all rooms.
+func (m *Manager) ids() []string {
+	var ids []string
+	_ = m.store.Each(context.Background(), func(r *model.Room) {
+		ids = append(ids, r.ID)
+	})
+	return ids
+}
+
+// NormalizeCampfireRooms runs campfire decay/respawn on all rooms. Returns
+// the IDs of rooms whose state changed.
+func (m *Manager) NormalizeCampfireRooms() []string {
+	return m.updateAll(campfire.Normalize)
+}
+
+// MarkAwayUsers marks users idle for longer than idle as away in all rooms.
+// Returns the IDs of rooms whose state changed.
+func (m *Manager) MarkAwayUsers(idle time.Duration) []string {
+	now := time.Now()
+	return m.updateAll(func(r *model.Room) bool {
+		return MarkAway(r, idle, now)
+	})
+}
+
+// SetTTL changes how long inactive rooms are kept, starting with the next
+// cleanup.
+func (m *Manager) SetTTL(ttl time.Duration) {
 	m.mu.Lock()
-	delete(m.rooms, id)
+	m.ttl = ttl
 	m.mu.Unlock()
 }
 
+// Delete removes a room.
+func (m *Manager) Delete(id string) {
+	_ = m.store.Delete(context.Background(), id)
+}
+
 // Cleanup removes rooms that have been inactive for longer than the TTL.
 // Returns the number of rooms removed.
 func (m *Manager) Cleanup() int {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
+	m.mu.RLock()
 	cutoff := time.Now().Add(-m.ttl)
-	removed := 0
-	for id, r := range m.rooms {
+	m.mu.RUnlock()
+
+	var expired []string
+	_ = m.store.Each(context.Background(), func(r *model.Room) {
 		if r.LastActivityAt.Before(cutoff) {
-			delete(m.rooms, id)
+			expired = append(expired, r.ID)
+		}
+	})
+	removed := 0
+	for _, id := range expired {
+		if m.store.Delete(context.Background(), id) == nil {
 			removed++
 		}
 	}
+	m.removed.Add(uint64(removed))
 	return removed
 }
 
+// Removed returns how many rooms Cleanup has removed since the manager was
+// created.
+func (m *Manager) Removed() uint64 {
+	return m.removed.Load()
+}
+
+// Summary is a short description of a room for operators.
+type Summary struct {
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Scale          string          `json:"scale"`
+	State          model.RoomState `json:"state"`
+	Users          int             `json:"users"`
+	Tickets        int             `json:"tickets"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	LastActivityAt time.Time       `json:"lastActivityAt"`
+}
+
+// List returns summaries of all rooms, oldest first.
+func (m *Manager) List() []Summary {
+	list := []Summary{}
+	_ = m.store.Each(context.Background(), func(r *model.Room) {
+		list = append(list, Summary{
+			ID:             r.ID,
+			Name:           r.Name,
+			Scale:          r.Scale,
+			State:          r.State,
+			Users:          len(r.Users),
+			Tickets:        len(r.Tickets),
+			CreatedAt:      r.CreatedAt,
+			LastActivityAt: r.LastActivityAt,
+		})
+	})
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
 // Count returns the number of active rooms.
 func (m *Manager) Count() int {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return len(m.rooms)
+	n, _ := m.store.Count(context.Background())
+	return n
+}
+
+// Shared reports whether the rooms are kept in a store that other server
+// processes use as well.
+func (m *Manager) Shared() bool {
+	return m.store.Shared()
+}
+
+// Ping checks that the room store can be reached.
+func (m *Manager) Ping(ctx context.Context) error {
+	return m.store.Ping(ctx)
+}
+
+// Close releases the room store.
+func (m *Manager) Close() {
+	m.store.Close()
 }
 
 // StartCleanup runs periodic cleanup in a goroutine.
 // It stops when the done channel is closed.
 func (m *Manager) StartCleanup(interval time.Duration, done <-chan struct{}) {
+	m.cleanupBeat.Store(time.Now().UnixNano())
+	m.cleanupEvery.Store(int64(interval))
 	go func() {
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 		for {
 			select {
 			case <-ticker.C:
+				m.cleanupBeat.Store(time.Now().UnixNano())
 				m.Cleanup()
 			case <-done:
 				return
@@ -139,3 +269,9 @@ func (m *Manager) StartCleanup(interval time.Duration, done <-chan struct{}) {
 		}
 	}()
 }
+
+// CleanupHeartbeat returns when the cleanup loop last ticked and its
+// interval, or a zero interval when StartCleanup has not been called.
+func (m *Manager) CleanupHeartbeat() (last time.Time, interval time.Duration) {
+	return time.Unix(0, m.cleanupBeat.Load()), time.Duration(m.cleanupEvery.Load())
+}
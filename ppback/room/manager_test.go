@@ -110,6 +110,23 @@ func TestManagerCleanup(t *testing.T) {
 	if m.Count() != 1 {
 		t.Errorf("expected 1 room remaining, got %d", m.Count())
 	}
+	if m.Removed() != 1 {
+		t.Errorf("expected removal count 1, got %d", m.Removed())
+	}
+}
+
+func TestManagerSetTTL(t *testing.T) {
+	m := NewManager()
+	m.Create("fibonacci", 3)
+	time.Sleep(10 * time.Millisecond)
+
+	if removed := m.Cleanup(); removed != 0 {
+		t.Fatalf("expected no room removed with default TTL, got %d", removed)
+	}
+	m.SetTTL(5 * time.Millisecond)
+	if removed := m.Cleanup(); removed != 1 {
+		t.Errorf("expected 1 room removed after SetTTL, got %d", removed)
+	}
 }
 
 func TestManagerCleanupKeepsActive(t *testing.T) {
@@ -137,7 +154,11 @@ func TestManagerStartCleanup(t *testing.T) {
 	m.ttl = 10 * time.Millisecond
 
 	_, _ = m.Create("fibonacci", 3)
+	if _, every := m.CleanupHeartbeat(); every != 0 {
+		t.Errorf("expected no heartbeat before start, got interval %v", every)
+	}
 
+	start := time.Now()
 	done := make(chan struct{})
 	m.StartCleanup(20*time.Millisecond, done)
 
@@ -148,6 +169,10 @@ func TestManagerStartCleanup(t *testing.T) {
 	if m.Count() != 0 {
 		t.Errorf("expected 0 rooms after cleanup, got %d", m.Count())
 	}
+	last, every := m.CleanupHeartbeat()
+	if every != 20*time.Millisecond || !last.After(start) {
+		t.Errorf("unexpected heartbeat %v every %v", last, every)
+	}
 }
 
 func TestManagerConcurrentCreateGet(t *testing.T) {
@@ -284,3 +309,30 @@ func TestManagerUniqueIDs(t *testing.T) {
 		ids[r.ID] = true
 	}
 }
+
+func TestManagerList(t *testing.T) {
+	m := NewManager()
+	r1, _ := m.Create("fibonacci", 3)
+	r2, _ := m.Create("linear", 3)
+
+	m.WithRoom(r1.ID, func(r *model.Room) error {
+		r.CreatedAt = r.CreatedAt.Add(-time.Minute)
+		AddUser(r, &model.User{ID: "u1", Name: "Alice", AvatarID: "cat"})
+		AddTicket(r, &model.Ticket{ID: "t1", Content: "First"})
+		return nil
+	})
+
+	list := m.List()
+	if len(list) != 2 {
+		t.Fatalf("expected 2 rooms, got %d", len(list))
+	}
+	if list[0].ID != r1.ID || list[1].ID != r2.ID {
+		t.Errorf("expected rooms ordered by creation, got %s, %s", list[0].ID, list[1].ID)
+	}
+	if list[0].Users != 1 || list[0].Tickets != 1 {
+		t.Errorf("expected 1 user and 1 ticket, got %d users, %d tickets", list[0].Users, list[0].Tickets)
+	}
+	if list[1].Scale != "linear" {
+		t.Errorf("expected scale linear, got %s", list[1].Scale)
+	}
+}
@@ -0,0 +1,276 @@
+package room
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"pockerplan/ppback/model"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/rueidis"
+)
+
+const (
+	// redisLockTTL bounds how long a room stays locked by a process that
+	// died during an update.
+	redisLockTTL = 10 * time.Second
+	// redisLockWait is how long an update waits for the room lock when its
+	// context has no deadline.
+	redisLockWait = 5 * time.Second
+)
+
+// ErrLockTimeout is returned when a room stays locked by another update for
+// too long.
+var ErrLockTimeout = errors.New("room is locked")
+
+var (
+	// unlockScript deletes a lock only if it is still ours.
+	unlockScript = rueidis.NewLuaScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	// saveScript writes a room only while we hold its lock, so that an
+	// update that outlived its lock cannot overwrite a newer one, and only
+	// while the room exists, so that an update racing Delete cannot bring
+	// the room back outside the index.
+	saveScript = rueidis.NewLuaScript(`
+if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if redis.call("EXISTS", KEYS[2]) == 0 then
+	return -1
+end
+redis.call("SET", KEYS[2], ARGV[2])
+return 1`)
+)
+
+// RedisStore keeps rooms in Redis, so that several server processes share
+// them. Each room is a key holding the gob-encoded room; an update takes a
+// per-room lock key, reads the room, runs and writes the room back if it
+// changed.
+type RedisStore struct {
+	client rueidis.Client
+	prefix string
+}
+
+// NewRedisStore connects to Redis at addr, given as host:port or as a
+// redis://, rediss:// or unix:// URL. All keys start with prefix.
+func NewRedisStore(addr, prefix string) (*RedisStore, error) {
+	opt := rueidis.ClientOption{InitAddress: []string{addr}}
+	if strings.Contains(addr, "://") {
+		var err error
+		if opt, err = rueidis.ParseURL(addr); err != nil {
+			return nil, fmt.Errorf("redis address: %w", err)
+		}
+	}
+	opt.DisableCache = true
+	client, err := rueidis.NewClient(opt)
+	if err != nil {
+		return nil, fmt.Errorf("connect to redis: %w", err)
+	}
+	return &RedisStore{client: client, prefix: prefix}, nil
+}
+
+// roomKey is the key of a room. The braces keep a room and its lock in the
+// same Redis Cluster slot, as the scripts need.
+func (s *RedisStore) roomKey(id string) string { return s.prefix + ":room:{" + id + "}" }
+func (s *RedisStore) lockKey(id string) string { return s.roomKey(id) + ":lock" }
+
+// indexKey is a set holding the IDs of all rooms.
+func (s *RedisStore) indexKey() string { return s.prefix + ":rooms" }
+
+func (s *RedisStore) Add(ctx context.Context, r *model.Room) error {
+	data, err := encodeRoom(r)
+	if err != nil {
+		return err
+	}
+	for _, resp := range s.client.DoMulti(ctx,
+		s.client.B().Set().Key(s.roomKey(r.ID)).Value(rueidis.BinaryString(data)).Build(),
+		s.client.B().Sadd().Key(s.indexKey()).Member(r.ID).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			return fmt.Errorf("add room: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (*model.Room, error) {
+	data, err := s.client.Do(ctx, s.client.B().Get().Key(s.roomKey(id)).Build()).AsBytes()
+	if rueidis.IsRedisNil(err) {
+		return nil, ErrRoomNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get room: %w", err)
+	}
+	return decodeRoom(data)
+}
+
+func (s *RedisStore) Update(ctx context.Context, id string, fn func(r *model.Room) error) error {
+	token, err := s.lock(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer unlockScript.Exec(context.WithoutCancel(ctx), s.client, []string{s.lockKey(id)}, []string{token})
+
+	data, err := s.client.Do(ctx, s.client.B().Get().Key(s.roomKey(id)).Build()).AsBytes()
+	if rueidis.IsRedisNil(err) {
+		return ErrRoomNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("get room: %w", err)
+	}
+	r, err := decodeRoom(data)
+	if err != nil {
+		return err
+	}
+	orig, err := decodeRoom(data)
+	if err != nil {
+		return err
+	}
+
+	fnErr := fn(r)
+	if reflect.DeepEqual(orig, r) {
+		return fnErr
+	}
+	if data, err = encodeRoom(r); err != nil {
+		return err
+	}
+	saved, err := saveScript.Exec(ctx, s.client,
+		[]string{s.lockKey(id), s.roomKey(id)},
+		[]string{token, rueidis.BinaryString(data)},
+	).AsInt64()
+	if err != nil {
+		return fmt.Errorf("save room: %w", err)
+	}
+	switch saved {
+	case 0:
+		return fmt.Errorf("save room: lock expired after %v", redisLockTTL)
+	case -1:
+		return ErrRoomNotFound
+	}
+	return fnErr
+}
+
+// lock takes the lock of a room and returns its token.
+func (s *RedisStore) lock(ctx context.Context, id string) (string, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, redisLockWait)
+		defer cancel()
+	}
+	token := uuid.New().String()
+	backoff := time.Millisecond
+	for {
+		// A command is recycled once sent, so each attempt builds its own.
+		cmd := s.client.B().Set().Key(s.lockKey(id)).Value(token).Nx().Px(redisLockTTL).Build()
+		err := s.client.Do(ctx, cmd).Error()
+		if err == nil {
+			return token, nil
+		}
+		if !rueidis.IsRedisNil(err) {
+			if ctx.Err() != nil {
+				return "", ErrLockTimeout
+			}
+			return "", fmt.Errorf("lock room: %w", err)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return "", ErrLockTimeout
+		}
+		backoff = min(2*backoff, 50*time.Millisecond)
+	}
+}
+
+func (s *RedisStore) Each(ctx context.Context, fn func(r *model.Room)) error {
+	ids, err := s.client.Do(ctx, s.client.B().Smembers().Key(s.indexKey()).Build()).AsStrSlice()
+	if err != nil {
+		return fmt.Errorf("list rooms: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	cmds := make(rueidis.Commands, 0, len(ids))
+	for _, id := range ids {
+		cmds = append(cmds, s.client.B().Get().Key(s.roomKey(id)).Build())
+	}
+	for _, resp := range s.client.DoMulti(ctx, cmds...) {
+		data, err := resp.AsBytes()
+		if rueidis.IsRedisNil(err) {
+			continue // deleted since the IDs were read
+		}
+		if err != nil {
+			return fmt.Errorf("get room: %w", err)
+		}
+		r, err := decodeRoom(data)
+		if err != nil {
+			return err
+		}
+		fn(r)
+	}
+	return nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	for _, resp := range s.client.DoMulti(ctx,
+		s.client.B().Del().Key(s.roomKey(id)).Build(),
+		s.client.B().Srem().Key(s.indexKey()).Member(id).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			return fmt.Errorf("delete room: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *RedisStore) Count(ctx context.Context) (int, error) {
+	n, err := s.client.Do(ctx, s.client.B().Scard().Key(s.indexKey()).Build()).AsInt64()
+	if err != nil {
+		return 0, fmt.Errorf("count rooms: %w", err)
+	}
+	return int(n), nil
+}
+
+func (s *RedisStore) Shared() bool { return true }
+
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Do(ctx, s.client.B().Ping().Build()).Error()
+}
+
+func (s *RedisStore) Close() {
+	s.client.Close()
+}
+
+func encodeRoom(r *model.Room) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(r); err != nil {
+		return nil, fmt.Errorf("encode room: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeRoom decodes a room written by encodeRoom. Gob leaves out empty maps,
+// so they are recreated for code that adds to them.
+func decodeRoom(data []byte) (*model.Room, error) {
+	var r model.Room
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&r); err != nil {
+		return nil, fmt.Errorf("decode room: %w", err)
+	}
+	if r.Users == nil {
+		r.Users = make(map[string]*model.User)
+	}
+	for _, t := range r.Tickets {
+		if t.Votes == nil {
+			t.Votes = make(map[string]model.Vote)
+		}
+	}
+	return &r, nil
+}
@@ -0,0 +1,211 @@
+package room
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"pockerplan/ppback/model"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newRedisManager(t *testing.T, mr *miniredis.Miniredis) *Manager {
+	t.Helper()
+	store, err := NewRedisStore(mr.Addr(), "test")
+	if err != nil {
+		t.Fatalf("new redis store: %v", err)
+	}
+	m := NewManagerWithStore(store, time.Hour)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func TestRedisStoreRoundTrip(t *testing.T) {
+	m := newRedisManager(t, miniredis.RunT(t))
+	r, err := m.Create("fibonacci", 3)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	err = m.WithRoom(r.ID, func(r *model.Room) error {
+		AddUser(r, &model.User{ID: "u1", Name: "Alice", AvatarID: "cat"})
+		return StartFreeVote(r, "t1")
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := m.WithRoom(r.ID, func(r *model.Room) error {
+		return SubmitVote(r, "u1", "5")
+	}); err != nil {
+		t.Fatalf("vote: %v", err)
+	}
+
+	got, err := m.Get(r.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.AdminSecret != r.AdminSecret || got.Users["u1"] == nil || len(got.Tickets) != 1 {
+		t.Fatalf("room not saved: %+v", got)
+	}
+	if v := got.Tickets[0].Votes["u1"]; v.Value != "5" {
+		t.Errorf("expected vote 5, got %+v", v)
+	}
+	if got.ThemeState == nil {
+		t.Error("expected theme state to be kept")
+	}
+	if m.Count() != 1 || !m.Shared() {
+		t.Errorf("expected 1 shared room, got %d shared=%v", m.Count(), m.Shared())
+	}
+	if err := m.Ping(context.Background()); err != nil {
+		t.Errorf("ping: %v", err)
+	}
+
+	m.Delete(r.ID)
+	if _, err := m.Get(r.ID); err != ErrRoomNotFound {
+		t.Errorf("expected ErrRoomNotFound after delete, got %v", err)
+	}
+	if m.Count() != 0 {
+		t.Errorf("expected 0 rooms, got %d", m.Count())
+	}
+}
+
+func TestRedisStoreNotFound(t *testing.T) {
+	m := newRedisManager(t, miniredis.RunT(t))
+	if _, err := m.Get("nonexistent"); err != ErrRoomNotFound {
+		t.Errorf("Get: expected ErrRoomNotFound, got %v", err)
+	}
+	err := m.WithRoom("nonexistent", func(*model.Room) error { return nil })
+	if err != ErrRoomNotFound {
+		t.Errorf("WithRoom: expected ErrRoomNotFound, got %v", err)
+	}
+}
+
+func TestRedisStoreSavesOnError(t *testing.T) {
+	m := newRedisManager(t, miniredis.RunT(t))
+	r, _ := m.Create("fibonacci", 3)
+	errStop := errors.New("stop")
+
+	err := m.WithRoom(r.ID, func(r *model.Room) error {
+		r.Name = "Sprint 12"
+		return errStop
+	})
+	if err != errStop {
+		t.Fatalf("expected errStop, got %v", err)
+	}
+	got, _ := m.Get(r.ID)
+	if got.Name != "Sprint 12" {
+		t.Errorf("expected change to be saved, got name %q", got.Name)
+	}
+}
+
+func TestRedisStoreSharedBetweenManagers(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := newRedisManager(t, mr)
+	b := newRedisManager(t, mr)
+
+	r, _ := a.Create("fibonacci", 3)
+	if err := b.WithRoom(r.ID, func(r *model.Room) error {
+		AddUser(r, &model.User{ID: "u1", Name: "Bob", AvatarID: "dog"})
+		return nil
+	}); err != nil {
+		t.Fatalf("update through b: %v", err)
+	}
+	got, err := a.Get(r.ID)
+	if err != nil {
+		t.Fatalf("get through a: %v", err)
+	}
+	if got.Users["u1"] == nil {
+		t.Error("expected a to see the user added through b")
+	}
+	if list := b.List(); len(list) != 1 || list[0].ID != r.ID || list[0].Users != 1 {
+		t.Errorf("unexpected list %+v", list)
+	}
+}
+
+func TestRedisStoreConcurrentUpdates(t *testing.T) {
+	mr := miniredis.RunT(t)
+	managers := []*Manager{newRedisManager(t, mr), newRedisManager(t, mr)}
+	r, _ := managers[0].Create("fibonacci", 3)
+
+	const perManager = 20
+	var wg sync.WaitGroup
+	for i, m := range managers {
+		for j := range perManager {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				id := fmt.Sprintf("u%d-%d", i, j)
+				err := m.WithRoom(r.ID, func(r *model.Room) error {
+					AddUser(r, &model.User{ID: id, Name: id, AvatarID: "cat"})
+					return nil
+				})
+				if err != nil {
+					t.Errorf("update %s: %v", id, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	got, _ := managers[1].Get(r.ID)
+	if len(got.Users) != len(managers)*perManager {
+		t.Errorf("expected %d users, got %d", len(managers)*perManager, len(got.Users))
+	}
+}
+
+func TestRedisStoreCleanup(t *testing.T) {
+	m := newRedisManager(t, miniredis.RunT(t))
+	old, _ := m.Create("fibonacci", 3)
+	fresh, _ := m.Create("fibonacci", 3)
+	_ = m.WithRoom(old.ID, func(r *model.Room) error {
+		r.LastActivityAt = time.Now().Add(-2 * time.Hour)
+		return nil
+	})
+
+	if removed := m.Cleanup(); removed != 1 {
+		t.Fatalf("expected 1 room removed, got %d", removed)
+	}
+	if _, err := m.Get(old.ID); err != ErrRoomNotFound {
+		t.Errorf("expected old room removed, got %v", err)
+	}
+	if _, err := m.Get(fresh.ID); err != nil {
+		t.Errorf("expected fresh room kept, got %v", err)
+	}
+}
+
+func TestRedisStorePingFails(t *testing.T) {
+	mr := miniredis.RunT(t)
+	m := newRedisManager(t, mr)
+	mr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Ping(ctx); err == nil {
+		t.Error("expected ping to fail with Redis stopped")
+	}
+}
+
+func TestRedisStoreUpdateAfterDelete(t *testing.T) {
+	mr := miniredis.RunT(t)
+	m := newRedisManager(t, mr)
+	r, _ := m.Create("fibonacci", 3)
+
+	// The room is deleted while an update holds its lock.
+	err := m.WithRoom(r.ID, func(r *model.Room) error {
+		m.Delete(r.ID)
+		r.Name = "Sprint 12"
+		return nil
+	})
+	if err != ErrRoomNotFound {
+		t.Errorf("expected ErrRoomNotFound, got %v", err)
+	}
+	if mr.Exists("test:room:{" + r.ID + "}") {
+		t.Error("expected the deleted room not to be written back")
+	}
+	if m.Count() != 0 {
+		t.Errorf("expected 0 rooms, got %d", m.Count())
+	}
+}
@@ -28,6 +28,8 @@ func SetName(r *model.Room, name string) {
 // it updates their info and marks them connected.
 func AddUser(r *model.Room, u *model.User) {
 	u.Connected = true
+	u.Away = false
+	u.LastActiveAt = time.Now()
 	r.Users[u.ID] = u
 	touch(r)
 }
@@ -40,6 +42,51 @@ func RemoveUser(r *model.Room, userID string) {
 	}
 }
 
+// ReconnectUser marks an existing user as connected and reports whether they
+// were offline.
+func ReconnectUser(r *model.Room, userID string) (bool, error) {
+	u, ok := r.Users[userID]
+	if !ok {
+		return false, ErrUserNotFound
+	}
+	if u.Connected {
+		return false, nil
+	}
+	u.Connected = true
+	u.Away = false
+	u.LastActiveAt = time.Now()
+	touch(r)
+	return true, nil
+}
+
+// MarkActive records activity of a user and reports whether they were away.
+func MarkActive(r *model.Room, userID string, now time.Time) (bool, error) {
+	u, ok := r.Users[userID]
+	if !ok {
+		return false, ErrUserNotFound
+	}
+	u.LastActiveAt = now
+	if !u.Away {
+		return false, nil
+	}
+	u.Away = false
+	return true, nil
+}
+
+// MarkAway marks connected users with no activity since now-idle as away and
+// reports whether any user changed.
+func MarkAway(r *model.Room, idle time.Duration, now time.Time) bool {
+	cutoff := now.Add(-idle)
+	changed := false
+	for _, u := range r.Users {
+		if u.Connected && !u.Away && u.LastActiveAt.Before(cutoff) {
+			u.Away = true
+			changed = true
+		}
+	}
+	return changed
+}
+
 // SubmitVote records a vote for the current ticket.
 func SubmitVote(r *model.Room, userID, value string) error {
 	if r.State != model.RoomStateVoting && r.State != model.RoomStateCountingDown {
@@ -63,6 +110,26 @@ func SubmitVote(r *model.Room, userID, value string) error {
 	return nil
 }
 
+// PendingVoters returns the IDs of connected users who have not voted on the
+// current ticket, sorted.
+func PendingVoters(r *model.Room) ([]string, error) {
+	if r.State != model.RoomStateVoting && r.State != model.RoomStateCountingDown {
+		return nil, ErrNotVoting
+	}
+	ticket := findTicket(r, r.CurrentTicketID)
+	if ticket == nil {
+		return nil, ErrNoCurrentTicket
+	}
+	var ids []string
+	for id, u := range r.Users {
+		if _, voted := ticket.Votes[id]; u.Connected && !voted {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
 // RemoveVote removes the current user's vote from the current ticket.
 func RemoveVote(r *model.Room, userID string) error {
 	if r.State != model.RoomStateVoting && r.State != model.RoomStateCountingDown {
@@ -312,6 +379,7 @@ func Snapshot(r *model.Room) *model.RoomSnapshot {
 			AvatarID:  u.AvatarID,
 			IsAdmin:   u.IsAdmin,
 			Connected: u.Connected,
+			Away:      u.Away,
 			Thinking:  u.Thinking,
 			JoinedAt:  u.JoinedAt,
 		})
@@ -323,10 +391,15 @@ func Snapshot(r *model.Room) *model.RoomSnapshot {
 	tickets := make([]*model.TicketSnapshot, 0, len(r.Tickets))
 	for _, t := range r.Tickets {
 		ts := &model.TicketSnapshot{
-			ID:      t.ID,
-			Content: t.Content,
-			Status:  t.Status,
-			Votes:   make([]model.VoteInfo, 0, len(t.Votes)),
+			ID:          t.ID,
+			Content:     t.Content,
+			Status:      t.Status,
+			Votes:       make([]model.VoteInfo, 0, len(t.Votes)),
+			ExternalKey: t.ExternalKey,
+			ExternalURL: t.ExternalURL,
+			SyncStatus:  t.SyncStatus,
+			SyncError:   t.SyncError,
+			SyncedValue: t.SyncedValue,
 		}
 		for _, v := range t.Votes {
 			vi := model.VoteInfo{UserID: v.UserID}
@@ -341,8 +414,13 @@ func Snapshot(r *model.Room) *model.RoomSnapshot {
 		tickets = append(tickets, ts)
 	}
 
-	events := r.PendingEvents
-	r.PendingEvents = nil
+	// Theme data is replaced, never modified in place, so a shallow copy keeps
+	// the snapshot unaffected by later changes.
+	var theme *model.ThemeState
+	if r.ThemeState != nil {
+		ts := *r.ThemeState
+		theme = &ts
+	}
 
 	return &model.RoomSnapshot{
 		ID:              r.ID,
@@ -353,11 +431,15 @@ func Snapshot(r *model.Room) *model.RoomSnapshot {
 		Users:           users,
 		Tickets:         tickets,
 		CurrentTicketID: r.CurrentTicketID,
-		Events:          events,
-		ThemeState:      r.ThemeState,
+		ThemeState:      theme,
 	}
 }
 
+// FindTicket returns the ticket with the given ID, or nil.
+func FindTicket(r *model.Room, id string) *model.Ticket {
+	return findTicket(r, id)
+}
+
 func findTicket(r *model.Room, id string) *model.Ticket {
 	for _, t := range r.Tickets {
 		if t.ID == id {
@@ -367,6 +449,70 @@ func findTicket(r *model.Room, id string) *model.Ticket {
 	return nil
 }
 
+// FinalEstimate returns the agreed estimate for a revealed ticket: the most
+// common vote, ignoring "?". Ties go to the larger value on the room's scale.
+// Returns "" when the ticket is not revealed or has no usable votes, and an
+// error when the room's scale no longer exists, e.g. a custom scale removed
+// from the configuration.
+func FinalEstimate(r *model.Room, t *model.Ticket) (string, error) {
+	if t.Status != model.TicketStatusRevealed {
+		return "", nil
+	}
+	s, err := scale.Get(r.Scale)
+	if err != nil {
+		return "", err
+	}
+	order := make(map[string]int, len(s.Values))
+	for i, v := range s.Values {
+		order[v] = i
+	}
+
+	counts := make(map[string]int)
+	for _, v := range t.Votes {
+		if v.Value == "?" {
+			continue
+		}
+		counts[v.Value]++
+	}
+
+	best := ""
+	for value, n := range counts {
+		if best == "" || n > counts[best] || (n == counts[best] && order[value] > order[best]) {
+			best = value
+		}
+	}
+	return best, nil
+}
+
+// SetSyncStatus records the outcome of writing a ticket's estimate back to the
+// issue tracker.
+func SetSyncStatus(r *model.Room, ticketID string, status model.SyncStatus, value, syncErr string) error {
+	t := findTicket(r, ticketID)
+	if t == nil {
+		return ErrTicketNotFound
+	}
+	t.SyncStatus = status
+	t.SyncError = syncErr
+	if status == model.SyncStatusSynced {
+		t.SyncedValue = value
+	}
+	return nil
+}
+
+// FindTicketByExternalKey returns the ticket linked to the given external issue
+// key, or nil. An empty key never matches.
+func FindTicketByExternalKey(r *model.Room, key string) *model.Ticket {
+	if key == "" {
+		return nil
+	}
+	for _, t := range r.Tickets {
+		if t.ExternalKey == key {
+			return t
+		}
+	}
+	return nil
+}
+
 func touch(r *model.Room) {
 	r.LastActivityAt = time.Now()
 }
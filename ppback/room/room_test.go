@@ -119,6 +119,85 @@ func TestRemoveUserNonExistent(t *testing.T) {
 	RemoveUser(r, "nonexistent")
 }
 
+func TestMarkAway(t *testing.T) {
+	r := newTestRoom()
+	AddUser(r, &model.User{ID: "u1", Name: "Alice", AvatarID: "cat"})
+	AddUser(r, &model.User{ID: "u2", Name: "Bob", AvatarID: "dog"})
+	AddUser(r, &model.User{ID: "u3", Name: "Carol", AvatarID: "fox"})
+	RemoveUser(r, "u3")
+
+	now := time.Now()
+	r.Users["u1"].LastActiveAt = now.Add(-3 * time.Minute)
+	r.Users["u3"].LastActiveAt = now.Add(-3 * time.Minute)
+
+	if !MarkAway(r, 2*time.Minute, now) {
+		t.Fatal("expected a change")
+	}
+	if !r.Users["u1"].Away {
+		t.Error("expected idle user to be away")
+	}
+	if r.Users["u2"].Away {
+		t.Error("expected active user not to be away")
+	}
+	if r.Users["u3"].Away {
+		t.Error("expected disconnected user not to be away")
+	}
+	if MarkAway(r, 2*time.Minute, now) {
+		t.Error("expected no change on the second pass")
+	}
+}
+
+func TestMarkActive(t *testing.T) {
+	r := newTestRoom()
+	AddUser(r, &model.User{ID: "u1", Name: "Alice", AvatarID: "cat"})
+	r.Users["u1"].Away = true
+
+	now := time.Now()
+	wasAway, err := MarkActive(r, "u1", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !wasAway {
+		t.Error("expected user to have been away")
+	}
+	if r.Users["u1"].Away || !r.Users["u1"].LastActiveAt.Equal(now) {
+		t.Error("expected user to be active now")
+	}
+
+	if wasAway, _ := MarkActive(r, "u1", now); wasAway {
+		t.Error("expected no change for an active user")
+	}
+	if _, err := MarkActive(r, "nobody", now); err != ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestPendingVoters(t *testing.T) {
+	r := newTestRoom()
+	AddUser(r, &model.User{ID: "u1", Name: "Alice", AvatarID: "cat"})
+	AddUser(r, &model.User{ID: "u2", Name: "Bob", AvatarID: "dog"})
+	AddUser(r, &model.User{ID: "u3", Name: "Carol", AvatarID: "fox"})
+	RemoveUser(r, "u3")
+
+	if _, err := PendingVoters(r); err != ErrNotVoting {
+		t.Errorf("expected ErrNotVoting, got %v", err)
+	}
+
+	if err := StartFreeVote(r, "t1"); err != nil {
+		t.Fatalf("start free vote: %v", err)
+	}
+	if err := SubmitVote(r, "u1", "5"); err != nil {
+		t.Fatalf("submit vote: %v", err)
+	}
+	ids, err := PendingVoters(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != "u2" {
+		t.Errorf("expected [u2], got %v", ids)
+	}
+}
+
 func TestSubmitVote(t *testing.T) {
 	r := newTestRoom()
 	AddUser(r, &model.User{ID: "u1", Name: "Alice", AvatarID: "cat"})
@@ -1033,3 +1112,60 @@ func TestFullNavigationFlow(t *testing.T) {
 		t.Errorf("expected ErrTicketNotFound at start, got %v", err)
 	}
 }
+
+func TestFinalEstimate(t *testing.T) {
+	tests := []struct {
+		name   string
+		votes  []string
+		status model.TicketStatus
+		want   string
+	}{
+		{"majority", []string{"3", "5", "5"}, model.TicketStatusRevealed, "5"},
+		{"tie goes to larger", []string{"3", "8"}, model.TicketStatusRevealed, "8"},
+		{"question marks ignored", []string{"?", "?", "2"}, model.TicketStatusRevealed, "2"},
+		{"only question marks", []string{"?"}, model.TicketStatusRevealed, ""},
+		{"no votes", nil, model.TicketStatusRevealed, ""},
+		{"not revealed", []string{"5"}, model.TicketStatusVoting, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRoom()
+			ticket := &model.Ticket{ID: "t1", Status: tt.status, Votes: make(map[string]model.Vote)}
+			for i, v := range tt.votes {
+				id := string(rune('a' + i))
+				ticket.Votes[id] = model.Vote{UserID: id, Value: v}
+			}
+			r.Tickets = append(r.Tickets, ticket)
+			got, err := FinalEstimate(r, ticket)
+			if err != nil || got != tt.want {
+				t.Errorf("FinalEstimate = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown scale", func(t *testing.T) {
+		r := newTestRoom()
+		r.Scale = "removed"
+		ticket := &model.Ticket{ID: "t1", Status: model.TicketStatusRevealed, Votes: map[string]model.Vote{"a": {UserID: "a", Value: "5"}}}
+		if _, err := FinalEstimate(r, ticket); err == nil {
+			t.Error("expected an error for a scale that no longer exists")
+		}
+	})
+}
+
+func TestSetSyncStatus(t *testing.T) {
+	r := newTestRoom()
+	AddTicket(r, &model.Ticket{ID: "t1", ExternalKey: "PROJ-1"})
+
+	if err := SetSyncStatus(r, "t1", model.SyncStatusSynced, "5", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	snap := Snapshot(r)
+	if snap.Tickets[0].SyncStatus != model.SyncStatusSynced || snap.Tickets[0].SyncedValue != "5" {
+		t.Errorf("unexpected snapshot ticket: %+v", snap.Tickets[0])
+	}
+
+	if err := SetSyncStatus(r, "missing", model.SyncStatusFailed, "", "boom"); err != ErrTicketNotFound {
+		t.Errorf("expected ErrTicketNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package room
+
+import (
+	"context"
+	"pockerplan/ppback/model"
+	"sync"
+)
+
+// Store keeps the rooms of a Manager. The in-memory store serves a single
+// process; RedisStore lets several processes share their rooms.
+type Store interface {
+	// Add saves a new room.
+	Add(ctx context.Context, r *model.Room) error
+	// Get returns a room, or ErrRoomNotFound. The room must only be read.
+	Get(ctx context.Context, id string) (*model.Room, error)
+	// Update runs fn on a room and saves what fn changed, even when fn
+	// returns an error, which Update then returns. Updates of one room never
+	// overlap, whichever process sharing the store runs them.
+	Update(ctx context.Context, id string, fn func(r *model.Room) error) error
+	// Each calls fn for every room. fn must only read the room.
+	Each(ctx context.Context, fn func(r *model.Room)) error
+	// Delete removes a room. Removing a missing room is not an error.
+	Delete(ctx context.Context, id string) error
+	// Count returns the number of rooms.
+	Count(ctx context.Context) (int, error)
+	// Shared reports whether other processes may use the same rooms.
+	Shared() bool
+	// Ping checks that the store can be reached.
+	Ping(ctx context.Context) error
+	// Close releases the resources of the store.
+	Close()
+}
+
+// memoryStore keeps rooms in a map. A single lock serializes all updates.
+type memoryStore struct {
+	mu    sync.RWMutex
+	rooms map[string]*model.Room
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{rooms: make(map[string]*model.Room)}
+}
+
+func (s *memoryStore) Add(_ context.Context, r *model.Room) error {
+	s.mu.Lock()
+	s.rooms[r.ID] = r
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryStore) Get(_ context.Context, id string) (*model.Room, error) {
+	s.mu.RLock()
+	r, ok := s.rooms[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrRoomNotFound
+	}
+	return r, nil
+}
+
+func (s *memoryStore) Update(_ context.Context, id string, fn func(r *model.Room) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rooms[id]
+	if !ok {
+		return ErrRoomNotFound
+	}
+	return fn(r)
+}
+
+func (s *memoryStore) Each(_ context.Context, fn func(r *model.Room)) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, r := range s.rooms {
+		fn(r)
+	}
+	return nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.rooms, id)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryStore) Count(context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.rooms), nil
+}
+
+func (s *memoryStore) Shared() bool               { return false }
+func (s *memoryStore) Ping(context.Context) error { return nil }
+func (s *memoryStore) Close()                     {}
@@ -1,6 +1,10 @@
 package scale
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
 
 type EstimationScale struct {
 	ID     string   `json:"id"`
@@ -8,6 +12,12 @@ type EstimationScale struct {
 	Values []string `json:"values"`
 }
 
+// maxValueLen is the longest value a custom scale may define, so that cards
+// stay readable.
+const maxValueLen = 8
+
+var validID = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
 var scales = map[string]EstimationScale{
 	"fibonacci": {
 		ID:     "fibonacci",
@@ -31,9 +41,19 @@ var scales = map[string]EstimationScale{
 	},
 }
 
+var (
+	customMu sync.RWMutex
+	custom   = map[string]EstimationScale{}
+)
+
 // Get returns the estimation scale with the given ID.
 func Get(id string) (EstimationScale, error) {
-	s, ok := scales[id]
+	if s, ok := scales[id]; ok {
+		return s, nil
+	}
+	customMu.RLock()
+	s, ok := custom[id]
+	customMu.RUnlock()
 	if !ok {
 		return EstimationScale{}, fmt.Errorf("unknown scale: %s", id)
 	}
@@ -42,10 +62,15 @@ func Get(id string) (EstimationScale, error) {
 
 // All returns all available estimation scales.
 func All() []EstimationScale {
-	result := make([]EstimationScale, 0, len(scales))
+	customMu.RLock()
+	defer customMu.RUnlock()
+	result := make([]EstimationScale, 0, len(scales)+len(custom))
 	for _, s := range scales {
 		result = append(result, s)
 	}
+	for _, s := range custom {
+		result = append(result, s)
+	}
 	return result
 }
 
@@ -65,9 +90,68 @@ func ValidValue(scaleID, value string) bool {
 
 // IDs returns all available scale IDs.
 func IDs() []string {
-	ids := make([]string, 0, len(scales))
+	customMu.RLock()
+	defer customMu.RUnlock()
+	ids := make([]string, 0, len(scales)+len(custom))
 	for id := range scales {
 		ids = append(ids, id)
 	}
+	for id := range custom {
+		ids = append(ids, id)
+	}
 	return ids
 }
+
+// Validate checks custom scale definitions: IDs must be unique, must not
+// shadow a built-in scale and may only contain lowercase letters, digits, "_"
+// and "-"; every scale needs a name and at least two distinct values of up to
+// 8 characters.
+func Validate(list []EstimationScale) error {
+	seen := make(map[string]bool, len(list))
+	for i, s := range list {
+		if !validID.MatchString(s.ID) {
+			return fmt.Errorf("scale #%d: invalid id %q: use 1-32 lowercase letters, digits, _ or -", i+1, s.ID)
+		}
+		if _, ok := scales[s.ID]; ok {
+			return fmt.Errorf("scale %q: id of a built-in scale", s.ID)
+		}
+		if seen[s.ID] {
+			return fmt.Errorf("scale %q: defined twice", s.ID)
+		}
+		seen[s.ID] = true
+		if s.Name == "" {
+			return fmt.Errorf("scale %q: name is required", s.ID)
+		}
+		if len(s.Values) < 2 {
+			return fmt.Errorf("scale %q: at least two values are required", s.ID)
+		}
+		values := make(map[string]bool, len(s.Values))
+		for _, v := range s.Values {
+			if v == "" || len([]rune(v)) > maxValueLen {
+				return fmt.Errorf("scale %q: value %q must be 1-%d characters", s.ID, v, maxValueLen)
+			}
+			if values[v] {
+				return fmt.Errorf("scale %q: value %q appears twice", s.ID, v)
+			}
+			values[v] = true
+		}
+	}
+	return nil
+}
+
+// SetCustom replaces the custom scales offered next to the built-in ones.
+// Rooms using a custom scale see changes to its values immediately; a room
+// whose scale is removed no longer accepts votes until it is defined again.
+func SetCustom(list []EstimationScale) error {
+	if err := Validate(list); err != nil {
+		return err
+	}
+	next := make(map[string]EstimationScale, len(list))
+	for _, s := range list {
+		next[s.ID] = s
+	}
+	customMu.Lock()
+	custom = next
+	customMu.Unlock()
+	return nil
+}
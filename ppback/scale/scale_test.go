@@ -1,6 +1,9 @@
 package scale
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestGet(t *testing.T) {
 	tests := []struct {
@@ -149,3 +152,53 @@ func TestAllScalesHaveQuestionMark(t *testing.T) {
 		}
 	}
 }
+
+func TestSetCustom(t *testing.T) {
+	t.Cleanup(func() { SetCustom(nil) })
+
+	err := SetCustom([]EstimationScale{{ID: "hours", Name: "Hours", Values: []string{"1", "2", "4", "8", "?"}}})
+	if err != nil {
+		t.Fatalf("SetCustom: %v", err)
+	}
+	if len(All()) != 5 || len(IDs()) != 5 {
+		t.Errorf("expected 5 scales with the custom one, got %d", len(All()))
+	}
+	if !ValidValue("hours", "4") || ValidValue("hours", "3") {
+		t.Error("unexpected validation of custom scale values")
+	}
+
+	if err := SetCustom(nil); err != nil {
+		t.Fatalf("SetCustom(nil): %v", err)
+	}
+	if _, err := Get("hours"); err == nil {
+		t.Error("expected removed scale to be unknown")
+	}
+}
+
+func TestValidateCustom(t *testing.T) {
+	tests := []struct {
+		name  string
+		scale EstimationScale
+		want  string
+	}{
+		{"bad id", EstimationScale{ID: "Hours", Name: "Hours", Values: []string{"1", "2"}}, `invalid id "Hours"`},
+		{"builtin", EstimationScale{ID: "fibonacci", Name: "Fib", Values: []string{"1", "2"}}, "built-in"},
+		{"no name", EstimationScale{ID: "hours", Values: []string{"1", "2"}}, "name is required"},
+		{"one value", EstimationScale{ID: "hours", Name: "Hours", Values: []string{"1"}}, "at least two"},
+		{"duplicate value", EstimationScale{ID: "hours", Name: "Hours", Values: []string{"1", "1"}}, `value "1" appears twice`},
+		{"long value", EstimationScale{ID: "hours", Name: "Hours", Values: []string{"1", "123456789"}}, "1-8 characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]EstimationScale{tt.scale})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	twice := EstimationScale{ID: "hours", Name: "Hours", Values: []string{"1", "2"}}
+	if err := Validate([]EstimationScale{twice, twice}); err == nil || !strings.Contains(err.Error(), "defined twice") {
+		t.Errorf("expected duplicate id error, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"pockerplan/ppback/model"
+
+	"github.com/centrifugal/centrifuge"
+)
+
+// maxBodyBytes limits REST request bodies.
+const maxBodyBytes = 1 << 20
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+// apiRoutes registers the versioned REST API. Every endpoint is a thin adapter
+// over a hub RPC method, so validation and authorization are shared with the
+// WebSocket API.
+func (s *Server) apiRoutes() {
+	s.mux.HandleFunc("GET /api/v1/openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("POST /api/v1/rooms", s.handleCreateRoom)
+	s.mux.HandleFunc("GET /api/v1/rooms/{id}", s.handleGetRoom)
+	s.mux.HandleFunc("POST /api/v1/rooms/{id}/tickets", s.handleAddTicket)
+	s.mux.HandleFunc("POST /api/v1/rooms/{id}/navigate", s.handleNavigate)
+	s.mux.HandleFunc("POST /api/v1/rooms/{id}/reveal", s.handleReveal)
+	// Keep unknown API paths away from the SPA fallback.
+	s.mux.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, &centrifuge.Error{Code: http.StatusNotFound, Message: "not found"})
+	})
+}
+
+// apiError is the JSON body of a failed REST call.
+type apiError struct {
+	Error string `json:"error"`
+	Code  uint32 `json:"code"`
+}
+
+// navigateRequest selects the ticket to move to: either a direction
+// ("next" or "prev") or an explicit ticket ID.
+type navigateRequest struct {
+	Direction string `json:"direction,omitempty"`
+	TicketID  string `json:"ticketId,omitempty"`
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+func (s *Server) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateRoomRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	s.call(w, r, "create_room", req, http.StatusCreated)
+}
+
+func (s *Server) handleGetRoom(w http.ResponseWriter, r *http.Request) {
+	s.call(w, r, "get_room", model.GetRoomRequest{RoomID: r.PathValue("id")}, http.StatusOK)
+}
+
+func (s *Server) handleAddTicket(w http.ResponseWriter, r *http.Request) {
+	secret, ok := adminSecret(w, r)
+	if !ok {
+		return
+	}
+	var req model.AddTicketRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	req.RoomID = r.PathValue("id")
+	req.AdminSecret = secret
+	s.call(w, r, "add_ticket", req, http.StatusCreated)
+}
+
+func (s *Server) handleNavigate(w http.ResponseWriter, r *http.Request) {
+	secret, ok := adminSecret(w, r)
+	if !ok {
+		return
+	}
+	var body navigateRequest
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	roomID := r.PathValue("id")
+	switch {
+	case body.TicketID != "" && body.Direction == "":
+		s.call(w, r, "set_ticket", model.SetTicketRequest{RoomID: roomID, AdminSecret: secret, TicketID: body.TicketID}, http.StatusOK)
+	case body.TicketID == "" && body.Direction == "next":
+		s.call(w, r, "next_ticket", model.AdminActionRequest{RoomID: roomID, AdminSecret: secret}, http.StatusOK)
+	case body.TicketID == "" && body.Direction == "prev":
+		s.call(w, r, "prev_ticket", model.AdminActionRequest{RoomID: roomID, AdminSecret: secret}, http.StatusOK)
+	default:
+		writeAPIError(w, centrifuge.ErrorBadRequest)
+	}
+}
+
+func (s *Server) handleReveal(w http.ResponseWriter, r *http.Request) {
+	secret, ok := adminSecret(w, r)
+	if !ok {
+		return
+	}
+	req := model.AdminActionRequest{RoomID: r.PathValue("id"), AdminSecret: secret}
+	s.call(w, r, "reveal_votes", req, http.StatusOK)
+}
+
+// call runs a hub RPC method on behalf of r and writes its reply or error.
+func (s *Server) call(w http.ResponseWriter, r *http.Request, method string, req any, status int) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		writeAPIError(w, centrifuge.ErrorInternal)
+		return
+	}
+	reply, err := s.hub.Call(r.Context(), method, data)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(reply)
+}
+
+// adminSecret reads the room admin secret from an "Authorization: Bearer"
+// header. When it is missing, a 401 response is written and ok is false.
+func adminSecret(w http.ResponseWriter, r *http.Request) (secret string, ok bool) {
+	secret, ok = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if secret = strings.TrimSpace(secret); !ok || secret == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="pockerplan"`)
+		writeAPIError(w, &centrifuge.Error{Code: http.StatusUnauthorized, Message: "admin secret required"})
+		return "", false
+	}
+	return secret, true
+}
+
+// decodeBody decodes a JSON request body into v. An empty body leaves v
+// unchanged. On failure it writes a 400 response and returns false.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.ContentLength == 0 {
+		return true
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(v); err != nil {
+		writeAPIError(w, centrifuge.ErrorBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeAPIError(w http.ResponseWriter, err error) {
+	var ce *centrifuge.Error
+	if !errors.As(err, &ce) {
+		ce = centrifuge.ErrorInternal
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(ce))
+	json.NewEncoder(w).Encode(apiError{Error: ce.Message, Code: ce.Code})
+}
+
+// httpStatus maps hub RPC errors to HTTP status codes. Application error codes
+// and centrifuge's built-in errors are translated; HTTP-like codes pass through.
+func httpStatus(ce *centrifuge.Error) int {
+	switch ce.Code {
+	case model.CodeRoomNotFound, model.CodeUserNotFound:
+		return http.StatusNotFound
+	case model.CodeInvalidAdminSecret:
+		return http.StatusForbidden
+	case model.CodeTicketNotFound, model.CodeTreeNotFound,
+		model.CodeNotVoting, model.CodeNoCurrentTicket, model.CodeInvalidVote,
+		model.CodeTreeAlreadyBurned, model.CodeNoCampfire:
+		return http.StatusBadRequest
+	case model.CodeTrackerFailed:
+		return http.StatusBadGateway
+	case model.CodeServerDraining, model.CodeRoomBusy:
+		return http.StatusServiceUnavailable
+	case centrifuge.ErrorBadRequest.Code:
+		return http.StatusBadRequest
+	case centrifuge.ErrorPermissionDenied.Code:
+		return http.StatusForbidden
+	case centrifuge.ErrorMethodNotFound.Code:
+		return http.StatusNotFound
+	case centrifuge.ErrorNotAvailable.Code:
+		return http.StatusNotImplemented
+	case centrifuge.ErrorTooManyRequests.Code:
+		return http.StatusTooManyRequests
+	}
+	if ce.Code >= 400 && ce.Code <= 599 {
+		return int(ce.Code)
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pockerplan/ppback/model"
+)
+
+func apiRequest(t *testing.T, srv *Server, method, path, secret, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	return w
+}
+
+func decodeJSON(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("unmarshal %q: %v", w.Body.String(), err)
+	}
+}
+
+func createAPIRoom(t *testing.T, srv *Server) model.CreateRoomResponse {
+	t.Helper()
+	w := apiRequest(t, srv, http.MethodPost, "/api/v1/rooms", "", `{"scaleId":"fibonacci","userName":"bot","avatarId":"cat"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create room: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp model.CreateRoomResponse
+	decodeJSON(t, w, &resp)
+	if resp.RoomID == "" || resp.AdminSecret == "" {
+		t.Fatalf("create room: incomplete response %+v", resp)
+	}
+	return resp
+}
+
+func TestAPIRoomFlow(t *testing.T) {
+	srv, cleanup := newTestServer(t)
+	defer cleanup()
+
+	room := createAPIRoom(t, srv)
+	base := "/api/v1/rooms/" + room.RoomID
+
+	var ticketIDs []string
+	for _, content := range []string{"first", "second"} {
+		w := apiRequest(t, srv, http.MethodPost, base+"/tickets", room.AdminSecret, `{"content":"`+content+`"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("add ticket: expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp model.AddTicketResponse
+		decodeJSON(t, w, &resp)
+		ticketIDs = append(ticketIDs, resp.TicketID)
+	}
+
+	w := apiRequest(t, srv, http.MethodPost, base+"/navigate", room.AdminSecret, `{"ticketId":"`+ticketIDs[1]+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("navigate: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = apiRequest(t, srv, http.MethodPost, base+"/navigate", room.AdminSecret, `{"direction":"prev"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("navigate prev: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = apiRequest(t, srv, http.MethodPost, base+"/reveal", room.AdminSecret, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("reveal: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = apiRequest(t, srv, http.MethodGet, base, "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get room: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var snap model.RoomSnapshot
+	decodeJSON(t, w, &snap)
+	if snap.ID != room.RoomID {
+		t.Errorf("expected room %s, got %s", room.RoomID, snap.ID)
+	}
+	if len(snap.Tickets) != 2 {
+		t.Fatalf("expected 2 tickets, got %d", len(snap.Tickets))
+	}
+	if snap.CurrentTicketID != ticketIDs[0] {
+		t.Errorf("expected current ticket %s, got %s", ticketIDs[0], snap.CurrentTicketID)
+	}
+	if snap.State != model.RoomStateRevealed {
+		t.Errorf("expected state revealed, got %s", snap.State)
+	}
+	if len(snap.Users) != 1 || snap.Users[0].Connected {
+		t.Errorf("expected one offline creator, got %+v", snap.Users)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	srv, cleanup := newTestServer(t)
+	defer cleanup()
+
+	room := createAPIRoom(t, srv)
+	base := "/api/v1/rooms/" + room.RoomID
+
+	tests := []struct {
+		name           string
+		method, path   string
+		secret, body   string
+		expectedStatus int
+		expectedCode   uint32 // 0 skips the check
+	}{
+		{"unknown room", http.MethodGet, "/api/v1/rooms/missing", "", "", http.StatusNotFound, model.CodeRoomNotFound},
+		{"bad scale", http.MethodPost, "/api/v1/rooms", "", `{"scaleId":"nope","userName":"bot","avatarId":"cat"}`, http.StatusBadRequest, 0},
+		{"malformed body", http.MethodPost, "/api/v1/rooms", "", `{`, http.StatusBadRequest, 0},
+		{"missing secret", http.MethodPost, base + "/reveal", "", "", http.StatusUnauthorized, 0},
+		{"wrong secret", http.MethodPost, base + "/tickets", "wrong", `{"content":"x"}`, http.StatusForbidden, model.CodeInvalidAdminSecret},
+		{"empty ticket", http.MethodPost, base + "/tickets", room.AdminSecret, `{"content":""}`, http.StatusBadRequest, 0},
+		{"bad direction", http.MethodPost, base + "/navigate", room.AdminSecret, `{"direction":"sideways"}`, http.StatusBadRequest, 0},
+		{"direction and ticket", http.MethodPost, base + "/navigate", room.AdminSecret, `{"direction":"next","ticketId":"x"}`, http.StatusBadRequest, 0},
+		{"unknown ticket", http.MethodPost, base + "/navigate", room.AdminSecret, `{"ticketId":"missing"}`, http.StatusBadRequest, model.CodeTicketNotFound},
+		{"unknown endpoint", http.MethodDelete, base, "", "", http.StatusNotFound, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := apiRequest(t, srv, tt.method, tt.path, tt.secret, tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			var resp apiError
+			decodeJSON(t, w, &resp)
+			if resp.Error == "" || resp.Code == 0 {
+				t.Errorf("expected error body, got %s", w.Body.String())
+			}
+			if tt.expectedCode != 0 && resp.Code != tt.expectedCode {
+				t.Errorf("expected code %d, got %d", tt.expectedCode, resp.Code)
+			}
+		})
+	}
+}
+
+func TestAPIOpenAPISpec(t *testing.T) {
+	srv, cleanup := newTestServer(t)
+	defer cleanup()
+
+	w := apiRequest(t, srv, http.MethodGet, "/api/v1/openapi.json", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var spec struct {
+		OpenAPI string         `json:"openapi"`
+		Paths   map[string]any `json:"paths"`
+	}
+	decodeJSON(t, w, &spec)
+	if spec.OpenAPI == "" {
+		t.Error("expected openapi version")
+	}
+	for _, path := range []string{"/rooms", "/rooms/{id}", "/rooms/{id}/tickets", "/rooms/{id}/navigate", "/rooms/{id}/reveal"} {
+		if _, ok := spec.Paths[path]; !ok {
+			t.Errorf("spec is missing path %s", path)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// originPattern matches Origin header values: an exact scheme://host[:port],
+// a wildcard subdomain (https://*.example.com) or anything ("*").
+type originPattern struct {
+	any      bool
+	scheme   string
+	host     string // without the "*." of a wildcard
+	port     string
+	wildcard bool
+}
+
+func parseOrigin(s string) (originPattern, error) {
+	if s == "*" {
+		return originPattern{any: true}, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		return originPattern{}, fmt.Errorf("allowed origin %q: want scheme://host[:port], e.g. https://*.example.com", s)
+	}
+	p := originPattern{scheme: u.Scheme, host: strings.ToLower(u.Hostname()), port: u.Port()}
+	if rest, ok := strings.CutPrefix(p.host, "*."); ok {
+		p.host, p.wildcard = rest, true
+	}
+	if p.host == "" || strings.Contains(p.host, "*") {
+		return originPattern{}, fmt.Errorf("allowed origin %q: only a leading *. wildcard is supported", s)
+	}
+	return p, nil
+}
+
+func (p originPattern) match(origin *url.URL) bool {
+	if p.any {
+		return true
+	}
+	if origin.Scheme != p.scheme || origin.Port() != p.port {
+		return false
+	}
+	host := strings.ToLower(origin.Hostname())
+	if p.wildcard {
+		return strings.HasSuffix(host, "."+p.host)
+	}
+	return host == p.host
+}
+
+// ValidateOrigins checks values for WithAllowedOrigins.
+func ValidateOrigins(origins []string) error {
+	for _, o := range origins {
+		if _, err := parseOrigin(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithAllowedOrigins lets browser pages from other origins open WebSocket
+// connections. Each origin is scheme://host[:port], where the host may start
+// with "*." to match every subdomain; "*" allows any origin. Pages served by
+// this server's own host are always allowed. Invalid entries are skipped;
+// check them with ValidateOrigins first.
+func WithAllowedOrigins(origins ...string) Option {
+	return func(s *Server) {
+		for _, o := range origins {
+			if p, err := parseOrigin(o); err == nil {
+				s.origins = append(s.origins, p)
+			}
+		}
+	}
+}
+
+// WithTrustedProxies trusts the X-Forwarded-Host and X-Forwarded-Proto headers
+// of requests coming from the given addresses, so that the public host and
+// scheme seen by a reverse proxy are used for origin checks and HSTS.
+func WithTrustedProxies(prefixes ...netip.Prefix) Option {
+	return func(s *Server) {
+		s.proxies = append(s.proxies, prefixes...)
+	}
+}
+
+// ParseTrustedProxies parses IP addresses and CIDR ranges for
+// WithTrustedProxies.
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		if p, err := netip.ParsePrefix(v); err == nil {
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: want an IP address or CIDR range", v)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// fromTrustedProxy reports whether the request was sent by a trusted proxy.
+func (s *Server) fromTrustedProxy(r *http.Request) bool {
+	if len(s.proxies) == 0 {
+		return false
+	}
+	ap, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	addr := ap.Addr().Unmap()
+	for _, p := range s.proxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwarded returns the first value of a X-Forwarded-* header.
+func forwarded(r *http.Request, name string) string {
+	v, _, _ := strings.Cut(r.Header.Get(name), ",")
+	return strings.TrimSpace(v)
+}
+
+// publicHost returns the host the client addressed: X-Forwarded-Host from a
+// trusted proxy, or the Host header.
+func (s *Server) publicHost(r *http.Request) string {
+	if s.fromTrustedProxy(r) {
+		if h := forwarded(r, "X-Forwarded-Host"); h != "" {
+			return h
+		}
+	}
+	return r.Host
+}
+
+// isHTTPS reports whether the client connected over HTTPS, either to this
+// server or to a trusted proxy in front of it.
+func (s *Server) isHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return s.fromTrustedProxy(r) && strings.EqualFold(forwarded(r, "X-Forwarded-Proto"), "https")
+}
+
+// checkOrigin allows WebSocket connections from non-browser clients, from
+// pages of the host the client addressed and from the allowed origins.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // allow non-browser clients (e.g., CLI tools)
+	}
+	host := s.publicHost(r)
+	if origin == "http://"+host || origin == "https://"+host {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	for _, p := range s.origins {
+		if p.match(u) {
+			return true
+		}
+	}
+	return false
+}
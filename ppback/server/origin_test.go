@@ -0,0 +1,119 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestOriginPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		origin  string
+		want    bool
+	}{
+		{"https://plan.example.com", "https://plan.example.com", true},
+		{"https://plan.example.com", "https://PLAN.example.com", true},
+		{"https://plan.example.com", "http://plan.example.com", false},
+		{"https://plan.example.com", "https://plan.example.com:8443", false},
+		{"https://*.example.com", "https://a.example.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://evilexample.com", false},
+		{"http://localhost:5173", "http://localhost:5173", true},
+		{"*", "https://anything.test", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.origin, func(t *testing.T) {
+			p, err := parseOrigin(tt.pattern)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.pattern, err)
+			}
+			u, _ := url.Parse(tt.origin)
+			if got := p.match(u); got != tt.want {
+				t.Errorf("match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOrigins(t *testing.T) {
+	for _, o := range []string{"example.com", "ftp://example.com", "https://example.com/path", "https://a.*.example.com", "https://*."} {
+		if err := ValidateOrigins([]string{o}); err == nil {
+			t.Errorf("expected error for %q", o)
+		}
+	}
+	if err := ValidateOrigins([]string{"https://*.example.com", "http://localhost:5173/", "*"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	prefixes, err := ParseTrustedProxies([]string{"10.0.0.0/8", "127.0.0.1", "::1"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(prefixes) != 3 || prefixes[1].Bits() != 32 || prefixes[2].Bits() != 128 {
+		t.Errorf("unexpected prefixes %v", prefixes)
+	}
+	if _, err := ParseTrustedProxies([]string{"proxy.local"}); err == nil {
+		t.Error("expected error for host name")
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	proxies, _ := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	s := &Server{}
+	WithAllowedOrigins("https://*.example.com")(s)
+	WithTrustedProxies(proxies...)(s)
+
+	tests := []struct {
+		name   string
+		remote string
+		host   string
+		fwd    string
+		origin string
+		want   bool
+	}{
+		{"no origin", "192.0.2.1:1000", "internal:8080", "", "", true},
+		{"same host", "192.0.2.1:1000", "internal:8080", "", "https://internal:8080", true},
+		{"allowed pattern", "192.0.2.1:1000", "internal:8080", "", "https://team.example.com", true},
+		{"other origin", "192.0.2.1:1000", "internal:8080", "", "https://evil.test", false},
+		{"forwarded host from proxy", "10.1.2.3:1000", "internal:8080", "poker.test", "https://poker.test", true},
+		{"forwarded host from client", "192.0.2.1:1000", "internal:8080", "poker.test", "https://poker.test", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/connection/websocket", nil)
+			r.RemoteAddr = tt.remote
+			r.Host = tt.host
+			if tt.fwd != "" {
+				r.Header.Set("X-Forwarded-Host", tt.fwd)
+			}
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := s.checkOrigin(r); got != tt.want {
+				t.Errorf("checkOrigin = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsHTTPSBehindProxy(t *testing.T) {
+	proxies, _ := ParseTrustedProxies([]string{"10.0.0.1"})
+	s := &Server{}
+	WithTrustedProxies(proxies...)(s)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Forwarded-Proto", "https, http")
+	r.RemoteAddr = "10.0.0.1:5000"
+	if !s.isHTTPS(r) {
+		t.Error("expected forwarded https from trusted proxy")
+	}
+	r.RemoteAddr = "10.0.0.2:5000"
+	if s.isHTTPS(r) {
+		t.Error("expected forwarded proto from untrusted address to be ignored")
+	}
+}
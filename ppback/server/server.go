@@ -2,11 +2,20 @@ package server
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"html"
 	"io/fs"
 	"net"
 	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"pockerplan/ppback/avatar"
@@ -14,9 +23,17 @@ import (
 	"pockerplan/ppback/scale"
 
 	"github.com/centrifugal/centrifuge"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("pockerplan/ppback/server")
+
 // responseWriter wraps http.ResponseWriter to capture the status code.
 type responseWriter struct {
 	http.ResponseWriter
@@ -55,34 +72,156 @@ type Server struct {
 	frontFS fs.FS
 	logger  zerolog.Logger
 	mux     *http.ServeMux
+	hsts    time.Duration // Strict-Transport-Security max-age; 0 omits the header
+	origins []originPattern
+	proxies []netip.Prefix
+	// basePath is the prefix everything is served under, without a trailing
+	// slash; empty when served at the root.
+	basePath string
+	index    []byte // index.html pointing <base> at basePath; nil to serve the file as is
+}
+
+// Option configures optional Server features.
+type Option func(*Server)
+
+// WithHSTS sends a Strict-Transport-Security header with the given max-age on
+// responses to HTTPS requests, so that browsers only use HTTPS afterwards.
+func WithHSTS(maxAge time.Duration) Option {
+	return func(s *Server) {
+		s.hsts = maxAge
+	}
+}
+
+// WithBasePath serves the API, the WebSocket endpoint and the frontend under
+// the path prefix p, such as /poker, for reverse proxies that forward a
+// sub-path. Requests outside of it get 404.
+func WithBasePath(p string) Option {
+	return func(s *Server) {
+		s.basePath = strings.TrimSuffix(p, "/")
+	}
+}
+
+// ValidateBasePath checks a value for WithBasePath.
+func ValidateBasePath(p string) error {
+	if p == "" {
+		return nil
+	}
+	clean := p == "/" || path.Clean(p) == strings.TrimSuffix(p, "/")
+	if !strings.HasPrefix(p, "/") || !clean || strings.ContainsAny(p, "?#\"<>") {
+		return fmt.Errorf("base path %q: want an absolute path such as /poker", p)
+	}
+	return nil
 }
 
 // New creates a new Server with all routes configured.
 // frontFS should be the subdirectory of the embedded FS pointing at the built frontend (e.g. ppfront/dist).
-func New(h *hub.Hub, frontFS fs.FS, logger zerolog.Logger) *Server {
+func New(h *hub.Hub, frontFS fs.FS, logger zerolog.Logger, opts ...Option) *Server {
 	s := &Server{
 		hub:     h,
 		frontFS: frontFS,
 		logger:  logger,
 		mux:     http.NewServeMux(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.loadIndex()
 	s.routes()
 	return s
 }
 
+// loadIndex reads index.html and adds a <base> element pointing at the base
+// path. The frontend resolves its assets, API calls and routes against it, so
+// it works on every client-side route and under any prefix.
+func (s *Server) loadIndex() {
+	data, err := fs.ReadFile(s.frontFS, "index.html")
+	if err != nil {
+		return
+	}
+	i := bytes.Index(data, []byte("<head>"))
+	if i < 0 {
+		return
+	}
+	i += len("<head>")
+	base := `<base href="` + html.EscapeString(s.basePath+"/") + `">`
+	s.index = slices.Concat(data[:i], []byte(base), data[i:])
+}
+
+// stripBasePath returns the request with the base path removed from its URL.
+// It answers requests outside of the base path itself and returns nil for
+// them: the bare prefix redirects to its directory form, anything else is not
+// found.
+func (s *Server) stripBasePath(w http.ResponseWriter, r *http.Request) *http.Request {
+	if r.URL.Path == s.basePath {
+		target := s.basePath + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return nil
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, s.basePath)
+	if !ok || !strings.HasPrefix(rest, "/") {
+		http.NotFound(w, r)
+		return nil
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = rest
+	r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, s.basePath)
+	return r2
+}
+
 // ServeHTTP implements http.Handler.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Skip request logging for WebSocket connections (long-lived, misleading duration).
-	if r.URL.Path == "/connection/websocket" {
+	reqPath := r.URL.Path
+	if s.basePath != "" {
+		if r = s.stripBasePath(w, r); r == nil {
+			return
+		}
+	}
+	if s.hsts > 0 && s.isHTTPS(r) {
+		w.Header().Set("Strict-Transport-Security", "max-age="+strconv.Itoa(int(s.hsts.Seconds())))
+	}
+	// Skip request logging for WebSocket connections (long-lived, misleading
+	// duration) and for metrics scrapes.
+	if r.URL.Path == "/connection/websocket" || r.URL.Path == "/metrics" {
 		s.mux.ServeHTTP(w, r)
 		return
 	}
 	start := time.Now()
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracer.Start(ctx, r.Method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.path", reqPath),
+		))
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
 	s.mux.ServeHTTP(rw, r)
+
+	// The mux records the matched pattern on the request; naming the span
+	// after it keeps span names low-cardinality.
+	if r.Pattern != "" {
+		name := r.Pattern
+		if !strings.Contains(name, " ") {
+			name = r.Method + " " + name
+		}
+		span.SetName(name)
+		span.SetAttributes(attribute.String("http.route", r.Pattern))
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", rw.status))
+	if rw.status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(rw.status))
+	}
 	s.logger.Info().
 		Str("method", r.Method).
-		Str("path", r.URL.Path).
+		Str("path", reqPath).
 		Int("status", rw.status).
 		Dur("duration", time.Since(start)).
 		Str("remote", r.RemoteAddr).
@@ -92,25 +231,45 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (s *Server) routes() {
 	// WebSocket endpoint for centrifuge
 	wsHandler := centrifuge.NewWebsocketHandler(s.hub.Node(), centrifuge.WebsocketConfig{
-		CheckOrigin: func(r *http.Request) bool {
-			origin := r.Header.Get("Origin")
-			if origin == "" {
-				return true // allow non-browser clients (e.g., CLI tools)
-			}
-			return origin == "http://"+r.Host || origin == "https://"+r.Host
-		},
+		CheckOrigin: s.checkOrigin,
 	})
 	s.mux.Handle("/connection/websocket", wsHandler)
 
 	// API endpoints
 	s.mux.HandleFunc("/api/scales", s.handleScales)
 	s.mux.HandleFunc("/api/avatars", s.handleAvatars)
-	s.mux.HandleFunc("/api/health", s.handleHealth)
+	s.mux.HandleFunc("GET /api/health", s.handleReady)
+	s.mux.HandleFunc("GET /api/health/live", s.handleLive)
+	s.mux.HandleFunc("GET /api/health/ready", s.handleReady)
+	s.mux.Handle("GET /metrics", promhttp.Handler())
+	s.apiRoutes()
 
 	// SPA fallback: serve static files, fall back to index.html for client-side routing
 	s.mux.Handle("/", s.spaHandler())
 }
 
+// RedirectHTTPS returns a handler that permanently redirects every request to
+// the same URL over HTTPS, on the port of httpsAddr.
+func RedirectHTTPS(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+	})
+}
+
 func (s *Server) handleScales(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -129,17 +288,39 @@ func (s *Server) handleAvatars(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(avatar.All())
 }
 
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	type response struct {
-		Status string `json:"status"`
+// healthResponse is the body of the health endpoints. Checks are only
+// included with ?verbose=1.
+type healthResponse struct {
+	Status string      `json:"status"`
+	Checks []hub.Check `json:"checks,omitempty"`
+}
+
+// handleLive reports that the process serves HTTP. It fails only when the
+// server is stuck, which is when it should be restarted.
+func (s *Server) handleLive(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, true, nil)
+}
+
+// handleReady reports whether the server should receive new traffic. It fails
+// while the server starts, drains before shutdown or a background loop is
+// stuck.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	ready := s.hub.Readiness()
+	writeHealth(w, r, ready.Ready, ready.Checks)
+}
+
+func writeHealth(w http.ResponseWriter, r *http.Request, ok bool, checks []hub.Check) {
+	resp := healthResponse{Status: "ok"}
+	if v, _ := strconv.ParseBool(r.URL.Query().Get("verbose")); v {
+		resp.Checks = checks
 	}
 	w.Header().Set("Content-Type", "application/json")
-	if !s.hub.Healthy() {
+	w.Header().Set("Cache-Control", "no-store")
+	if !ok {
+		resp.Status = "unavailable"
 		w.WriteHeader(http.StatusServiceUnavailable)
-		json.NewEncoder(w).Encode(response{Status: "unavailable"})
-		return
 	}
-	json.NewEncoder(w).Encode(response{Status: "ok"})
+	json.NewEncoder(w).Encode(resp)
 }
 
 // spaHandler returns an http.Handler that serves static files from frontFS
@@ -150,7 +331,7 @@ func (s *Server) spaHandler() http.Handler {
 		// Try to open the requested path in the frontend FS.
 		path := r.URL.Path
 		if path == "/" {
-			fileServer.ServeHTTP(w, r)
+			s.serveIndex(w, r, fileServer)
 			return
 		}
 
@@ -160,7 +341,7 @@ func (s *Server) spaHandler() http.Handler {
 		if err != nil {
 			// File not found — serve index.html for SPA client-side routing
 			r.URL.Path = "/"
-			fileServer.ServeHTTP(w, r)
+			s.serveIndex(w, r, fileServer)
 			return
 		}
 		f.Close()
@@ -169,3 +350,14 @@ func (s *Server) spaHandler() http.Handler {
 		fileServer.ServeHTTP(w, r)
 	})
 }
+
+// serveIndex serves index.html with its <base> element, or the file as is
+// when it has no <head> to put one in.
+func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request, fileServer http.Handler) {
+	if s.index == nil {
+		fileServer.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	http.ServeContent(w, r, "index.html", time.Time{}, bytes.NewReader(s.index))
+}
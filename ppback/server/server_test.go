@@ -5,14 +5,17 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"testing/fstest"
+	"time"
 
 	"pockerplan/ppback/avatar"
 	"pockerplan/ppback/hub"
 	"pockerplan/ppback/room"
 	"pockerplan/ppback/scale"
 
+	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog"
 )
 
@@ -76,6 +79,86 @@ func TestHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestHealthLiveAndReady(t *testing.T) {
+	logger := zerolog.Nop()
+	rm := room.NewManager()
+	h, err := hub.New(rm, 3, false, logger)
+	if err != nil {
+		t.Fatalf("create hub: %v", err)
+	}
+	srv := New(h, fstest.MapFS{}, logger)
+
+	get := func(path string) (int, healthResponse) {
+		t.Helper()
+		rw := httptest.NewRecorder()
+		srv.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp healthResponse
+		if err := json.Unmarshal(rw.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal %s: %v", path, err)
+		}
+		return rw.Code, resp
+	}
+
+	if code, _ := get("/api/health/ready"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected not ready before the node runs, got %d", code)
+	}
+	if code, _ := get("/api/health/live"); code != http.StatusOK {
+		t.Errorf("expected live before the node runs, got %d", code)
+	}
+
+	if err := h.Run(); err != nil {
+		t.Fatalf("run hub: %v", err)
+	}
+	defer h.Shutdown()
+	done := make(chan struct{})
+	defer close(done)
+	rm.StartCleanup(time.Minute, done)
+	h.StartCampfireLoop(time.Minute, done)
+
+	code, resp := get("/api/health/ready?verbose=1")
+	if code != http.StatusOK || resp.Status != "ok" {
+		t.Fatalf("expected ready, got %d %+v", code, resp)
+	}
+	var names []string
+	for _, c := range resp.Checks {
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "node,draining,cleanup_loop,campfire_loop" {
+		t.Errorf("unexpected checks %v", names)
+	}
+	if _, resp := get("/api/health/ready"); resp.Checks != nil {
+		t.Errorf("expected no checks without verbose, got %+v", resp.Checks)
+	}
+
+	h.Drain(0, 0)
+	code, resp = get("/api/health/ready?verbose=true")
+	if code != http.StatusServiceUnavailable || resp.Status != "unavailable" {
+		t.Errorf("expected unavailable while draining, got %d %+v", code, resp)
+	}
+	if code, _ := get("/api/health"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected /api/health to follow readiness, got %d", code)
+	}
+	if code, _ := get("/api/health/live"); code != http.StatusOK {
+		t.Errorf("expected live while draining, got %d", code)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	srv, cleanup := newTestServer(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "go_goroutines") {
+		t.Error("expected Prometheus text output")
+	}
+}
+
 func TestScalesEndpoint(t *testing.T) {
 	srv, cleanup := newTestServer(t)
 	defer cleanup()
@@ -259,3 +342,134 @@ func TestWebSocketEndpointExists(t *testing.T) {
 		t.Error("websocket endpoint should be registered")
 	}
 }
+
+func TestWebSocket_AcceptsHTTPSOrigin(t *testing.T) {
+	srv, cleanup := newTestServer(t)
+	defer cleanup()
+	ts := httptest.NewTLSServer(srv)
+	defer ts.Close()
+
+	dialer := websocket.Dialer{TLSClientConfig: ts.Client().Transport.(*http.Transport).TLSClientConfig}
+	wsURL := "wss" + strings.TrimPrefix(ts.URL, "https") + "/connection/websocket"
+	conn, resp, err := dialer.Dial(wsURL, http.Header{"Origin": {ts.URL}})
+	if err != nil {
+		t.Fatalf("dial with same https origin: %v (response %v)", err, resp)
+	}
+	conn.Close()
+
+	_, resp, err = dialer.Dial(wsURL, http.Header{"Origin": {"https://evil.example.com"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for cross-origin https request, got %v", err)
+	}
+}
+
+func TestHSTS(t *testing.T) {
+	logger := zerolog.Nop()
+	h, err := hub.New(room.NewManager(), 3, false, logger)
+	if err != nil {
+		t.Fatalf("create hub: %v", err)
+	}
+	defer h.Shutdown()
+	srv := New(h, fstest.MapFS{"index.html": {Data: []byte("app")}}, logger, WithHSTS(365*24*time.Hour))
+
+	rw := httptest.NewRecorder()
+	srv.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "https://example.com/api/scales", nil))
+	if got := rw.Header().Get("Strict-Transport-Security"); got != "max-age=31536000" {
+		t.Errorf("unexpected HSTS header %q", got)
+	}
+
+	rw = httptest.NewRecorder()
+	srv.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "http://example.com/api/scales", nil))
+	if got := rw.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("expected no HSTS header over plain HTTP, got %q", got)
+	}
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	tests := []struct {
+		httpsAddr string
+		method    string
+		target    string
+		status    int
+		location  string
+	}{
+		{":443", http.MethodGet, "http://example.com/room/1?x=y", http.StatusMovedPermanently, "https://example.com/room/1?x=y"},
+		{":8443", http.MethodGet, "http://example.com:8080/", http.StatusMovedPermanently, "https://example.com:8443/"},
+		{":443", http.MethodPost, "http://example.com/api/rooms", http.StatusPermanentRedirect, "https://example.com/api/rooms"},
+		{":443", http.MethodGet, "http://[::1]:8080/", http.StatusMovedPermanently, "https://[::1]/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			RedirectHTTPS(tt.httpsAddr).ServeHTTP(rw, httptest.NewRequest(tt.method, tt.target, nil))
+			if rw.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if got := rw.Header().Get("Location"); got != tt.location {
+				t.Errorf("expected Location %q, got %q", tt.location, got)
+			}
+		})
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	logger := zerolog.Nop()
+	h, err := hub.New(room.NewManager(), 3, false, logger)
+	if err != nil {
+		t.Fatalf("create hub: %v", err)
+	}
+	defer h.Shutdown()
+	frontFS := fstest.MapFS{
+		"index.html":    {Data: []byte("<html><head><title>app</title></head></html>")},
+		"assets/app.js": {Data: []byte("console.log('app')")},
+	}
+	if err := h.Run(); err != nil {
+		t.Fatalf("run hub: %v", err)
+	}
+	srv := New(h, frontFS, logger, WithBasePath("/poker/"))
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/poker/api/health", http.StatusOK, `"ok"`},
+		{"/poker/assets/app.js", http.StatusOK, "console.log"},
+		{"/poker/room/abc", http.StatusOK, `<head><base href="/poker/"><title>`},
+		{"/poker/", http.StatusOK, `<base href="/poker/">`},
+		{"/poker", http.StatusMovedPermanently, ""},
+		{"/api/health", http.StatusNotFound, ""},
+		{"/pokerface/", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			srv.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rw.Code != tt.status {
+				t.Fatalf("expected %d, got %d", tt.status, rw.Code)
+			}
+			if !strings.Contains(rw.Body.String(), tt.body) {
+				t.Errorf("expected body containing %q, got %q", tt.body, rw.Body.String())
+			}
+		})
+	}
+
+	rw := httptest.NewRecorder()
+	srv.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/poker?x=1", nil))
+	if loc := rw.Header().Get("Location"); loc != "/poker/?x=1" {
+		t.Errorf("unexpected redirect to %q", loc)
+	}
+}
+
+func TestValidateBasePath(t *testing.T) {
+	for _, p := range []string{"", "/", "/poker", "/poker/", "/a/b"} {
+		if err := ValidateBasePath(p); err != nil {
+			t.Errorf("ValidateBasePath(%q): %v", p, err)
+		}
+	}
+	for _, p := range []string{"poker", "/a//b", "/a/../b", "/a?b", `/a"b`} {
+		if err := ValidateBasePath(p); err == nil {
+			t.Errorf("expected error for %q", p)
+		}
+	}
+}
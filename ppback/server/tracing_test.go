@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// spans receives the spans of every test in the package. Tracers are created
+// once per package from the global provider, so the provider is installed
+// once in TestMain and each test swaps in its own recorder with recordSpans.
+var spans spanSink
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(&spans)))
+	os.Exit(m.Run())
+}
+
+// recordSpans returns a recorder receiving the spans ended until the test
+// finishes.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	spans.rec.Store(sr)
+	t.Cleanup(func() { spans.rec.Store(nil) })
+	return sr
+}
+
+// spanSink is a span processor forwarding to the current test's recorder.
+type spanSink struct {
+	rec atomic.Pointer[tracetest.SpanRecorder]
+}
+
+func (s *spanSink) OnStart(ctx context.Context, span sdktrace.ReadWriteSpan) {
+	if sr := s.rec.Load(); sr != nil {
+		sr.OnStart(ctx, span)
+	}
+}
+
+func (s *spanSink) OnEnd(span sdktrace.ReadOnlySpan) {
+	if sr := s.rec.Load(); sr != nil {
+		sr.OnEnd(span)
+	}
+}
+
+func (s *spanSink) Shutdown(context.Context) error   { return nil }
+func (s *spanSink) ForceFlush(context.Context) error { return nil }
+
+func TestRequestTracing(t *testing.T) {
+	sr := recordSpans(t)
+
+	srv, cleanup := newTestServer(t)
+	defer cleanup()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/rooms/missing", nil)
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+
+	var httpSpan, rpcSpan sdktrace.ReadOnlySpan
+	for _, s := range sr.Ended() {
+		switch s.Name() {
+		case "GET /api/v1/rooms/{id}":
+			httpSpan = s
+		case "rpc get_room":
+			rpcSpan = s
+		}
+	}
+	if httpSpan == nil || rpcSpan == nil {
+		t.Fatalf("expected HTTP and RPC spans, got %d spans", len(sr.Ended()))
+	}
+	if rpcSpan.Parent().SpanID() != httpSpan.SpanContext().SpanID() {
+		t.Error("expected the RPC span to be a child of the HTTP span")
+	}
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestSetupDisabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{})
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+}
+
+func TestSetupErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"unknown exporter", Config{Exporter: "zipkin"}},
+		{"negative ratio", Config{Exporter: ExporterStdout, SampleRatio: -1}},
+		{"ratio above one", Config{Exporter: ExporterStdout, SampleRatio: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Setup(context.Background(), tt.cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestSetupStdout(t *testing.T) {
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{
+		Exporter:    ExporterStdout,
+		SampleRatio: 1,
+		ServiceName: "pockerplan-test",
+		Output:      &out,
+	})
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if !strings.Contains(out.String(), "test-span") || !strings.Contains(out.String(), "pockerplan-test") {
+		t.Errorf("expected span in output, got %q", out.String())
+	}
+}
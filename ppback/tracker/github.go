@@ -0,0 +1,154 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"pockerplan/ppback/model"
+)
+
+// github talks to the GitHub REST API. The query uses the issue search syntax,
+// e.g. "repo:owner/name is:open label:sprint-12".
+type github struct {
+	cfg Config
+}
+
+func newGitHub(cfg Config) *github {
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &github{cfg: cfg}
+}
+
+func (g *github) Name() string { return "github" }
+
+type githubSearchResponse struct {
+	Items []struct {
+		Number        int             `json:"number"`
+		Title         string          `json:"title"`
+		Body          string          `json:"body"`
+		HTMLURL       string          `json:"html_url"`
+		RepositoryURL string          `json:"repository_url"`
+		PullRequest   json.RawMessage `json:"pull_request,omitempty"`
+	} `json:"items"`
+}
+
+// FetchIssues runs an issue search and returns the matching issues as tickets.
+// Pull requests returned by the search are skipped.
+func (g *github) FetchIssues(ctx context.Context, query string) ([]*model.Ticket, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("per_page", strconv.Itoa(g.cfg.MaxResults))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.cfg.BaseURL+"/search/issues?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("github: build request: %w", err)
+	}
+	g.authorize(req)
+
+	resp, err := g.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github: search: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{Provider: "github", Status: resp.StatusCode}
+	}
+
+	var body githubSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("github: decode response: %w", err)
+	}
+
+	tickets := make([]*model.Ticket, 0, len(body.Items))
+	for _, item := range body.Items {
+		if len(item.PullRequest) > 0 {
+			continue
+		}
+		key := githubRepo(item.RepositoryURL) + "#" + strconv.Itoa(item.Number)
+		tickets = append(tickets, &model.Ticket{
+			Content:     ticketContent(key, item.Title, item.Body),
+			ExternalKey: key,
+			ExternalURL: item.HTMLURL,
+		})
+	}
+	return tickets, nil
+}
+
+// UpdateEstimate sets the estimate label (e.g. "estimate: 5") of the issue,
+// replacing any estimate label set before so that the issue carries only the
+// agreed estimate. Other labels are kept. The key has the form
+// "owner/name#number" as produced by FetchIssues.
+func (g *github) UpdateEstimate(ctx context.Context, key, estimate string) error {
+	repo, number, ok := strings.Cut(key, "#")
+	if !ok || strings.Count(repo, "/") != 1 {
+		return &keyError{Provider: "github", Key: key}
+	}
+	if _, err := strconv.Atoi(number); err != nil {
+		return &keyError{Provider: "github", Key: key}
+	}
+	labelsURL := g.cfg.BaseURL + "/repos/" + repo + "/issues/" + number + "/labels"
+
+	current, err := g.labels(ctx, labelsURL)
+	if err != nil {
+		return err
+	}
+	labels := []string{g.cfg.EstimateLabelPrefix + estimate}
+	for _, name := range current {
+		if !strings.HasPrefix(name, g.cfg.EstimateLabelPrefix) {
+			labels = append(labels, name)
+		}
+	}
+	// PUT replaces the whole label set in one request.
+	return doJSON(ctx, g.cfg.HTTPClient, "github", http.MethodPut, labelsURL, map[string]any{"labels": labels}, g.authorize)
+}
+
+// labels returns the names of the labels of an issue.
+func (g *github) labels(ctx context.Context, labelsURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, labelsURL+"?per_page=100", nil)
+	if err != nil {
+		return nil, fmt.Errorf("github: build request: %w", err)
+	}
+	g.authorize(req)
+
+	resp, err := g.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github: list labels: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{Provider: "github", Status: resp.StatusCode}
+	}
+
+	var body []struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("github: decode labels: %w", err)
+	}
+	names := make([]string, 0, len(body))
+	for _, l := range body {
+		names = append(names, l.Name)
+	}
+	return names, nil
+}
+
+func (g *github) authorize(req *http.Request) {
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if g.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.cfg.Token)
+	}
+}
+
+// githubRepo extracts "owner/name" from an API repository URL such as
+// https://api.github.com/repos/owner/name.
+func githubRepo(repositoryURL string) string {
+	_, repo, ok := strings.Cut(repositoryURL, "/repos/")
+	if !ok {
+		return repositoryURL
+	}
+	return repo
+}
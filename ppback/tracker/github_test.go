@@ -0,0 +1,144 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestGitHubFetchIssues(t *testing.T) {
+	var gotQuery, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/issues" {
+			http.NotFound(w, r)
+			return
+		}
+		gotQuery = r.URL.Query().Get("q")
+		gotAuth = r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": []map[string]any{
+				{
+					"number":         7,
+					"title":          "Fix flaky test",
+					"body":           "It fails on CI",
+					"html_url":       "https://github.com/acme/app/issues/7",
+					"repository_url": "https://api.github.com/repos/acme/app",
+				},
+				{
+					"number":         8,
+					"title":          "A pull request",
+					"html_url":       "https://github.com/acme/app/pull/8",
+					"repository_url": "https://api.github.com/repos/acme/app",
+					"pull_request":   map[string]any{"url": "x"},
+				},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	p, err := New(Config{Kind: "github", BaseURL: srv.URL, Token: "ghp"})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	tickets, err := p.FetchIssues(context.Background(), "repo:acme/app is:open")
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if gotQuery != "repo:acme/app is:open" {
+		t.Errorf("expected query forwarded, got %q", gotQuery)
+	}
+	if gotAuth != "Bearer ghp" {
+		t.Errorf("expected bearer token, got %q", gotAuth)
+	}
+	if len(tickets) != 1 {
+		t.Fatalf("expected pull requests to be skipped, got %d tickets", len(tickets))
+	}
+	if tickets[0].ExternalKey != "acme/app#7" {
+		t.Errorf("expected key acme/app#7, got %q", tickets[0].ExternalKey)
+	}
+	if tickets[0].ExternalURL != "https://github.com/acme/app/issues/7" {
+		t.Errorf("unexpected URL %q", tickets[0].ExternalURL)
+	}
+	if tickets[0].Content != "# acme/app#7 Fix flaky test\n\nIt fails on CI" {
+		t.Errorf("unexpected content %q", tickets[0].Content)
+	}
+}
+
+func TestGitHubDefaultBaseURL(t *testing.T) {
+	p, err := New(Config{Kind: "github"})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	if got := p.(*github).cfg.BaseURL; got != "https://api.github.com" {
+		t.Errorf("expected default base URL, got %q", got)
+	}
+}
+
+func TestGitHubRepo(t *testing.T) {
+	if got := githubRepo("https://api.github.com/repos/acme/app"); got != "acme/app" {
+		t.Errorf("expected acme/app, got %q", got)
+	}
+}
+
+func TestGitHubUpdateEstimate(t *testing.T) {
+	// The stub keeps the issue's labels like GitHub does: GET lists them and
+	// PUT replaces them.
+	var mu sync.Mutex
+	labels := []string{"bug", "estimate: 3"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/app/issues/7/labels" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var body struct {
+				Labels []string `json:"labels"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			labels = body.Labels
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		out := make([]map[string]string, 0, len(labels))
+		for _, name := range labels {
+			out = append(out, map[string]string{"name": name})
+		}
+		json.NewEncoder(w).Encode(out)
+	}))
+	defer srv.Close()
+
+	p, err := New(Config{Kind: "github", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	for _, estimate := range []string{"5", "8"} {
+		if err := p.UpdateEstimate(context.Background(), "acme/app#7", estimate); err != nil {
+			t.Fatalf("update to %s: %v", estimate, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Strings(labels)
+	if len(labels) != 2 || labels[0] != "bug" || labels[1] != "estimate: 8" {
+		t.Errorf("expected only the latest estimate label next to bug, got %v", labels)
+	}
+}
+
+func TestGitHubUpdateEstimateInvalidKey(t *testing.T) {
+	p, _ := New(Config{Kind: "github", BaseURL: "http://127.0.0.1:0"})
+	for _, key := range []string{"PROJ-1", "acme#7", "acme/app#x"} {
+		if err := p.UpdateEstimate(context.Background(), key, "5"); err == nil || Temporary(err) {
+			t.Errorf("key %q: expected permanent error, got %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"pockerplan/ppback/model"
+)
+
+// jira talks to the Jira REST API v2. The query is a JQL expression.
+type jira struct {
+	cfg Config
+}
+
+func newJira(cfg Config) *jira {
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	return &jira{cfg: cfg}
+}
+
+func (j *jira) Name() string { return "jira" }
+
+type jiraSearchResponse struct {
+	Issues []struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary     string `json:"summary"`
+			Description string `json:"description"`
+		} `json:"fields"`
+	} `json:"issues"`
+}
+
+// FetchIssues runs a JQL search and returns the matching issues as tickets.
+func (j *jira) FetchIssues(ctx context.Context, query string) ([]*model.Ticket, error) {
+	params := url.Values{}
+	params.Set("jql", query)
+	params.Set("maxResults", strconv.Itoa(j.cfg.MaxResults))
+	params.Set("fields", "summary,description")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.cfg.BaseURL+"/rest/api/2/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("jira: build request: %w", err)
+	}
+	j.authorize(req)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := j.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jira: search: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{Provider: "jira", Status: resp.StatusCode}
+	}
+
+	var body jiraSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("jira: decode response: %w", err)
+	}
+
+	tickets := make([]*model.Ticket, 0, len(body.Issues))
+	for _, issue := range body.Issues {
+		tickets = append(tickets, &model.Ticket{
+			Content:     ticketContent(issue.Key, issue.Fields.Summary, issue.Fields.Description),
+			ExternalKey: issue.Key,
+			ExternalURL: j.cfg.BaseURL + "/browse/" + issue.Key,
+		})
+	}
+	return tickets, nil
+}
+
+// UpdateEstimate sets the configured estimate field on the issue. A numeric
+// field such as Story Points gets the estimate as a number; estimates that are
+// not numbers fail without a request. A text field gets the estimate as is.
+func (j *jira) UpdateEstimate(ctx context.Context, key, estimate string) error {
+	if key == "" || strings.ContainsAny(key, "/?#") {
+		return &keyError{Provider: "jira", Key: key}
+	}
+	var value any = estimate
+	if !j.cfg.EstimateText {
+		f, err := strconv.ParseFloat(estimate, 64)
+		if err != nil {
+			return &estimateError{Provider: "jira", Estimate: estimate}
+		}
+		value = f
+	}
+	body := map[string]any{
+		"fields": map[string]any{j.cfg.EstimateField: value},
+	}
+	return doJSON(ctx, j.cfg.HTTPClient, "jira", http.MethodPut, j.cfg.BaseURL+"/rest/api/2/issue/"+url.PathEscape(key), body, j.authorize)
+}
+
+// authorize uses basic auth for Jira Cloud (e-mail + API token) and a bearer
+// token for Jira Data Center personal access tokens.
+func (j *jira) authorize(req *http.Request) {
+	if j.cfg.Token == "" {
+		return
+	}
+	if j.cfg.User != "" {
+		req.SetBasicAuth(j.cfg.User, j.cfg.Token)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+j.cfg.Token)
+}
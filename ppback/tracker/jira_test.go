@@ -0,0 +1,203 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newJiraStub(t *testing.T, handler http.HandlerFunc) IssueProvider {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	p, err := New(Config{Kind: "jira", BaseURL: srv.URL, User: "bot@example.com", Token: "secret"})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	return p
+}
+
+func TestJiraFetchIssues(t *testing.T) {
+	var gotJQL, gotUser, gotPass string
+	p := newJiraStub(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/search" {
+			http.NotFound(w, r)
+			return
+		}
+		gotJQL = r.URL.Query().Get("jql")
+		gotUser, gotPass, _ = r.BasicAuth()
+		json.NewEncoder(w).Encode(map[string]any{
+			"issues": []map[string]any{
+				{"key": "PROJ-1", "fields": map[string]any{"summary": "Login page", "description": "Add SSO"}},
+				{"key": "PROJ-2", "fields": map[string]any{"summary": "Logout", "description": ""}},
+			},
+		})
+	})
+
+	tickets, err := p.FetchIssues(context.Background(), "sprint = 12")
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if gotJQL != "sprint = 12" {
+		t.Errorf("expected jql forwarded, got %q", gotJQL)
+	}
+	if gotUser != "bot@example.com" || gotPass != "secret" {
+		t.Errorf("expected basic auth, got %q/%q", gotUser, gotPass)
+	}
+	if len(tickets) != 2 {
+		t.Fatalf("expected 2 tickets, got %d", len(tickets))
+	}
+	if tickets[0].ExternalKey != "PROJ-1" {
+		t.Errorf("expected key PROJ-1, got %q", tickets[0].ExternalKey)
+	}
+	if !strings.HasSuffix(tickets[0].ExternalURL, "/browse/PROJ-1") {
+		t.Errorf("unexpected URL %q", tickets[0].ExternalURL)
+	}
+	if tickets[0].Content != "# PROJ-1 Login page\n\nAdd SSO" {
+		t.Errorf("unexpected content %q", tickets[0].Content)
+	}
+	if tickets[1].Content != "# PROJ-2 Logout" {
+		t.Errorf("unexpected content %q", tickets[1].Content)
+	}
+}
+
+func TestJiraBearerToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer pat" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"issues":[]}`))
+	}))
+	defer srv.Close()
+
+	p, err := New(Config{Kind: "jira", BaseURL: srv.URL, Token: "pat"})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	if _, err := p.FetchIssues(context.Background(), "project = X"); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+}
+
+func TestJiraErrorStatus(t *testing.T) {
+	p := newJiraStub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	_, err := p.FetchIssues(context.Background(), "bad jql")
+	var se *statusError
+	if !errors.As(err, &se) || se.Status != http.StatusBadRequest {
+		t.Errorf("expected status error 400, got %v", err)
+	}
+}
+
+func TestNewRequiresJiraURL(t *testing.T) {
+	if _, err := New(Config{Kind: "jira"}); err == nil {
+		t.Error("expected error for missing base URL")
+	}
+}
+
+func TestNewUnknownProvider(t *testing.T) {
+	_, err := New(Config{Kind: "trello"})
+	if !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider, got %v", err)
+	}
+}
+
+func TestTicketContentTruncated(t *testing.T) {
+	content := ticketContent("K-1", "Title", strings.Repeat("x", maxContentRunes))
+	if n := len([]rune(content)); n != maxContentRunes {
+		t.Errorf("expected %d runes, got %d", maxContentRunes, n)
+	}
+}
+
+func TestJiraUpdateEstimate(t *testing.T) {
+	var gotMethod, gotPath string
+	var gotBody map[string]map[string]any
+	p := newJiraStub(t, func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	if err := p.UpdateEstimate(context.Background(), "PROJ-1", "8"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if gotMethod != http.MethodPut || gotPath != "/rest/api/2/issue/PROJ-1" {
+		t.Errorf("unexpected request %s %s", gotMethod, gotPath)
+	}
+	if v, ok := gotBody["fields"]["customfield_10016"].(float64); !ok || v != 8 {
+		t.Errorf("expected numeric estimate 8, got %v", gotBody["fields"]["customfield_10016"])
+	}
+
+	// The default field is numeric: cards such as "XL" or "☕" are refused
+	// before they reach Jira, which would answer 400.
+	gotMethod = ""
+	for _, estimate := range []string{"XL", "☕"} {
+		err := p.UpdateEstimate(context.Background(), "PROJ-1", estimate)
+		if err == nil || Temporary(err) || !strings.Contains(err.Error(), "not a number") {
+			t.Errorf("%s: expected permanent not-a-number error, got %v", estimate, err)
+		}
+	}
+	if gotMethod != "" {
+		t.Error("expected no request for a non-numeric estimate")
+	}
+}
+
+func TestJiraUpdateEstimateTextField(t *testing.T) {
+	var gotBody map[string]map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	p, err := New(Config{Kind: "jira", BaseURL: srv.URL, Token: "secret", EstimateField: "customfield_20000", EstimateText: true})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+
+	for _, estimate := range []string{"XL", "8"} {
+		if err := p.UpdateEstimate(context.Background(), "PROJ-1", estimate); err != nil {
+			t.Fatalf("update %s: %v", estimate, err)
+		}
+		if v, ok := gotBody["fields"]["customfield_20000"].(string); !ok || v != estimate {
+			t.Errorf("expected string estimate %s, got %v", estimate, gotBody["fields"]["customfield_20000"])
+		}
+	}
+}
+
+func TestJiraUpdateEstimateInvalidKey(t *testing.T) {
+	p := newJiraStub(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("no request expected for an invalid key")
+	})
+	err := p.UpdateEstimate(context.Background(), "../admin", "5")
+	if err == nil || Temporary(err) {
+		t.Errorf("expected permanent error, got %v", err)
+	}
+}
+
+func TestTemporary(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&statusError{Status: http.StatusServiceUnavailable}, true},
+		{&statusError{Status: http.StatusTooManyRequests}, true},
+		{&statusError{Status: http.StatusForbidden}, false},
+		{&keyError{Key: "x"}, false},
+		{&estimateError{Estimate: "XL"}, false},
+		{errors.New("connection refused"), true},
+		{context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := Temporary(tt.err); got != tt.want {
+			t.Errorf("Temporary(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"pockerplan/ppback/model"
+)
+
+const (
+	defaultMaxResults = 50
+	defaultTimeout    = 15 * time.Second
+
+	// maxContentRunes mirrors the ticket content limit enforced by the hub.
+	maxContentRunes = 10000
+)
+
+var ErrUnknownProvider = errors.New("unknown issue provider")
+
+// IssueProvider fetches issues from an external tracker and converts them into
+// pending tickets carrying the external key and URL.
+type IssueProvider interface {
+	// Name returns the provider identifier, e.g. "jira" or "github".
+	Name() string
+	// FetchIssues returns the issues matching the provider-specific query.
+	FetchIssues(ctx context.Context, query string) ([]*model.Ticket, error)
+	// UpdateEstimate writes the agreed estimate to the issue with the given key.
+	UpdateEstimate(ctx context.Context, key, estimate string) error
+}
+
+// Config describes how to reach an issue tracker.
+type Config struct {
+	Kind       string // "jira" or "github"
+	BaseURL    string
+	User       string // Jira account e-mail; when set, basic auth is used instead of a bearer token
+	Token      string
+	MaxResults int
+	// EstimateField is the Jira field that receives estimates
+	// (default "customfield_10016", the Story Points field on Jira Cloud).
+	EstimateField string
+	// EstimateText marks EstimateField as a text field. Otherwise it is
+	// numeric and estimates that are not numbers, such as "☕" or "XL", are
+	// refused without calling Jira.
+	EstimateText bool
+	// EstimateLabelPrefix prefixes the GitHub label carrying the estimate
+	// (default "estimate: ").
+	EstimateLabelPrefix string
+	HTTPClient          *http.Client
+}
+
+// New creates the issue provider described by cfg.
+func New(cfg Config) (IssueProvider, error) {
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = defaultMaxResults
+	}
+	if cfg.EstimateField == "" {
+		cfg.EstimateField = "customfield_10016"
+	}
+	if cfg.EstimateLabelPrefix == "" {
+		cfg.EstimateLabelPrefix = "estimate: "
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: defaultTimeout}
+	}
+	switch cfg.Kind {
+	case "jira":
+		if cfg.BaseURL == "" {
+			return nil, errors.New("jira: base URL is required")
+		}
+		return newJira(cfg), nil
+	case "github":
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://api.github.com"
+		}
+		return newGitHub(cfg), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, cfg.Kind)
+	}
+}
+
+// ticketContent renders an issue as markdown ticket content.
+func ticketContent(key, title, body string) string {
+	content := "# " + key + " " + title
+	if body = strings.TrimSpace(body); body != "" {
+		content += "\n\n" + body
+	}
+	runes := []rune(content)
+	if len(runes) > maxContentRunes {
+		content = string(runes[:maxContentRunes])
+	}
+	return content
+}
+
+// statusError describes an unexpected HTTP response from a tracker.
+type statusError struct {
+	Provider string
+	Status   int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s: unexpected status %d", e.Provider, e.Status)
+}
+
+// Temporary reports whether a failed tracker call is worth retrying: transport
+// errors, rate limiting and server-side failures are; other client errors are not.
+func Temporary(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.Status == http.StatusTooManyRequests || se.Status >= 500
+	}
+	var ke *keyError
+	var ee *estimateError
+	return !errors.As(err, &ke) && !errors.As(err, &ee)
+}
+
+// keyError reports an external key the provider cannot address.
+type keyError struct {
+	Provider string
+	Key      string
+}
+
+func (e *keyError) Error() string {
+	return fmt.Sprintf("%s: invalid issue key %q", e.Provider, e.Key)
+}
+
+// estimateError reports an estimate the tracker field cannot hold.
+type estimateError struct {
+	Provider string
+	Estimate string
+}
+
+func (e *estimateError) Error() string {
+	return fmt.Sprintf("%s: estimate %q is not a number", e.Provider, e.Estimate)
+}
+
+// doJSON sends body as JSON and expects a 2xx response.
+func doJSON(ctx context.Context, client *http.Client, provider, method, url string, body any, authorize func(*http.Request)) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("%s: encode request: %w", provider, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: build request: %w", provider, err)
+	}
+	authorize(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %s %s: %w", provider, method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{Provider: provider, Status: resp.StatusCode}
+	}
+	return nil
+}
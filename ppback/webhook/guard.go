@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errBlockedAddress is returned when a per-room endpoint resolves to an
+// address that is not on the public internet.
+var errBlockedAddress = errors.New("webhook address is not public")
+
+// errRedirect is returned instead of following a redirect: a redirect could
+// send the signed payload to a host that was never allowed.
+var errRedirect = errors.New("webhook endpoint redirected")
+
+// sharedAddressSpace is 100.64.0.0/10 (RFC 6598), used by carrier-grade NAT
+// and by some cloud metadata services.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddr reports whether a is a unicast address on the public internet.
+func publicAddr(a netip.Addr) bool {
+	a = a.Unmap()
+	return a.IsValid() &&
+		!a.IsLoopback() &&
+		!a.IsPrivate() &&
+		!a.IsLinkLocalUnicast() &&
+		!a.IsLinkLocalMulticast() &&
+		!a.IsInterfaceLocalMulticast() &&
+		!a.IsMulticast() &&
+		!a.IsUnspecified() &&
+		!sharedAddressSpace.Contains(a)
+}
+
+// dialPublicOnly is a net.Dialer Control function. It runs after DNS
+// resolution, so a host name that resolves to a private address is refused
+// too.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errBlockedAddress, address)
+	}
+	if !publicAddr(ap.Addr()) {
+		return fmt.Errorf("%w: %s", errBlockedAddress, ap.Addr())
+	}
+	return nil
+}
+
+func refuseRedirect(*http.Request, []*http.Request) error {
+	return errRedirect
+}
+
+// newRoomClient returns the client for per-room endpoints. Unless
+// allowPrivate is set, it only connects to public addresses. It never uses a
+// proxy from the environment, since the proxy would make the address check
+// apply to the proxy instead of the endpoint.
+func newRoomClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = dialPublicOnly
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport:     transport,
+		Timeout:       defaultTimeout,
+		CheckRedirect: refuseRedirect,
+	}
+}
+
+// hostPattern matches a host name exactly, every subdomain of a domain
+// ("*.example.com") or any host ("*").
+type hostPattern struct {
+	any      bool
+	host     string // without the "*." of a wildcard
+	wildcard bool
+}
+
+func parseHost(s string) (hostPattern, error) {
+	if s == "*" {
+		return hostPattern{any: true}, nil
+	}
+	p := hostPattern{host: strings.ToLower(s)}
+	if rest, ok := strings.CutPrefix(p.host, "*."); ok {
+		p.host, p.wildcard = rest, true
+	}
+	if p.host == "" || strings.ContainsAny(p.host, "*/:") {
+		return hostPattern{}, fmt.Errorf("room webhook host %q: want a host name, *.domain or *", s)
+	}
+	return p, nil
+}
+
+func (p hostPattern) match(host string) bool {
+	if p.any {
+		return true
+	}
+	host = strings.ToLower(host)
+	if p.wildcard {
+		return strings.HasSuffix(host, "."+p.host)
+	}
+	return host == p.host
+}
+
+// ValidateRoomHosts checks values for Config.RoomHosts.
+func ValidateRoomHosts(hosts []string) error {
+	for _, h := range hosts {
+		if _, err := parseHost(h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RoomWebhooks reports whether rooms may set their own webhook.
+func (d *Dispatcher) RoomWebhooks() bool {
+	return len(d.roomHosts) > 0
+}
+
+// RoomEndpointAllowed reports whether a room may send its events to rawURL:
+// an http(s) URL whose host matches Config.RoomHosts and, unless private
+// addresses are allowed, is not a non-public IP address. Host names are
+// checked again against the addresses they resolve to on every delivery.
+func (d *Dispatcher) RoomEndpointAllowed(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	host := u.Hostname()
+	if a, err := netip.ParseAddr(host); err == nil && !d.allowPrivate && !publicAddr(a) {
+		return false
+	}
+	for _, p := range d.roomHosts {
+		if p.match(host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRoomEndpointAllowed(t *testing.T) {
+	d := newTestDispatcher(t, Config{RoomHosts: []string{"hooks.example.com", "*.slack.test"}})
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://hooks.example.com/a", true},
+		{"https://HOOKS.example.com/a", true},
+		{"https://team.slack.test/x", true},
+		{"https://slack.test/x", false},
+		{"https://evil.example.com/a", false},
+		{"ftp://hooks.example.com/a", false},
+		{"https:///a", false},
+	}
+	for _, tt := range tests {
+		if got := d.RoomEndpointAllowed(tt.url); got != tt.want {
+			t.Errorf("RoomEndpointAllowed(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	if newTestDispatcher(t, Config{}).RoomWebhooks() {
+		t.Error("expected per-room webhooks disabled without hosts")
+	}
+
+	open := newTestDispatcher(t, Config{RoomHosts: []string{"*"}})
+	for _, u := range []string{
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://100.64.0.1/hook",
+		"http://0.0.0.0/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+	} {
+		if open.RoomEndpointAllowed(u) {
+			t.Errorf("expected %s to be refused", u)
+		}
+	}
+	if !open.RoomEndpointAllowed("http://93.184.216.34/hook") {
+		t.Error("expected a public address to be allowed")
+	}
+}
+
+func TestRoomLoopbackRefusedAfterResolve(t *testing.T) {
+	srv, ch, calls := newReceiver(t, func(int32) int { return http.StatusOK })
+	// "localhost" passes the host allowlist; the dialer must still refuse the
+	// loopback address it resolves to.
+	url := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	d := newTestDispatcher(t, Config{RoomHosts: []string{"localhost"}})
+
+	retry, err := d.post(delivery{endpoint: Endpoint{URL: url}, typ: EventRoomCreated, body: []byte("{}"), room: true})
+	if !errors.Is(err, errBlockedAddress) {
+		t.Fatalf("expected blocked address, got %v", err)
+	}
+	if retry {
+		t.Error("expected a refused address not to be retried")
+	}
+
+	d.Send(Event{Type: EventRoomCreated, RoomID: "r"}, Endpoint{URL: url})
+	select {
+	case <-ch:
+		t.Fatal("loopback endpoint received a delivery")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if n := atomic.LoadInt32(calls); n != 0 {
+		t.Errorf("expected no requests, got %d", n)
+	}
+}
+
+func TestRedirectNotFollowed(t *testing.T) {
+	target, _, targetCalls := newReceiver(t, func(int32) int { return http.StatusOK })
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	t.Cleanup(redirect.Close)
+	d := newTestDispatcher(t, Config{RoomHosts: []string{"127.0.0.1"}, AllowPrivateRoomHosts: true})
+
+	for _, room := range []bool{false, true} {
+		retry, err := d.post(delivery{endpoint: Endpoint{URL: redirect.URL}, typ: EventRoomCreated, body: []byte("{}"), room: room})
+		if !errors.Is(err, errRedirect) {
+			t.Errorf("room=%v: expected redirect error, got %v", room, err)
+		}
+		if retry {
+			t.Errorf("room=%v: expected a redirect not to be retried", room)
+		}
+	}
+	if n := atomic.LoadInt32(targetCalls); n != 0 {
+		t.Errorf("expected redirect target not to be called, got %d", n)
+	}
+}
+
+func TestValidateRoomHosts(t *testing.T) {
+	if err := ValidateRoomHosts([]string{"*", "example.com", "*.example.com"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, h := range []string{"", "*.", "https://example.com", "example.com:443", "a.*.com"} {
+		if ValidateRoomHosts([]string{h}) == nil {
+			t.Errorf("expected %q to be rejected", h)
+		}
+	}
+}
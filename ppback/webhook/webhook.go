@@ -0,0 +1,345 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Event types delivered to webhook endpoints.
+const (
+	EventRoomCreated     = "room.created"
+	EventUserJoined      = "user.joined"
+	EventUserLeft        = "user.left"
+	EventVotingStarted   = "voting.started"
+	EventVotesRevealed   = "votes.revealed"
+	EventTicketEstimated = "ticket.estimated"
+)
+
+// Headers set on every delivery.
+const (
+	HeaderEvent     = "X-Pockerplan-Event"
+	HeaderDelivery  = "X-Pockerplan-Delivery"
+	HeaderSignature = "X-Pockerplan-Signature"
+)
+
+const (
+	defaultWorkers     = 4
+	defaultQueueSize   = 1024
+	defaultMaxAttempts = 5
+	defaultBackoff     = time.Second
+	maxBackoff         = 30 * time.Second
+	defaultTimeout     = 10 * time.Second
+)
+
+// Event is the JSON payload POSTed to webhook endpoints.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	RoomID    string    `json:"roomId"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data,omitempty"`
+}
+
+// Endpoint is a webhook receiver. When Secret is set, each delivery carries an
+// HMAC-SHA256 signature of the body in the X-Pockerplan-Signature header.
+type Endpoint struct {
+	URL    string
+	Secret string
+}
+
+// Config tunes the dispatcher. Zero values select the defaults.
+type Config struct {
+	Endpoints   []Endpoint // server-wide endpoints receiving events of every room
+	Workers     int
+	QueueSize   int // deliveries waiting across all workers
+	MaxAttempts int
+	Backoff     time.Duration // delay before the first retry; doubles on each attempt
+	HTTPClient  *http.Client  // for server-wide endpoints
+
+	// RoomHosts lists the hosts rooms may send their events to: a host name,
+	// "*.example.com" for its subdomains or "*" for any. Empty disables
+	// per-room webhooks.
+	RoomHosts []string
+	// AllowPrivateRoomHosts lets per-room webhooks reach loopback, private
+	// and link-local addresses, e.g. on an intranet.
+	AllowPrivateRoomHosts bool
+}
+
+type delivery struct {
+	endpoint Endpoint
+	eventID  string
+	typ      string
+	body     []byte
+	room     bool
+}
+
+// Dispatcher delivers events asynchronously. Send never blocks: when the queue
+// is full the event is dropped and logged.
+//
+// All events of a room go to the same worker, which delivers them one at a
+// time, retries included, so each endpoint receives a room's events in the
+// order they were sent. Events of different rooms are not ordered.
+type Dispatcher struct {
+	endpoints    []Endpoint
+	client       *http.Client
+	roomClient   *http.Client
+	roomHosts    []hostPattern
+	allowPrivate bool
+	maxAttempts  int
+	backoff      time.Duration
+	logger       zerolog.Logger
+
+	queues []chan delivery // one per worker
+	done   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// New creates a dispatcher and starts its workers.
+func New(cfg Config, logger zerolog.Logger) *Dispatcher {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultBackoff
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: defaultTimeout, CheckRedirect: refuseRedirect}
+	}
+
+	d := &Dispatcher{
+		endpoints:    cfg.Endpoints,
+		client:       cfg.HTTPClient,
+		roomClient:   newRoomClient(cfg.AllowPrivateRoomHosts),
+		allowPrivate: cfg.AllowPrivateRoomHosts,
+		maxAttempts:  cfg.MaxAttempts,
+		backoff:      cfg.Backoff,
+		logger:       logger,
+		queues:       make([]chan delivery, cfg.Workers),
+		done:         make(chan struct{}),
+	}
+	for _, h := range cfg.RoomHosts {
+		p, err := parseHost(h)
+		if err != nil {
+			logger.Error().Err(err).Msg("ignoring room webhook host")
+			continue
+		}
+		d.roomHosts = append(d.roomHosts, p)
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan delivery, max(cfg.QueueSize/cfg.Workers, 1))
+		d.wg.Add(1)
+		go d.worker(d.queues[i])
+	}
+	return d
+}
+
+// Send queues the event for the server-wide endpoints and any extra
+// (per-room) endpoints. Extra endpoints that RoomEndpointAllowed refuses are
+// skipped. ID and Timestamp are filled in when empty.
+func (d *Dispatcher) Send(ev Event, extra ...Endpoint) {
+	var rooms []Endpoint
+	for _, ep := range extra {
+		if ep.URL == "" {
+			continue
+		}
+		if !d.RoomEndpointAllowed(ep.URL) {
+			d.logger.Warn().Str("event", ev.Type).Str("url", ep.URL).Msg("room webhook not allowed, skipping")
+			continue
+		}
+		rooms = append(rooms, ep)
+	}
+	if len(d.endpoints) == 0 && len(rooms) == 0 {
+		return
+	}
+	if ev.ID == "" {
+		ev.ID = uuid.New().String()
+	}
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now().UTC()
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		d.logger.Error().Err(err).Str("event", ev.Type).Msg("marshal webhook event")
+		return
+	}
+
+	queued := make([]delivery, 0, len(d.endpoints)+len(rooms))
+	for _, ep := range d.endpoints {
+		queued = append(queued, delivery{endpoint: ep, eventID: ev.ID, typ: ev.Type, body: body})
+	}
+	for _, ep := range rooms {
+		queued = append(queued, delivery{endpoint: ep, eventID: ev.ID, typ: ev.Type, body: body, room: true})
+	}
+	queue := d.queueFor(ev.RoomID)
+	for _, dl := range queued {
+		if dl.endpoint.URL == "" {
+			continue
+		}
+		select {
+		case <-d.done:
+			return
+		default:
+		}
+		select {
+		case queue <- dl:
+		default:
+			d.logger.Warn().
+				Str("event", ev.Type).
+				Str("url", dl.endpoint.URL).
+				Msg("webhook queue full, dropping event")
+		}
+	}
+}
+
+// Close stops accepting events and waits for in-flight deliveries until ctx
+// expires. Queued deliveries that have not started are discarded.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.once.Do(func() { close(d.done) })
+	finished := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// queueFor returns the queue of the worker that delivers the events of a room.
+func (d *Dispatcher) queueFor(roomID string) chan delivery {
+	h := fnv.New32a()
+	h.Write([]byte(roomID))
+	return d.queues[h.Sum32()%uint32(len(d.queues))]
+}
+
+func (d *Dispatcher) worker(queue <-chan delivery) {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.done:
+			return
+		case dl := <-queue:
+			d.deliver(dl)
+		}
+	}
+}
+
+// deliver POSTs the event, retrying transport errors, 429 and 5xx responses
+// with exponential backoff. Refused addresses and redirects are not retried.
+func (d *Dispatcher) deliver(dl delivery) {
+	backoff := d.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(dl)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= d.maxAttempts {
+			d.logger.Warn().Err(err).
+				Str("event", dl.typ).
+				Str("url", dl.endpoint.URL).
+				Int("attempts", attempt).
+				Msg("webhook delivery failed")
+			return
+		}
+		select {
+		case <-d.done:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (d *Dispatcher) post(dl delivery) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, dl.endpoint.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pockerplan-webhook")
+	req.Header.Set(HeaderEvent, dl.typ)
+	req.Header.Set(HeaderDelivery, dl.eventID)
+	if dl.endpoint.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(dl.endpoint.Secret, dl.body))
+	}
+
+	client := d.client
+	if dl.room {
+		client = d.roomClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return !errors.Is(err, errBlockedAddress) && !errors.Is(err, errRedirect), err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by the
+// hex-encoded HMAC-SHA256 of body keyed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid signature of body.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// UserData is the payload of user.joined and user.left events.
+type UserData struct {
+	UserID string `json:"userId"`
+	Name   string `json:"name,omitempty"`
+}
+
+// RoomData is the payload of room.created events.
+type RoomData struct {
+	Scale     string `json:"scale"`
+	CreatorID string `json:"creatorId"`
+}
+
+// TicketData is the payload of voting.started, votes.revealed and
+// ticket.estimated events.
+type TicketData struct {
+	TicketID    string     `json:"ticketId"`
+	ExternalKey string     `json:"externalKey,omitempty"`
+	Votes       []VoteData `json:"votes,omitempty"`
+	Estimate    string     `json:"estimate,omitempty"`
+}
+
+// VoteData is a single revealed vote.
+type VoteData struct {
+	UserID string `json:"userId"`
+	Value  string `json:"value"`
+}
@@ -0,0 +1,194 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, status func(n int32) int) (*httptest.Server, <-chan received, *int32) {
+	t.Helper()
+	ch := make(chan received, 16)
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		code := status(n)
+		if code == http.StatusOK {
+			ch <- received{header: r.Header.Clone(), body: body}
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, ch, &calls
+}
+
+func newTestDispatcher(t *testing.T, cfg Config) *Dispatcher {
+	t.Helper()
+	if cfg.Backoff == 0 {
+		cfg.Backoff = time.Millisecond
+	}
+	d := New(cfg, zerolog.Nop())
+	t.Cleanup(func() { d.Close(context.Background()) })
+	return d
+}
+
+func TestSendSigned(t *testing.T) {
+	srv, ch, _ := newReceiver(t, func(int32) int { return http.StatusOK })
+	d := newTestDispatcher(t, Config{Endpoints: []Endpoint{{URL: srv.URL, Secret: "s3cret"}}})
+
+	d.Send(Event{Type: EventVotesRevealed, RoomID: "room-1", Data: map[string]string{"ticketId": "t1"}})
+
+	select {
+	case got := <-ch:
+		if got.header.Get(HeaderEvent) != EventVotesRevealed {
+			t.Errorf("unexpected event header %q", got.header.Get(HeaderEvent))
+		}
+		if !Verify("s3cret", got.body, got.header.Get(HeaderSignature)) {
+			t.Error("signature does not verify")
+		}
+		var ev Event
+		if err := json.Unmarshal(got.body, &ev); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if ev.ID == "" || ev.Timestamp.IsZero() || ev.RoomID != "room-1" {
+			t.Errorf("unexpected event %+v", ev)
+		}
+		if got.header.Get(HeaderDelivery) != ev.ID {
+			t.Errorf("delivery header %q does not match event ID %q", got.header.Get(HeaderDelivery), ev.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+}
+
+func TestSendUnsignedWithoutSecret(t *testing.T) {
+	srv, ch, _ := newReceiver(t, func(int32) int { return http.StatusOK })
+	d := newTestDispatcher(t, Config{RoomHosts: []string{"127.0.0.1"}, AllowPrivateRoomHosts: true})
+
+	d.Send(Event{Type: EventRoomCreated, RoomID: "r"}, Endpoint{URL: srv.URL})
+
+	select {
+	case got := <-ch:
+		if got.header.Get(HeaderSignature) != "" {
+			t.Error("expected no signature without a secret")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	srv, ch, calls := newReceiver(t, func(n int32) int {
+		if n < 3 {
+			return http.StatusBadGateway
+		}
+		return http.StatusOK
+	})
+	d := newTestDispatcher(t, Config{Endpoints: []Endpoint{{URL: srv.URL}}})
+
+	d.Send(Event{Type: EventUserJoined, RoomID: "r"})
+
+	select {
+	case <-ch:
+		if n := atomic.LoadInt32(calls); n != 3 {
+			t.Errorf("expected 3 attempts, got %d", n)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for retried delivery")
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	srv, _, calls := newReceiver(t, func(int32) int { return http.StatusBadRequest })
+	d := newTestDispatcher(t, Config{Endpoints: []Endpoint{{URL: srv.URL}}})
+
+	d.Send(Event{Type: EventUserLeft, RoomID: "r"})
+	time.Sleep(100 * time.Millisecond)
+
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Errorf("expected a single attempt, got %d", n)
+	}
+}
+
+func TestRoomEventsKeepOrder(t *testing.T) {
+	srv, ch, _ := newReceiver(t, func(n int32) int {
+		if n%3 == 0 {
+			return http.StatusServiceUnavailable // retried before the next event
+		}
+		return http.StatusOK
+	})
+	d := newTestDispatcher(t, Config{Endpoints: []Endpoint{{URL: srv.URL}}, Workers: 4})
+
+	const events = 10
+	for i := range events {
+		d.Send(Event{Type: EventUserJoined, RoomID: "r", Data: i})
+	}
+	for want := range events {
+		select {
+		case got := <-ch:
+			var ev Event
+			if err := json.Unmarshal(got.body, &ev); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if ev.Data != float64(want) {
+				t.Fatalf("expected event %d, got %v", want, ev.Data)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for event %d", want)
+		}
+	}
+}
+
+func TestSendNeverBlocks(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+
+	d := New(Config{Endpoints: []Endpoint{{URL: srv.URL}}, Workers: 1, QueueSize: 1}, zerolog.Nop())
+	defer func() {
+		close(block)
+		d.Close(context.Background())
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			d.Send(Event{Type: EventVotingStarted, RoomID: "r"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Send blocked on a full queue")
+	}
+}
+
+func TestSendAfterClose(t *testing.T) {
+	srv, _, calls := newReceiver(t, func(int32) int { return http.StatusOK })
+	d := New(Config{Endpoints: []Endpoint{{URL: srv.URL}}}, zerolog.Nop())
+	if err := d.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	d.Send(Event{Type: EventRoomCreated, RoomID: "r"})
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(calls); n != 0 {
+		t.Errorf("expected no deliveries after close, got %d", n)
+	}
+}
@@ -2,6 +2,7 @@ import { BrowserRouter, Link, Route, Routes } from "react-router-dom";
 import ErrorBoundary from "./components/ErrorBoundary";
 import { ThemeProvider } from "./context/ThemeContext";
 import { UserProvider } from "./context/UserContext";
+import { basePath } from "./lib/basePath";
 import { HomePage } from "./pages/HomePage";
 import { JoinPage } from "./pages/JoinPage";
 import { RoomPage } from "./pages/RoomPage";
@@ -25,7 +26,7 @@ function App() {
   return (
     <ThemeProvider>
       <UserProvider>
-        <BrowserRouter>
+        <BrowserRouter basename={basePath || "/"}>
           <ErrorBoundary>
             <Routes>
               <Route path="/" element={<HomePage />} />
@@ -1,12 +1,11 @@
 import { Centrifuge } from "centrifuge";
+import { websocketUrl } from "../lib/basePath";
 
 let client: Centrifuge | null = null;
 
 export function getCentrifuge(): Centrifuge {
   if (!client) {
-    const protocol = window.location.protocol === "https:" ? "wss:" : "ws:";
-    const url = `${protocol}//${window.location.host}/connection/websocket`;
-    client = new Centrifuge(url);
+    client = new Centrifuge(websocketUrl());
     client.connect();
 
     // Ensure connection is closed when page unloads
@@ -19,6 +19,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     expect(screen.getByRole("button", { name: "Reveal Votes" })).toBeDisabled();
@@ -37,6 +38,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     expect(screen.getByRole("button", { name: "Reveal Votes" })).toBeEnabled();
@@ -55,6 +57,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     expect(screen.getByRole("button", { name: "Reset Votes" })).toBeDisabled();
@@ -73,6 +76,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     expect(screen.getByRole("button", { name: "Next Ticket" })).toBeDisabled();
@@ -91,6 +95,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     expect(screen.getByRole("button", { name: "Prev Ticket" })).toBeDisabled();
@@ -110,6 +115,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     await userEvent.click(screen.getByRole("button", { name: "Reveal Votes" }));
@@ -130,6 +136,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     await userEvent.click(screen.getByRole("button", { name: "Reset Votes" }));
@@ -150,6 +157,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={onNextTicket}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     await userEvent.click(screen.getByRole("button", { name: "Next Ticket" }));
@@ -170,6 +178,7 @@ describe("AdminControls", () => {
         onPrevTicket={onPrevTicket}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     await userEvent.click(screen.getByRole("button", { name: "Prev Ticket" }));
@@ -189,6 +198,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     expect(
@@ -209,6 +219,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     expect(
@@ -229,6 +240,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     expect(
@@ -249,6 +261,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     expect(
@@ -269,6 +282,7 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={noop}
+        onNudge={noop}
       />,
     );
     expect(
@@ -290,9 +304,48 @@ describe("AdminControls", () => {
         onPrevTicket={noop}
         onNextTicket={noop}
         onStartFreeVote={onStartFreeVote}
+        onNudge={noop}
       />,
     );
     await userEvent.click(screen.getByRole("button", { name: "Start Voting" }));
     expect(onStartFreeVote).toHaveBeenCalledOnce();
   });
+
+  it("enables nudge only while voting", async () => {
+    const onNudge = vi.fn();
+    const { rerender } = render(
+      <AdminControls
+        roomState="revealed"
+        ticketsEnabled={false}
+        hasPrevTicket={false}
+        hasNextTicket={false}
+        hasTickets={true}
+        onReveal={noop}
+        onReset={noop}
+        onPrevTicket={noop}
+        onNextTicket={noop}
+        onStartFreeVote={noop}
+        onNudge={onNudge}
+      />,
+    );
+    expect(screen.getByRole("button", { name: "Nudge" })).toBeDisabled();
+
+    rerender(
+      <AdminControls
+        roomState="voting"
+        ticketsEnabled={false}
+        hasPrevTicket={false}
+        hasNextTicket={false}
+        hasTickets={true}
+        onReveal={noop}
+        onReset={noop}
+        onPrevTicket={noop}
+        onNextTicket={noop}
+        onStartFreeVote={noop}
+        onNudge={onNudge}
+      />,
+    );
+    await userEvent.click(screen.getByRole("button", { name: "Nudge" }));
+    expect(onNudge).toHaveBeenCalledOnce();
+  });
 });
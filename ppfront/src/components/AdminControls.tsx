@@ -11,6 +11,7 @@ interface AdminControlsProps {
   onPrevTicket: () => void;
   onNextTicket: () => void;
   onStartFreeVote: () => void;
+  onNudge: () => void;
 }
 
 export function AdminControls({
@@ -24,6 +25,7 @@ export function AdminControls({
   onPrevTicket,
   onNextTicket,
   onStartFreeVote,
+  onNudge,
 }: AdminControlsProps) {
   return (
     <div className="admin-controls">
@@ -44,6 +46,14 @@ export function AdminControls({
         <button type="button" onClick={onReset} disabled={roomState === "idle"}>
           Reset Votes
         </button>
+        <button
+          type="button"
+          onClick={onNudge}
+          disabled={roomState !== "voting"}
+          title="Remind everyone who has not voted yet"
+        >
+          Nudge
+        </button>
         {ticketsEnabled && (
           <div className="ticket-nav-buttons">
             <button
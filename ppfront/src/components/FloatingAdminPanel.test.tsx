@@ -18,6 +18,7 @@ const defaultProps = {
   onNextTicket: vi.fn(),
   onAddTicket: vi.fn().mockResolvedValue(undefined),
   onStartFreeVote: vi.fn(),
+  onNudge: vi.fn(),
 };
 
 describe("FloatingAdminPanel", () => {
@@ -17,6 +17,7 @@ interface FloatingAdminPanelProps {
   onNextTicket: () => void;
   onAddTicket: (content: string) => Promise<unknown>;
   onStartFreeVote: () => void;
+  onNudge: () => void;
 }
 
 export function FloatingAdminPanel({
@@ -32,6 +33,7 @@ export function FloatingAdminPanel({
   onNextTicket,
   onAddTicket,
   onStartFreeVote,
+  onNudge,
 }: FloatingAdminPanelProps) {
   const [collapsed, setCollapsed] = useState(false);
 
@@ -59,6 +61,7 @@ export function FloatingAdminPanel({
             onPrevTicket={onPrevTicket}
             onNextTicket={onNextTicket}
             onStartFreeVote={onStartFreeVote}
+            onNudge={onNudge}
           />
           {ticketsEnabled && <TicketForm onAdd={onAddTicket} />}
         </div>
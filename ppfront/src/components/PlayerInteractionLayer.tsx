@@ -20,13 +20,19 @@ export function PlayerInteractionLayer({
 }: PlayerInteractionLayerProps) {
   const [activeThrows, setActiveThrows] = useState<ActiveThrow[]>([]);
   const throwIdRef = useRef(0);
+  // The event list keeps recent history, so skip events already animated.
+  const seenRef = useRef(new Set<string>());
 
   useEffect(() => {
     if (!events || events.length === 0) return;
     const paperThrows = events.filter(
-      (e) => e.type === "player_interaction" && e.action === "paper_throw",
+      (e) =>
+        e.type === "player_interaction" &&
+        e.action === "paper_throw" &&
+        !seenRef.current.has(e.id),
     );
     if (paperThrows.length === 0) return;
+    for (const ev of paperThrows) seenRef.current.add(ev.id);
 
     const newThrows: ActiveThrow[] = [];
     for (const ev of paperThrows) {
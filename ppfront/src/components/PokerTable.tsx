@@ -42,15 +42,20 @@ export function PokerTable({
     { id: string; fromX: number; fromY: number; dx: number; dy: number }[]
   >([]);
   const flyIdRef = useRef(0);
+  const seenEventsRef = useRef(new Set<string>());
 
   // Derive campfire data from server state.
   const aliveTrees = (campfire?.trees ?? []).filter((t) => !t.burnedAt);
   const fireLevel = campfire?.fireLevel ?? 0;
 
   // React to incoming campfire events by enqueueing flying-tree animations.
+  // The event list keeps recent history, so skip events already animated.
   useEffect(() => {
-    const feedFireEvents = campfireEvents.filter((e) => e.action === "feed_fire");
+    const feedFireEvents = campfireEvents.filter(
+      (e) => e.action === "feed_fire" && !seenEventsRef.current.has(e.id),
+    );
     if (feedFireEvents.length === 0) return;
+    for (const ev of feedFireEvents) seenEventsRef.current.add(ev.id);
 
     setFlyingTrees((prev) => {
       const next = [...prev];
@@ -67,7 +72,7 @@ export function PokerTable({
       }
       return next;
     });
-  // Only re-run when the campfireEvents reference changes (new snapshot).
+  // Only re-run when the campfireEvents reference changes (new event).
   // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [campfireEvents]);
 
@@ -101,6 +106,9 @@ export function PokerTable({
     if (user.thinking) {
       return <span className="poker-table-badge poker-table-badge--thinking">💭</span>;
     }
+    if (user.connected && user.away) {
+      return <span className="poker-table-badge poker-table-badge--away">💤</span>;
+    }
     return <span className="poker-table-badge poker-table-badge--empty">🃏</span>;
   }
 
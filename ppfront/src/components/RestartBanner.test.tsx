@@ -0,0 +1,49 @@
+import { act, render, screen } from "@testing-library/react";
+import { afterEach, beforeEach, describe, expect, it, vi } from "vitest";
+import { RestartBanner } from "./RestartBanner";
+
+describe("RestartBanner", () => {
+  beforeEach(() => {
+    vi.useFakeTimers();
+    vi.setSystemTime(new Date("2026-01-01T12:00:00Z"));
+  });
+
+  afterEach(() => {
+    vi.useRealTimers();
+  });
+
+  it("counts down to the disconnect, then to the expected return", () => {
+    render(
+      <RestartBanner
+        restart={{
+          disconnectAt: "2026-01-01T12:00:02Z",
+          backAt: "2026-01-01T12:00:32Z",
+        }}
+      />,
+    );
+    expect(screen.getByRole("status")).toHaveTextContent(
+      "The server restarts in 2s.",
+    );
+
+    act(() => {
+      vi.advanceTimersByTime(2000);
+    });
+    expect(screen.getByRole("status")).toHaveTextContent(
+      "The server is restarting, back in about 30s.",
+    );
+  });
+
+  it("omits the estimate once it has passed", () => {
+    render(
+      <RestartBanner
+        restart={{
+          disconnectAt: "2026-01-01T11:59:00Z",
+          backAt: "2026-01-01T11:59:30Z",
+        }}
+      />,
+    );
+    expect(screen.getByRole("status")).toHaveTextContent(
+      "The server is restarting. Reconnecting…",
+    );
+  });
+});
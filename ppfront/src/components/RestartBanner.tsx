@@ -0,0 +1,40 @@
+import { useEffect, useState } from "react";
+import type { ServerRestartingPayload } from "../types";
+
+interface RestartBannerProps {
+  restart: ServerRestartingPayload;
+}
+
+function secondsUntil(iso: string, now: number): number {
+  return Math.ceil((Date.parse(iso) - now) / 1000);
+}
+
+// Counts down to the announced disconnect, then to the time the server is
+// expected back. The connection reconnects on its own.
+export function RestartBanner({ restart }: RestartBannerProps) {
+  const [now, setNow] = useState(Date.now);
+
+  useEffect(() => {
+    const id = setInterval(() => setNow(Date.now()), 1000);
+    return () => clearInterval(id);
+  }, []);
+
+  const untilDisconnect = secondsUntil(restart.disconnectAt, now);
+  const untilBack = secondsUntil(restart.backAt, now);
+  let message: string;
+  if (untilDisconnect > 0) {
+    message =
+      `The server restarts in ${untilDisconnect}s. You will be reconnected automatically.`;
+  } else if (untilBack > 0) {
+    message =
+      `The server is restarting, back in about ${untilBack}s. Reconnecting…`;
+  } else {
+    message = "The server is restarting. Reconnecting…";
+  }
+
+  return (
+    <div className="restart-banner" role="status">
+      {message}
+    </div>
+  );
+}
@@ -1,4 +1,4 @@
-import { scales } from "../data/scales";
+import { useScales } from "../hooks/useScales";
 
 interface ScalePickerProps {
   selected: string;
@@ -6,6 +6,7 @@ interface ScalePickerProps {
 }
 
 export function ScalePicker({ selected, onSelect }: ScalePickerProps) {
+  const scales = useScales();
   return (
     <div className="scale-picker">
       <label htmlFor="scale-select">Estimation Scale</label>
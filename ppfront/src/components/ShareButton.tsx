@@ -1,4 +1,5 @@
 import { useEffect, useRef, useState } from "react";
+import { appUrl } from "../lib/basePath";
 
 interface ShareButtonProps {
   roomId: string;
@@ -15,7 +16,7 @@ export function ShareButton({ roomId }: ShareButtonProps) {
   }, []);
 
   const handleClick = async () => {
-    const url = `${window.location.origin}/room/${roomId}/join`;
+    const url = appUrl(`/room/${roomId}/join`);
     try {
       await navigator.clipboard.writeText(url);
       setCopied(true);
@@ -47,4 +47,13 @@ describe("UserList", () => {
     const disconnected = container.querySelectorAll(".disconnected");
     expect(disconnected).toHaveLength(1);
   });
+
+  it("marks away users", () => {
+    const withAway: User[] = [{ ...users[1], away: true }, users[2]];
+    const { container } = render(
+      <UserList users={withAway} votes={[]} revealed={false} />,
+    );
+    expect(container.querySelectorAll(".away")).toHaveLength(1);
+    expect(screen.getByText("Away")).toBeInTheDocument();
+  });
 });
@@ -24,11 +24,14 @@ export function UserList({ users, votes, revealed }: UserListProps) {
           return (
             <li
               key={user.id}
-              className={`user-item${user.connected ? "" : " disconnected"}`}
+              className={`user-item${user.connected ? (user.away ? " away" : "") : " disconnected"}`}
             >
               <span className="user-avatar">{getEmoji(user.avatarId)}</span>
               <span className="user-name">{user.name}</span>
               {user.isAdmin && <span className="user-badge">Admin</span>}
+              {user.connected && user.away && (
+                <span className="user-badge user-badge--away">Away</span>
+              )}
               <span className="user-vote-status">
                 {hasVoted
                   ? revealed && vote.value
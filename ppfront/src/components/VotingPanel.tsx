@@ -1,4 +1,4 @@
-import { scales } from "../data/scales";
+import { useScales } from "../hooks/useScales";
 import { VoteCard } from "./VoteCard";
 
 interface VotingPanelProps {
@@ -16,6 +16,7 @@ export function VotingPanel({
   onVote,
   onInteraction,
 }: VotingPanelProps) {
+  const scales = useScales();
   const scale = scales.find((s) => s.id === scaleId);
   const values = scale?.values ?? [];
 
@@ -0,0 +1,51 @@
+import { renderHook } from "@testing-library/react";
+import { afterEach, beforeEach, describe, expect, it, vi } from "vitest";
+import { useActivityHeartbeat } from "./useActivityHeartbeat";
+
+function fireKey() {
+  window.dispatchEvent(new KeyboardEvent("keydown", { key: "a" }));
+}
+
+describe("useActivityHeartbeat", () => {
+  beforeEach(() => {
+    vi.useFakeTimers();
+  });
+
+  afterEach(() => {
+    vi.useRealTimers();
+  });
+
+  it("sends a heartbeat on the first interaction", () => {
+    const heartbeat = vi.fn().mockResolvedValue(undefined);
+    renderHook(() => useActivityHeartbeat(heartbeat));
+
+    fireKey();
+
+    expect(heartbeat).toHaveBeenCalledTimes(1);
+  });
+
+  it("throttles heartbeats", () => {
+    const heartbeat = vi.fn().mockResolvedValue(undefined);
+    renderHook(() => useActivityHeartbeat(heartbeat));
+
+    fireKey();
+    fireKey();
+    vi.advanceTimersByTime(10_000);
+    fireKey();
+    expect(heartbeat).toHaveBeenCalledTimes(1);
+
+    vi.advanceTimersByTime(30_000);
+    fireKey();
+    expect(heartbeat).toHaveBeenCalledTimes(2);
+  });
+
+  it("stops listening on unmount", () => {
+    const heartbeat = vi.fn().mockResolvedValue(undefined);
+    const { unmount } = renderHook(() => useActivityHeartbeat(heartbeat));
+
+    unmount();
+    fireKey();
+
+    expect(heartbeat).not.toHaveBeenCalled();
+  });
+});
@@ -0,0 +1,34 @@
+import { useEffect } from "react";
+
+// Well below the server idle timeout, so an active user is never shown away.
+const HEARTBEAT_INTERVAL_MS = 30_000;
+
+const ACTIVITY_EVENTS = ["pointerdown", "pointermove", "keydown", "wheel"] as const;
+
+/**
+ * Reports user activity to the server so the user is not shown as away. The
+ * first interaction after a quiet period is sent immediately, later ones at
+ * most once per interval.
+ */
+export function useActivityHeartbeat(heartbeat: () => Promise<void>) {
+  useEffect(() => {
+    let lastSent = 0;
+    const onActivity = () => {
+      const now = Date.now();
+      if (now - lastSent < HEARTBEAT_INTERVAL_MS) return;
+      lastSent = now;
+      heartbeat().catch((err: unknown) => {
+        if (import.meta.env.DEV) console.debug("heartbeat failed:", err);
+      });
+    };
+
+    for (const type of ACTIVITY_EVENTS) {
+      window.addEventListener(type, onActivity, { passive: true });
+    }
+    return () => {
+      for (const type of ACTIVITY_EVENTS) {
+        window.removeEventListener(type, onActivity);
+      }
+    };
+  }, [heartbeat]);
+}
@@ -1,19 +1,25 @@
-import type { Subscription } from "centrifuge";
+import { SubscriptionState, type Subscription } from "centrifuge";
 import { useCallback, useEffect, useRef, useState } from "react";
 import { getCentrifuge } from "../api/centrifuge";
-import { isRoomSnapshot } from "../lib/validate";
+import { applyRoomDelta } from "../lib/roomDelta";
+import { isRoomEvent, isRoomSnapshot, isRoomUpdate } from "../lib/validate";
+import { DisconnectCode, ErrorCode } from "../types";
 import type {
   AddTicketRequest,
   AddTicketResponse,
   AdminActionRequest,
-  JoinRoomResponse,
+  NudgeRequest,
+  NudgeResponse,
   RemoveVoteRequest,
+  RoomEvent,
   RoomSnapshot,
+  RoomUpdate,
+  ServerRestartingPayload,
   SetTicketRequest,
   SubmitVoteRequest,
   UpdateRoomNameRequest,
 } from "../types";
-import { loadRoomInfo, loadUser } from "./useUser";
+import { loadRoomInfo } from "./useUser";
 
 export type RoomErrorType =
   | "not_found"
@@ -26,8 +32,18 @@ export interface RoomError {
   message: string;
 }
 
+// How many recent room events are kept for animations.
+const MAX_EVENTS = 50;
+
 export interface UseRoomResult {
   roomState: RoomSnapshot | null;
+  /** Recent one-off interactions, oldest first; consumers track seen IDs. */
+  events: RoomEvent[];
+  /** The latest nudge sent to this user, until dismissed. */
+  nudge: RoomEvent | null;
+  dismissNudge: () => void;
+  /** The announced server restart, until the room is subscribed again. */
+  restart: ServerRestartingPayload | null;
   connected: boolean;
   error: RoomError | null;
   loading: boolean;
@@ -42,7 +58,9 @@ export interface UseRoomResult {
   prevTicket: () => Promise<void>;
   setTicket: (ticketId: string) => Promise<void>;
   startFreeVote: () => Promise<void>;
+  nudgeUsers: (targetUserId?: string) => Promise<string[]>;
   setThinking: (active: boolean) => Promise<void>;
+  heartbeat: () => Promise<void>;
   interactPlayer: (action: string, targetUserId: string) => Promise<void>;
   themeInteract: (action: string, data: unknown) => Promise<void>;
 }
@@ -55,6 +73,7 @@ function classifyError(
   if (
     code === 403 ||
     code === 404 ||
+    code === ErrorCode.RoomNotFound ||
     msg.includes("not found") ||
     msg.includes("permission denied")
   ) {
@@ -78,6 +97,11 @@ function classifyError(
 
 export function useRoom(roomId: string | undefined): UseRoomResult {
   const [roomState, setRoomState] = useState<RoomSnapshot | null>(null);
+  const [events, setEvents] = useState<RoomEvent[]>([]);
+  const [nudge, setNudge] = useState<RoomEvent | null>(null);
+  const [restart, setRestart] = useState<ServerRestartingPayload | null>(
+    null,
+  );
   const [connected, setConnected] = useState(false);
   const [error, setError] = useState<RoomError | null>(null);
   const subRef = useRef<Subscription | null>(null);
@@ -89,9 +113,80 @@ export function useRoom(roomId: string | undefined): UseRoomResult {
 
     const client = getCentrifuge();
     const channel = `room:${roomId}`;
-    const sub = client.newSubscription(channel);
+    // Subscribing with our user ID re-registers this connection for the user,
+    // so a reconnect shows us online again. Missed updates are recovered from
+    // channel history; otherwise the subscribe reply carries a fresh snapshot.
+    const info = loadRoomInfo(roomId);
+    const sub = client.newSubscription(channel, {
+      data: info?.userId ? { userId: info.userId } : undefined,
+    });
     let subscribed = false;
 
+    // Nudges arrive on our private channel. The server only lets a connection
+    // registered for the user subscribe, so it is subscribed after the room.
+    const userSub = info?.userId
+      ? client.newSubscription(`user:${info.userId}`)
+      : null;
+    userSub?.on("publication", (ctx) => {
+      if (isRoomEvent(ctx.data) && ctx.data.type === "nudge") {
+        setNudge(ctx.data);
+      }
+    });
+
+    // The channel carries revisioned updates: deltas apply on top of the
+    // previous revision; a missed revision triggers a refetch via get_room.
+    let current: RoomSnapshot | null = null;
+    let pending: RoomUpdate[] = [];
+    let resyncing = false;
+
+    const show = (snap: RoomSnapshot) => {
+      current = snap;
+      setRoomState(snap);
+    };
+
+    const applyUpdate = (update: RoomUpdate) => {
+      if (update.type === "snapshot") {
+        if (!current || update.revision >= current.revision) {
+          show(update.snapshot);
+        }
+        return;
+      }
+      if (!current || update.revision <= current.revision) return;
+      if (update.revision > current.revision + 1) {
+        pending.push(update);
+        resync();
+        return;
+      }
+      show({
+        ...applyRoomDelta(current, update.delta),
+        revision: update.revision,
+      });
+    };
+
+    const resync = () => {
+      resyncing = true;
+      client
+        .rpc("get_room", { roomId })
+        .then((result) => {
+          if (!isRoomSnapshot(result.data)) return;
+          if (!current || result.data.revision >= current.revision) {
+            show(result.data);
+          }
+        })
+        .catch(() => {
+          // the next gap retries
+        })
+        .finally(() => {
+          resyncing = false;
+          const queued = pending;
+          pending = [];
+          for (const update of queued) {
+            if (resyncing) pending.push(update);
+            else applyUpdate(update);
+          }
+        });
+    };
+
     const timeoutId = setTimeout(() => {
       if (!subscribed) {
         setError({
@@ -102,39 +197,38 @@ export function useRoom(roomId: string | undefined): UseRoomResult {
     }, 10_000);
 
     sub.on("publication", (ctx) => {
-      if (!isRoomSnapshot(ctx.data)) {
-        console.error("useRoom: received malformed snapshot", ctx.data);
+      if (!isRoomUpdate(ctx.data)) {
+        console.error("useRoom: received malformed update", ctx.data);
         return;
       }
-      setRoomState(ctx.data);
+      if (ctx.data.type === "event") {
+        // Events do not touch the state, so they skip revision tracking.
+        const event = ctx.data.event;
+        if (event.type === "server" && event.action === "restarting") {
+          setRestart(event.payload as ServerRestartingPayload);
+          return;
+        }
+        setEvents((prev) => [...prev, event].slice(-MAX_EVENTS));
+        return;
+      }
+      if (resyncing) pending.push(ctx.data);
+      else applyUpdate(ctx.data);
     });
 
-    sub.on("subscribed", () => {
+    sub.on("subscribed", (ctx) => {
       subscribed = true;
+      // Every (re)subscription starts from the full snapshot in the reply;
+      // revisions restart from zero when the server restarts.
+      if (isRoomUpdate(ctx.data) && ctx.data.type === "snapshot") {
+        pending = [];
+        show(ctx.data.snapshot);
+      }
       clearTimeout(timeoutId);
       setConnected(true);
       setError(null);
-      // Issue join_room RPC to get initial state and register the client mapping.
-      // This runs on first subscribe and on reconnect.
-      const info = loadRoomInfo(roomId);
-      const userPrefs = loadUser();
-      if (info?.userId && userPrefs) {
-        client
-          .rpc("join_room", {
-            roomId,
-            userName: userPrefs.name,
-            avatarId: userPrefs.avatarId,
-            userId: info.userId,
-          })
-          .then((result) => {
-            const resp = result.data as unknown as JoinRoomResponse;
-            if (resp.state) {
-              setRoomState(resp.state);
-            }
-          })
-          .catch(() => {
-            // broadcast will deliver state; non-critical if this fails
-          });
+      setRestart(null);
+      if (userSub?.state === SubscriptionState.Unsubscribed) {
+        userSub.subscribe();
       }
     });
 
@@ -153,8 +247,17 @@ export function useRoom(roomId: string | undefined): UseRoomResult {
     const onClientDisconnected = () => {
       setConnected(false);
     };
-    const onClientConnecting = () => {
+    const onClientConnecting = (ctx: { code: number }) => {
       setConnected(false);
+      if (ctx.code === DisconnectCode.Restarting) {
+        // Disconnected for a restart we missed the notice of: the client
+        // retries on its own, there is just no estimate to show.
+        setRestart((prev) => {
+          if (prev) return prev;
+          const now = new Date().toISOString();
+          return { disconnectAt: now, backAt: now };
+        });
+      }
     };
     const onClientConnected = () => {
       setError((prev) =>
@@ -179,8 +282,16 @@ export function useRoom(roomId: string | undefined): UseRoomResult {
       sub.unsubscribe();
       sub.removeAllListeners();
       client.removeSubscription(sub);
+      if (userSub) {
+        userSub.unsubscribe();
+        userSub.removeAllListeners();
+        client.removeSubscription(userSub);
+      }
       subRef.current = null;
       setConnected(false);
+      setEvents([]);
+      setNudge(null);
+      setRestart(null);
     };
   }, [roomId]);
 
@@ -282,6 +393,23 @@ export function useRoom(roomId: string | undefined): UseRoomResult {
     () => adminAction("start_free_vote"),
     [adminAction],
   );
+  const nudgeUsers = useCallback(
+    async (targetUserId?: string) => {
+      if (!roomId) return [];
+      const info = loadRoomInfo(roomId);
+      if (!info?.adminSecret) throw new Error("Not admin");
+      const client = getCentrifuge();
+      const req: NudgeRequest = {
+        roomId,
+        adminSecret: info.adminSecret,
+        targetUserId,
+      };
+      const result = await client.rpc("nudge", req);
+      return (result.data as NudgeResponse).nudged;
+    },
+    [roomId],
+  );
+  const dismissNudge = useCallback(() => setNudge(null), []);
   const setTicket = useCallback(
     async (ticketId: string) => {
       if (!roomId) return;
@@ -313,6 +441,11 @@ export function useRoom(roomId: string | undefined): UseRoomResult {
     [roomId],
   );
 
+  const heartbeat = useCallback(async () => {
+    const client = getCentrifuge();
+    await client.rpc("heartbeat", {});
+  }, []);
+
   const interactPlayer = useCallback(
     async (action: string, targetUserId: string) => {
       if (!roomId) return;
@@ -347,6 +480,10 @@ export function useRoom(roomId: string | undefined): UseRoomResult {
 
   return {
     roomState,
+    events,
+    nudge,
+    dismissNudge,
+    restart,
     connected,
     error,
     loading,
@@ -361,7 +498,9 @@ export function useRoom(roomId: string | undefined): UseRoomResult {
     prevTicket,
     setTicket,
     startFreeVote,
+    nudgeUsers,
     setThinking,
+    heartbeat,
     interactPlayer,
     themeInteract,
   };
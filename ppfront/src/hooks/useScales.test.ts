@@ -0,0 +1,54 @@
+import { renderHook, waitFor } from "@testing-library/react";
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+const hours = { id: "hours", name: "Hours", values: ["1", "2", "4", "?"] };
+
+describe("useScales", () => {
+  afterEach(() => {
+    vi.unstubAllGlobals();
+    vi.restoreAllMocks();
+    vi.resetModules();
+  });
+
+  it("adds custom scales from the server after the built-in ones", async () => {
+    vi.stubGlobal(
+      "fetch",
+      vi.fn().mockResolvedValue(
+        new Response(
+          JSON.stringify([
+            { id: "fibonacci", name: "Fibonacci", values: [] },
+            hours,
+          ]),
+        ),
+      ),
+    );
+    const { useScales } = await import("./useScales");
+    const { result } = renderHook(() => useScales());
+
+    expect(result.current.map((s) => s.id)).not.toContain("hours");
+    await waitFor(() => {
+      expect(result.current.map((s) => s.id)).toEqual([
+        "fibonacci",
+        "power_of_2",
+        "linear",
+        "tshirt",
+        "hours",
+      ]);
+    });
+  });
+
+  it("keeps the built-in scales when the request fails", async () => {
+    vi.stubGlobal(
+      "fetch",
+      vi.fn().mockResolvedValue(new Response("", { status: 500 })),
+    );
+    vi.spyOn(console, "warn").mockImplementation(() => {});
+    const { useScales } = await import("./useScales");
+    const { result } = renderHook(() => useScales());
+
+    await waitFor(() => {
+      expect(console.warn).toHaveBeenCalled();
+    });
+    expect(result.current).toHaveLength(4);
+  });
+});
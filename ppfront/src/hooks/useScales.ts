@@ -0,0 +1,42 @@
+import { useSyncExternalStore } from "react";
+import { scales as builtInScales } from "../data/scales";
+import { basePath } from "../lib/basePath";
+import type { EstimationScale } from "../types";
+
+// Built-in scales are known up front; custom scales configured on the server
+// are added once /api/scales has loaded.
+let current: EstimationScale[] = builtInScales;
+let requested = false;
+const listeners = new Set<() => void>();
+
+function load() {
+  if (requested) return;
+  requested = true;
+  fetch(`${basePath}/api/scales`)
+    .then((res) => (res.ok ? res.json() : Promise.reject(res.status)))
+    .then((all: EstimationScale[]) => {
+      const custom = all
+        .filter((s) => !builtInScales.some((b) => b.id === s.id))
+        .sort((a, b) => a.name.localeCompare(b.name));
+      if (custom.length === 0) return;
+      current = [...builtInScales, ...custom];
+      for (const listener of listeners) {
+        listener();
+      }
+    })
+    .catch((err: unknown) => {
+      console.warn("loading scales failed:", err);
+    });
+}
+
+function subscribe(listener: () => void) {
+  listeners.add(listener);
+  load();
+  return () => {
+    listeners.delete(listener);
+  };
+}
+
+export function useScales(): EstimationScale[] {
+  return useSyncExternalStore(subscribe, () => current);
+}
@@ -0,0 +1,26 @@
+import { afterEach, describe, expect, it, vi } from "vitest";
+
+describe("basePath", () => {
+  afterEach(() => {
+    document.head.querySelector("base")?.remove();
+    vi.resetModules();
+  });
+
+  it("is empty without a base element", async () => {
+    const { basePath, appUrl } = await import("./basePath");
+    expect(basePath).toBe("");
+    expect(appUrl("/room/1")).toBe(`${window.location.origin}/room/1`);
+  });
+
+  it("follows the base element set by the server", async () => {
+    const base = document.createElement("base");
+    base.setAttribute("href", "/poker/");
+    document.head.appendChild(base);
+    const { basePath, appUrl, websocketUrl } = await import("./basePath");
+    expect(basePath).toBe("/poker");
+    expect(appUrl("/room/1")).toBe(`${window.location.origin}/poker/room/1`);
+    expect(websocketUrl()).toBe(
+      `ws://${window.location.host}/poker/connection/websocket`,
+    );
+  });
+});
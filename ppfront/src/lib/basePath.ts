@@ -0,0 +1,21 @@
+// The server may serve the app under a path prefix and points the <base>
+// element of index.html at it. Without one (e.g. the Vite dev server) the app
+// lives at the root.
+function readBasePath(): string {
+  const href = document.querySelector("base")?.getAttribute("href") ?? "/";
+  return new URL(href, window.location.origin).pathname.replace(/\/$/, "");
+}
+
+/** Path prefix of the app without a trailing slash; "" at the root. */
+export const basePath = readBasePath();
+
+/** Absolute URL of a path inside the app, e.g. for links to share. */
+export function appUrl(path: string): string {
+  return `${window.location.origin}${basePath}${path}`;
+}
+
+/** URL of the Centrifuge WebSocket endpoint. */
+export function websocketUrl(): string {
+  const protocol = window.location.protocol === "https:" ? "wss:" : "ws:";
+  return `${protocol}//${window.location.host}${basePath}/connection/websocket`;
+}
@@ -0,0 +1,69 @@
+import { describe, it, expect } from "vitest";
+import { applyRoomDelta } from "./roomDelta";
+import type { RoomSnapshot, User } from "../types";
+
+function user(id: string, name: string): User {
+  return { id, name, avatarId: "cat", isAdmin: false, connected: true };
+}
+
+const base: RoomSnapshot = {
+  id: "r",
+  revision: 1,
+  name: "",
+  scale: "fibonacci",
+  state: "idle",
+  countdown: 0,
+  users: [user("u1", "Alice"), user("u2", "Bob")],
+  tickets: [
+    { id: "t1", content: "# First", status: "pending", votes: [] },
+    { id: "t2", content: "# Second", status: "pending", votes: [] },
+  ],
+  currentTicketId: "",
+  ticketsEnabled: true,
+};
+
+describe("applyRoomDelta", () => {
+  it("changes only the fields present in the delta", () => {
+    const next = applyRoomDelta(base, { name: "Sprint 42" });
+    expect(next.name).toBe("Sprint 42");
+    expect(next.state).toBe("idle");
+    expect(next.users).toEqual(base.users);
+  });
+
+  it("keeps ticket content when the delta omits it", () => {
+    const next = applyRoomDelta(base, {
+      state: "voting",
+      currentTicketId: "t1",
+      tickets: [{ id: "t1", status: "voting", votes: [{ userId: "u1" }] }],
+    });
+    expect(next.tickets[0]).toEqual({
+      id: "t1",
+      content: "# First",
+      status: "voting",
+      votes: [{ userId: "u1" }],
+    });
+    expect(next.tickets[1]).toBe(base.tickets[1]);
+  });
+
+  it("keeps an explicitly empty content", () => {
+    const next = applyRoomDelta(base, {
+      tickets: [{ id: "free", content: "", status: "voting", votes: [] }],
+    });
+    expect(next.tickets[2].content).toBe("");
+  });
+
+  it("removes, appends and reorders users", () => {
+    const next = applyRoomDelta(base, {
+      users: [user("u3", "Carol")],
+      removedUsers: ["u1"],
+      userOrder: ["u3", "u2"],
+    });
+    expect(next.users.map((u) => u.id)).toEqual(["u3", "u2"]);
+  });
+
+  it("does not modify the input", () => {
+    applyRoomDelta(base, { name: "x", removedUsers: ["u1"] });
+    expect(base.name).toBe("");
+    expect(base.users).toHaveLength(2);
+  });
+});
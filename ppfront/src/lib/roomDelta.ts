@@ -0,0 +1,56 @@
+import type {
+  RoomDelta,
+  RoomSnapshot,
+  TicketDelta,
+  TicketSnapshot,
+  User,
+} from "../types";
+
+/** Removes, replaces, appends and optionally reorders items by id. */
+function upsert<T extends { id: string }, C extends { id: string }>(
+  items: T[],
+  changed: C[] | undefined,
+  removed: string[] | undefined,
+  order: string[] | undefined,
+  merge: (old: T | undefined, next: C) => T,
+): T[] {
+  const gone = new Set(removed ?? []);
+  const out = items.filter((item) => !gone.has(item.id));
+  for (const item of changed ?? []) {
+    const i = out.findIndex((o) => o.id === item.id);
+    if (i >= 0) out[i] = merge(out[i], item);
+    else out.push(merge(undefined, item));
+  }
+  if (!order) return out;
+  const byId = new Map(out.map((item) => [item.id, item]));
+  return order.flatMap((id) => byId.get(id) ?? []);
+}
+
+/** Returns a new snapshot with delta applied; revision is set by the caller. */
+export function applyRoomDelta(
+  snap: RoomSnapshot,
+  delta: RoomDelta,
+): RoomSnapshot {
+  return {
+    ...snap,
+    name: delta.name ?? snap.name,
+    state: delta.state ?? snap.state,
+    countdown: delta.countdown ?? snap.countdown,
+    currentTicketId: delta.currentTicketId ?? snap.currentTicketId,
+    themeState: delta.themeState ?? snap.themeState,
+    users: upsert<User, User>(
+      snap.users,
+      delta.users,
+      delta.removedUsers,
+      delta.userOrder,
+      (_, u) => u,
+    ),
+    tickets: upsert<TicketSnapshot, TicketDelta>(
+      snap.tickets,
+      delta.tickets,
+      delta.removedTickets,
+      delta.ticketOrder,
+      (old, t) => ({ ...t, content: t.content ?? old?.content ?? "" }),
+    ),
+  };
+}
@@ -1,5 +1,5 @@
 import { describe, it, expect } from "vitest";
-import { isRoomSnapshot } from "./validate";
+import { isRoomEvent, isRoomSnapshot, isRoomUpdate } from "./validate";
 
 describe("isRoomSnapshot", () => {
   it("returns true for a valid snapshot shape", () => {
@@ -18,3 +18,49 @@ describe("isRoomSnapshot", () => {
     expect(isRoomSnapshot("string")).toBe(false);
   });
 });
+
+describe("isRoomUpdate", () => {
+  it("accepts a snapshot update", () => {
+    expect(
+      isRoomUpdate({
+        type: "snapshot",
+        revision: 1,
+        snapshot: { id: "x", users: [], state: "idle" },
+      }),
+    ).toBe(true);
+  });
+
+  it("accepts a delta update", () => {
+    expect(isRoomUpdate({ type: "delta", revision: 2, delta: {} })).toBe(true);
+  });
+
+  it("accepts an event update", () => {
+    expect(
+      isRoomUpdate({
+        type: "event",
+        revision: 2,
+        event: { id: "e1", type: "player_interaction", action: "paper_throw" },
+      }),
+    ).toBe(true);
+  });
+
+  it("rejects a bare snapshot", () => {
+    expect(isRoomUpdate({ id: "x", users: [], state: "idle" })).toBe(false);
+  });
+
+  it("rejects a malformed snapshot update", () => {
+    expect(isRoomUpdate({ type: "snapshot", revision: 1, snapshot: {} })).toBe(
+      false,
+    );
+  });
+});
+
+describe("isRoomEvent", () => {
+  it("accepts a nudge", () => {
+    expect(isRoomEvent({ id: "e1", type: "nudge", action: "vote" })).toBe(true);
+  });
+
+  it("rejects an event without an id", () => {
+    expect(isRoomEvent({ type: "nudge" })).toBe(false);
+  });
+});
@@ -1,4 +1,4 @@
-import type { RoomSnapshot } from "../types";
+import type { RoomEvent, RoomSnapshot, RoomUpdate } from "../types";
 
 export function isRoomSnapshot(value: unknown): value is RoomSnapshot {
   if (!value || typeof value !== "object") return false;
@@ -9,3 +9,18 @@ export function isRoomSnapshot(value: unknown): value is RoomSnapshot {
     typeof v.state === "string"
   );
 }
+
+export function isRoomEvent(value: unknown): value is RoomEvent {
+  if (!value || typeof value !== "object") return false;
+  const v = value as Record<string, unknown>;
+  return typeof v.id === "string" && typeof v.type === "string";
+}
+
+export function isRoomUpdate(value: unknown): value is RoomUpdate {
+  if (!value || typeof value !== "object") return false;
+  const v = value as Record<string, unknown>;
+  if (typeof v.revision !== "number") return false;
+  if (v.type === "snapshot") return isRoomSnapshot(v.snapshot);
+  if (v.type === "event") return isRoomEvent(v.event);
+  return v.type === "delta" && !!v.delta && typeof v.delta === "object";
+}
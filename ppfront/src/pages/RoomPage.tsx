@@ -9,6 +9,7 @@ import { CountdownOverlay } from "../components/CountdownOverlay";
 import { FloatingAdminPanel } from "../components/FloatingAdminPanel";
 import { PlayerInteractionLayer } from "../components/PlayerInteractionLayer";
 import { PokerTable } from "../components/PokerTable";
+import { RestartBanner } from "../components/RestartBanner";
 import { RoomNameEditor } from "../components/RoomNameEditor";
 import { ThemeToggle } from "../components/ThemeToggle";
 import { TicketList } from "../components/TicketList";
@@ -17,8 +18,9 @@ import { UserList } from "../components/UserList";
 import { VoteResults } from "../components/VoteResults";
 import { VotingPanel } from "../components/VotingPanel";
 import { RoomProvider, useRoomContext } from "../context/RoomContext";
-import { scales } from "../data/scales";
+import { useScales } from "../hooks/useScales";
 import { useKeyboardShortcuts } from "../hooks/useKeyboardShortcuts";
+import { useActivityHeartbeat } from "../hooks/useActivityHeartbeat";
 import { useThinkingHeartbeat } from "../hooks/useThinkingHeartbeat";
 import { loadRoomInfo, saveRoomInfo } from "../hooks/useUser";
 
@@ -56,6 +58,10 @@ export function RoomPage() {
 function RoomPageContent({ roomId }: { roomId: string }) {
   const {
     roomState,
+    events,
+    nudge,
+    dismissNudge,
+    restart,
     connected,
     error,
     submitVote,
@@ -69,7 +75,9 @@ function RoomPageContent({ roomId }: { roomId: string }) {
     prevTicket,
     setTicket,
     startFreeVote,
+    nudgeUsers,
     setThinking,
+    heartbeat,
     interactPlayer,
     themeInteract,
   } = useRoomContext();
@@ -81,8 +89,9 @@ function RoomPageContent({ roomId }: { roomId: string }) {
   const campfireState = roomState?.themeState?.theme === "campfire"
     ? roomState.themeState.data
     : null;
-  const campfireEvents = (roomState?.events ?? []).filter(
-    (e) => e.type === "theme_interaction",
+  const campfireEvents = useMemo(
+    () => events.filter((e) => e.type === "theme_interaction"),
+    [events],
   );
 
   const ticketsEnabled = roomState?.ticketsEnabled ?? false;
@@ -120,6 +129,7 @@ function RoomPageContent({ roomId }: { roomId: string }) {
   const userPositions = useRef<Map<string, { x: number; y: number }>>(new Map());
 
   const { onInteraction } = useThinkingHeartbeat({ setThinking, isVoting });
+  useActivityHeartbeat(heartbeat);
 
   const currentTicketIndex = roomState?.currentTicketId
     ? tickets.findIndex((t) => t.id === roomState.currentTicketId)
@@ -132,10 +142,11 @@ function RoomPageContent({ roomId }: { roomId: string }) {
       : currentTicketIndex < tickets.length - 1) &&
     !isCountingDown;
 
+  const scales = useScales();
   const scaleValues = useMemo(() => {
     const scale = scales.find((s) => s.id === roomState?.scale);
     return scale?.values ?? [];
-  }, [roomState?.scale]);
+  }, [scales, roomState?.scale]);
 
   const onCountdownComplete = useCallback(() => {
     if (isAdmin) {
@@ -366,11 +377,19 @@ function RoomPageContent({ roomId }: { roomId: string }) {
           onNextTicket={nextTicket}
           onAddTicket={addTicket}
           onStartFreeVote={startFreeVote}
+          onNudge={() => {
+            nudgeUsers().catch((err: unknown) => {
+              console.warn("nudge failed:", err);
+            });
+          }}
         />
       )}
 
+      {nudge && <NudgeBanner key={nudge.id} onDismiss={dismissNudge} />}
+      {restart && <RestartBanner restart={restart} />}
+
       <PlayerInteractionLayer
-        events={roomState?.events ?? []}
+        events={events}
         userPositions={userPositions.current}
       />
 
@@ -379,6 +398,25 @@ function RoomPageContent({ roomId }: { roomId: string }) {
   );
 }
 
+// How long a nudge stays on screen unless dismissed.
+const NUDGE_VISIBLE_MS = 8000;
+
+function NudgeBanner({ onDismiss }: { onDismiss: () => void }) {
+  useEffect(() => {
+    const id = setTimeout(onDismiss, NUDGE_VISIBLE_MS);
+    return () => clearTimeout(id);
+  }, [onDismiss]);
+
+  return (
+    <div className="nudge-banner" role="alert">
+      <span>The facilitator is waiting for your vote</span>
+      <button type="button" onClick={onDismiss}>
+        Dismiss
+      </button>
+    </div>
+  );
+}
+
 function ConnectionStatusBar({ connected }: { connected: boolean }) {
   return (
     <div className={`connection-status-bar ${connected ? "connected" : "reconnecting"}`}>
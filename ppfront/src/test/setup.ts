@@ -14,3 +14,7 @@ Object.defineProperty(window, "matchMedia", {
     dispatchEvent: () => false,
   }),
 });
+
+// Stub fetch for /api/scales (used by useScales); tests see the built-in scales
+globalThis.fetch = (() =>
+  Promise.resolve(new Response("[]"))) as unknown as typeof fetch;
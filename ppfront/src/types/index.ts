@@ -11,6 +11,7 @@ export interface User {
   avatarId: string;
   isAdmin: boolean;
   connected: boolean;
+  away?: boolean;
   thinking?: boolean;
 }
 
@@ -54,9 +55,19 @@ export interface FeedFirePayload {
   fromY: number;
 }
 
+// Payload of the "server" event with action "restarting", sent to every room
+// before the server shuts down
+export interface ServerRestartingPayload {
+  disconnectAt: string; // ISO time connections are closed
+  backAt: string; // ISO time the server is expected to accept connections again
+}
+
 // Player/theme interaction event
+// One-off interaction published on the room channel, separate from the state
 export interface RoomEvent {
-  type: string;   // "player_interaction" | "theme_interaction"
+  id: string;
+  at: string; // ISO time
+  type: string;   // "player_interaction" | "theme_interaction" | "nudge" | "server"
   action: string; // "paper_throw", "feed_fire", etc.
   fromId: string;
   toId: string;
@@ -66,6 +77,7 @@ export interface RoomEvent {
 // Sanitized room snapshot sent to clients
 export interface RoomSnapshot {
   id: string;
+  revision: number;
   name: string;
   scale: string;
   state: RoomState;
@@ -74,18 +86,52 @@ export interface RoomSnapshot {
   tickets: TicketSnapshot[];
   currentTicketId: string;
   ticketsEnabled: boolean;
-  events?: RoomEvent[];
   themeState?: ThemeState;
 }
 
+// Outcome of writing an estimate back to the issue tracker
+export type SyncStatus = "pending" | "synced" | "failed";
+
 // Sanitized ticket in a snapshot
 export interface TicketSnapshot {
   id: string;
   content: string;
   status: TicketStatus;
   votes: VoteInfo[];
+  externalKey?: string;
+  externalUrl?: string;
+  syncStatus?: SyncStatus;
+  syncError?: string;
+  syncedValue?: string;
 }
 
+// Changes between two snapshots; absent fields are unchanged.
+// Ticket content is omitted unless it changed.
+export interface RoomDelta {
+  name?: string;
+  state?: RoomState;
+  countdown?: number;
+  currentTicketId?: string;
+  themeState?: ThemeState;
+  users?: User[];
+  removedUsers?: string[];
+  userOrder?: string[];
+  tickets?: TicketDelta[];
+  removedTickets?: string[];
+  ticketOrder?: string[];
+}
+
+// Changed ticket in a delta; content is absent when unchanged
+export type TicketDelta = Omit<TicketSnapshot, "content"> & {
+  content?: string;
+};
+
+// Payload of every publication on a room channel
+export type RoomUpdate =
+  | { type: "snapshot"; revision: number; snapshot: RoomSnapshot }
+  | { type: "delta"; revision: number; delta: RoomDelta }
+  | { type: "event"; revision: number; event: RoomEvent };
+
 // Vote info in a snapshot (value hidden during voting)
 export interface VoteInfo {
   userId: string;
@@ -140,6 +186,27 @@ export interface AddTicketResponse {
   ticketId: string;
 }
 
+export interface ImportTicketsRequest {
+  roomId: string;
+  adminSecret: string;
+  query: string;
+}
+
+export interface ImportTicketsResponse {
+  ticketIds: string[];
+}
+
+export interface SyncEstimatesResponse {
+  queued: number;
+}
+
+export interface SetWebhookRequest {
+  roomId: string;
+  adminSecret: string;
+  url: string;
+  secret?: string;
+}
+
 export interface AdminActionRequest {
   roomId: string;
   adminSecret: string;
@@ -151,6 +218,16 @@ export interface UpdateRoomNameRequest {
   name: string;
 }
 
+export interface NudgeRequest {
+  roomId: string;
+  adminSecret: string;
+  targetUserId?: string;
+}
+
+export interface NudgeResponse {
+  nudged: string[];
+}
+
 export interface SetTicketRequest {
   roomId: string;
   adminSecret: string;
@@ -168,3 +245,25 @@ export interface StoredRoomInfo {
   userId: string;
   adminSecret?: string;
 }
+
+// Application error codes sent in RPC errors; see the README for the list.
+export const ErrorCode = {
+  RoomNotFound: 1000,
+  UserNotFound: 1001,
+  TicketNotFound: 1002,
+  TreeNotFound: 1003,
+  InvalidAdminSecret: 1100,
+  NotVoting: 1200,
+  NoCurrentTicket: 1201,
+  InvalidVote: 1202,
+  TreeAlreadyBurned: 1203,
+  NoCampfire: 1204,
+  TrackerFailed: 1300,
+  ServerDraining: 1400,
+  RoomBusy: 1401,
+} as const;
+
+// Codes the server closes connections with; the client reconnects after them.
+export const DisconnectCode = {
+  Restarting: 4000,
+} as const;
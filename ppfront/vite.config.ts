@@ -2,6 +2,9 @@ import react from "@vitejs/plugin-react";
 import { defineConfig } from "vite";
 
 export default defineConfig({
+  // Assets are loaded relative to the <base> element the server adds, so the
+  // same build works under any base path.
+  base: "./",
   plugins: [react()],
   server: {
     proxy: {
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"pockerplan/ppback/admin"
+	"pockerplan/ppback/certs"
+	"pockerplan/ppback/config"
+	"pockerplan/ppback/hub"
+	"pockerplan/ppback/room"
+	"pockerplan/ppback/scale"
+	"pockerplan/ppback/server"
+	"pockerplan/ppback/tracing"
+	"pockerplan/ppback/tracker"
+	"pockerplan/ppback/webhook"
+
+	"github.com/alecthomas/kong"
+	"github.com/centrifugal/centrifuge"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+// ServeCmd runs the pockerplan server.
+type ServeCmd struct {
+	Config kong.ConfigFlag `type:"existingfile" env:"CONFIG" help:"YAML file with settings; keys are flag names. Flags and environment variables take precedence."`
+
+	Addr            string        `default:":8080" env:"ADDR" help:"Listen address."`
+	TLSCert         string        `type:"existingfile" env:"TLS_CERT" help:"PEM certificate file, leaf first, to serve HTTPS; reloaded when it changes."`
+	TLSKey          string        `type:"existingfile" env:"TLS_KEY" help:"PEM private key file of the TLS certificate."`
+	HTTPRedirect    string        `env:"HTTP_REDIRECT_ADDR" help:"With TLS, listen address of a plain HTTP server redirecting to HTTPS, e.g. :80; disabled when empty."`
+	HSTSMaxAge      time.Duration `default:"8760h" env:"HSTS_MAX_AGE" help:"With TLS or behind a trusted proxy, max-age of the Strict-Transport-Security header sent on HTTPS requests; 0 omits the header."`
+	BasePath        string        `env:"BASE_PATH" help:"Path prefix, e.g. /poker, under which the frontend, API and WebSocket are served."`
+	AllowedOrigins  []string      `env:"ALLOWED_ORIGINS" sep:"," help:"Other origins whose pages may connect, e.g. https://*.example.com; * allows any."`
+	TrustedProxies  []string      `env:"TRUSTED_PROXIES" sep:"," help:"IP addresses or CIDR ranges of reverse proxies whose X-Forwarded-Host and X-Forwarded-Proto headers are trusted."`
+	LogFormat       string        `enum:"console,json" default:"console" env:"LOG_FORMAT" help:"Log output format (console, json)."`
+	LogLevel        string        `enum:"trace,debug,info,warn,error" default:"info" env:"LOG_LEVEL" help:"Minimum level of log messages (trace, debug, info, warn, error)."`
+	CentrifugeLog   string        `enum:"none,trace,debug,info,warn,error" default:"info" env:"CENTRIFUGE_LOG_LEVEL" help:"Minimum level of Centrifuge's own log messages (none, trace, debug, info, warn, error)."`
+	Countdown       int           `default:"3" env:"COUNTDOWN" help:"Countdown seconds before reveal."`
+	Tickets         bool          `default:"false" env:"TICKETS" help:"Enable tickets feature."`
+	RoomTTL         time.Duration `default:"24h" env:"ROOM_TTL" help:"How long inactive rooms are kept."`
+	CleanupInterval time.Duration `default:"10m" env:"CLEANUP_EVERY" help:"How often the room cleanup runs."`
+	Redis           string        `env:"REDIS_URL" help:"Redis address, host:port or redis:// URL, shared by several server instances for rooms, publications and presence; rooms are kept in memory when empty."`
+	RedisPrefix     string        `default:"pockerplan" env:"REDIS_PREFIX" help:"Prefix of the Redis keys, so that several deployments can share one Redis."`
+	AdminSocket     string        `env:"ADMIN_SOCKET" help:"Path of the Unix socket serving the operator API; disabled when empty."`
+	BroadcastWindow time.Duration `default:"50ms" env:"BROADCAST_WINDOW" help:"How long room changes are collected into one publication; 0 publishes each change immediately."`
+	DisconnectGrace time.Duration `default:"5s" env:"DISCONNECT_GRACE" help:"How long a user stays online after their last connection drops; 0 marks them offline immediately."`
+	IdleTimeout     time.Duration `default:"2m" env:"IDLE_TIMEOUT" help:"How long a connected user may be inactive before they are shown as away; 0 disables away detection."`
+	DrainNotice     time.Duration `default:"5s" env:"DRAIN_NOTICE" help:"On SIGTERM or SIGINT, how long rooms are warned of the restart before clients are disconnected."`
+	RestartETA      time.Duration `default:"30s" env:"RESTART_ETA" help:"Expected downtime after a shutdown, announced to rooms with the restart warning."`
+	RPCRate         float64       `default:"20" env:"RPC_RATE" help:"Average RPCs per second allowed from one connection; 0 disables the limit."`
+	RPCBurst        int           `default:"40" env:"RPC_BURST" help:"RPCs one connection may send in a burst above the average rate."`
+	Scales          config.Scales `env:"SCALES" help:"Custom estimation scales offered next to the built-in ones, as a JSON array of {id, name, values}."`
+
+	Tracker      string `enum:",jira,github" default:"" env:"TRACKER" help:"Issue tracker to import tickets from (jira, github)."`
+	TrackerURL   string `env:"TRACKER_URL" help:"Issue tracker base URL (required for Jira, defaults to https://api.github.com for GitHub)."`
+	TrackerUser  string `env:"TRACKER_USER" help:"Jira account e-mail for basic auth; bearer token auth is used when empty."`
+	TrackerToken string `env:"TRACKER_TOKEN" help:"Issue tracker API token."`
+	TrackerField string `env:"TRACKER_ESTIMATE_FIELD" help:"Jira field that receives agreed estimates (default customfield_10016)."`
+	TrackerText  bool   `env:"TRACKER_ESTIMATE_TEXT" help:"The Jira estimate field takes text; by default it is numeric and non-numeric estimates are not written."`
+
+	WebhookURL    []string `env:"WEBHOOK_URL" sep:"," help:"Server-wide webhook endpoints receiving events of every room."`
+	WebhookSecret string   `env:"WEBHOOK_SECRET" help:"HMAC secret used to sign server-wide webhook payloads."`
+
+	RoomWebhookHosts   []string `env:"ROOM_WEBHOOK_HOSTS" sep:"," help:"Hosts rooms may send webhooks to: a host name, *.domain or *. Empty disables per-room webhooks."`
+	RoomWebhookPrivate bool     `env:"ROOM_WEBHOOK_PRIVATE" help:"Let per-room webhooks reach loopback, private and link-local addresses."`
+
+	TraceExporter    string  `enum:",otlp,stdout" default:"" env:"TRACE_EXPORTER" help:"OpenTelemetry trace exporter (otlp, stdout); tracing is off when empty."`
+	TraceEndpoint    string  `env:"TRACE_ENDPOINT" help:"OTLP/HTTP collector URL, e.g. http://localhost:4318; the OTEL_EXPORTER_OTLP_* variables apply when empty."`
+	TraceSampleRatio float64 `default:"1" env:"TRACE_SAMPLE_RATIO" help:"Fraction of new traces to record (0 to 1)."`
+}
+
+// certWatchInterval is how often the TLS certificate files are checked for
+// changes.
+const certWatchInterval = 10 * time.Second
+
+// centrifugeLogLevels maps --centrifuge-log values to Centrifuge log levels.
+var centrifugeLogLevels = map[string]centrifuge.LogLevel{
+	"none":  centrifuge.LogLevelNone,
+	"trace": centrifuge.LogLevelTrace,
+	"debug": centrifuge.LogLevelDebug,
+	"info":  centrifuge.LogLevelInfo,
+	"warn":  centrifuge.LogLevelWarn,
+	"error": centrifuge.LogLevelError,
+}
+
+// newLogger creates the server logger writing to stderr in the given format.
+func newLogger(format, level string) (zerolog.Logger, error) {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return zerolog.Logger{}, err
+	}
+	var out io.Writer = os.Stderr
+	if format == "console" {
+		out = zerolog.ConsoleWriter{Out: os.Stderr}
+	}
+	return zerolog.New(out).Level(lvl).With().Timestamp().Logger(), nil
+}
+
+// Validate checks settings that kong cannot, so that a bad flag, variable or
+// config file entry is reported before the server starts or reloads.
+func (c *ServeCmd) Validate() error {
+	var errs []error
+	if c.Countdown < 1 || c.Countdown > 30 {
+		errs = append(errs, fmt.Errorf("countdown: %d is outside [1, 30]", c.Countdown))
+	}
+	if c.RoomTTL <= 0 {
+		errs = append(errs, fmt.Errorf("room-ttl: %v must be positive", c.RoomTTL))
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		errs = append(errs, errors.New("tls-cert and tls-key must be set together"))
+	}
+	if c.HTTPRedirect != "" && c.TLSCert == "" {
+		errs = append(errs, errors.New("http-redirect: requires tls-cert and tls-key"))
+	}
+	if err := server.ValidateBasePath(c.BasePath); err != nil {
+		errs = append(errs, err)
+	}
+	if err := server.ValidateOrigins(c.AllowedOrigins); err != nil {
+		errs = append(errs, err)
+	}
+	if err := webhook.ValidateRoomHosts(c.RoomWebhookHosts); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := server.ParseTrustedProxies(c.TrustedProxies); err != nil {
+		errs = append(errs, err)
+	}
+	if c.HSTSMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("hsts-max-age: %v must not be negative", c.HSTSMaxAge))
+	}
+	if c.CleanupInterval <= 0 {
+		errs = append(errs, fmt.Errorf("cleanup-interval: %v must be positive", c.CleanupInterval))
+	}
+	for name, d := range map[string]time.Duration{
+		"broadcast-window": c.BroadcastWindow,
+		"disconnect-grace": c.DisconnectGrace,
+		"idle-timeout":     c.IdleTimeout,
+		"drain-notice":     c.DrainNotice,
+		"restart-eta":      c.RestartETA,
+	} {
+		if d < 0 {
+			errs = append(errs, fmt.Errorf("%s: %v must not be negative", name, d))
+		}
+	}
+	if c.RPCRate < 0 {
+		errs = append(errs, fmt.Errorf("rpc-rate: %v must not be negative", c.RPCRate))
+	}
+	if c.RPCBurst < 0 {
+		errs = append(errs, fmt.Errorf("rpc-burst: %d must not be negative", c.RPCBurst))
+	}
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("trace-sample-ratio: %v is outside [0, 1]", c.TraceSampleRatio))
+	}
+	return errors.Join(errs...)
+}
+
+// reloadable are the settings that SIGHUP applies to a running server. The
+// others require a restart.
+var reloadable = map[string]bool{
+	"countdown": true,
+	"room-ttl":  true,
+	"rpc-rate":  true,
+	"rpc-burst": true,
+	"scales":    true,
+}
+
+// reload parses the command line again, re-reading the environment and the
+// config file, and applies the reloadable settings. An invalid configuration
+// is logged and leaves the running one in place. WebSocket connections are
+// not affected.
+func (c *ServeCmd) reload(h *hub.Hub, rm *room.Manager, logger zerolog.Logger) {
+	var next CLI
+	parser, err := newParser(&next)
+	if err == nil {
+		_, err = parser.Parse(os.Args[1:])
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("config reload rejected")
+		return
+	}
+	n := &next.Serve
+	if err := scale.SetCustom(n.Scales); err != nil {
+		logger.Error().Err(err).Msg("config reload rejected")
+		return
+	}
+	h.SetCountdown(n.Countdown)
+	h.SetRPCRateLimit(n.RPCRate, n.RPCBurst)
+	rm.SetTTL(n.RoomTTL)
+
+	for _, name := range changedSettings(parser.Model, c, n) {
+		if !reloadable[name] {
+			logger.Warn().Str("setting", name).Msg("setting changed; restart to apply")
+		}
+	}
+	c.Countdown, c.RoomTTL, c.RPCRate, c.RPCBurst, c.Scales = n.Countdown, n.RoomTTL, n.RPCRate, n.RPCBurst, n.Scales
+	logger.Info().Msg("config reloaded")
+}
+
+// changedSettings returns the flag names of the settings that differ between
+// old and next. next must be the command filled by app.
+func changedSettings(app *kong.Application, old, next *ServeCmd) []string {
+	names := map[uintptr]string{}
+	kong.Visit(app, func(node kong.Visitable, visit kong.Next) error {
+		if f, ok := node.(*kong.Flag); ok && f.Target.CanAddr() {
+			names[f.Target.Addr().Pointer()] = f.Name
+		}
+		return visit(nil)
+	})
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(next).Elem()
+	var changed []string
+	for i := range nv.NumField() {
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			changed = append(changed, names[nv.Field(i).Addr().Pointer()])
+		}
+	}
+	return changed
+}
+
+func (c *ServeCmd) Run() error {
+	logger, err := newLogger(c.LogFormat, c.LogLevel)
+	if err != nil {
+		return err
+	}
+
+	if err := scale.SetCustom(c.Scales); err != nil {
+		logger.Fatal().Err(err).Msg("scales")
+	}
+
+	addr := c.Addr
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    c.TraceExporter,
+		Endpoint:    c.TraceEndpoint,
+		SampleRatio: c.TraceSampleRatio,
+		ServiceName: "pockerplan",
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("tracing")
+	}
+
+	// Frontend FS: strip the ppfront/dist prefix so files are served from root
+	frontFS, err := fs.Sub(frontendFS, "ppfront/dist")
+	if err != nil {
+		logger.Fatal().Err(err).Msg("frontend fs")
+	}
+
+	// Room manager with periodic cleanup
+	rm := room.NewManagerWithTTL(c.RoomTTL)
+	if c.Redis != "" {
+		store, err := room.NewRedisStore(c.Redis, c.RedisPrefix)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("room store")
+		}
+		rm = room.NewManagerWithStore(store, c.RoomTTL)
+	}
+	cleanupDone := make(chan struct{})
+	rm.StartCleanup(c.CleanupInterval, cleanupDone)
+
+	var endpoints []webhook.Endpoint
+	for _, u := range c.WebhookURL {
+		endpoints = append(endpoints, webhook.Endpoint{URL: u, Secret: c.WebhookSecret})
+	}
+	hubOpts := []hub.Option{
+		hub.WithBroadcastWindow(c.BroadcastWindow),
+		hub.WithDisconnectGrace(c.DisconnectGrace),
+		hub.WithIdleTimeout(c.IdleTimeout),
+		hub.WithRPCRateLimit(c.RPCRate, c.RPCBurst),
+		hub.WithMetrics(prometheus.DefaultRegisterer),
+		hub.WithCentrifugeLogLevel(centrifugeLogLevels[c.CentrifugeLog]),
+		hub.WithWebhooks(webhook.New(webhook.Config{
+			Endpoints:             endpoints,
+			RoomHosts:             c.RoomWebhookHosts,
+			AllowPrivateRoomHosts: c.RoomWebhookPrivate,
+		}, logger.With().Str("component", "webhook").Logger())),
+	}
+	if c.Redis != "" {
+		hubOpts = append(hubOpts, hub.WithRedis(c.Redis, c.RedisPrefix))
+	}
+	if c.Tracker != "" {
+		issues, err := tracker.New(tracker.Config{
+			Kind:    c.Tracker,
+			BaseURL: c.TrackerURL,
+			User:    c.TrackerUser,
+			Token:   c.TrackerToken,
+
+			EstimateField: c.TrackerField,
+			EstimateText:  c.TrackerText,
+		})
+		if err != nil {
+			logger.Fatal().Err(err).Msg("issue tracker")
+		}
+		hubOpts = append(hubOpts, hub.WithIssueProvider(issues))
+	}
+
+	// Centrifuge hub
+	h, err := hub.New(rm, c.Countdown, c.Tickets, logger.With().Str("component", "hub").Logger(), hubOpts...)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("create hub")
+	}
+	if err := h.Run(); err != nil {
+		logger.Fatal().Err(err).Msg("run hub")
+	}
+	h.StartCampfireLoop(5*time.Second, cleanupDone)
+
+	// TLS certificate, reloaded when the files change
+	var cert *certs.Certificate
+	if c.TLSCert != "" {
+		cert, err = certs.Load(c.TLSCert, c.TLSKey, logger.With().Str("component", "tls").Logger())
+		if err != nil {
+			logger.Fatal().Err(err).Msg("tls certificate")
+		}
+		cert.StartWatch(certWatchInterval, cleanupDone)
+	}
+
+	// Operator API on a local socket
+	var adminSrv *admin.Server
+	if c.AdminSocket != "" {
+		adminSrv = admin.New(rm, h, logger.With().Str("component", "admin").Logger())
+		if err := adminSrv.Listen(c.AdminSocket); err != nil {
+			logger.Fatal().Err(err).Str("socket", c.AdminSocket).Msg("admin socket")
+		}
+	}
+
+	// HTTP server
+	proxies, _ := server.ParseTrustedProxies(c.TrustedProxies) // checked by Validate
+	srvOpts := []server.Option{
+		server.WithBasePath(c.BasePath),
+		server.WithAllowedOrigins(c.AllowedOrigins...),
+		server.WithTrustedProxies(proxies...),
+	}
+	if cert != nil || len(proxies) > 0 {
+		srvOpts = append(srvOpts, server.WithHSTS(c.HSTSMaxAge))
+	}
+	srv := server.New(h, frontFS, logger.With().Str("component", "server").Logger(), srvOpts...)
+	httpServer := &http.Server{
+		Addr:    addr,
+		Handler: srv,
+	}
+	if cert != nil {
+		httpServer.TLSConfig = cert.TLSConfig()
+	}
+	var redirectServer *http.Server
+	if c.HTTPRedirect != "" {
+		redirectServer = &http.Server{
+			Addr:    c.HTTPRedirect,
+			Handler: server.RedirectHTTPS(addr),
+		}
+	}
+
+	// Reload on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			c.reload(h, rm, logger)
+			if cert != nil {
+				if err := cert.Reload(); err != nil {
+					logger.Error().Err(err).Msg("certificate reload failed")
+				}
+			}
+		}
+	}()
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		var err error
+		if cert != nil {
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logger.Fatal().Err(err).Msg("listen")
+		}
+	}()
+	if redirectServer != nil {
+		go func() {
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatal().Err(err).Msg("listen http redirect")
+			}
+		}()
+	}
+
+	logger.Info().Str("addr", addr).Bool("tls", cert != nil).Msg("server started")
+
+	<-quit
+	logger.Info().Msg("shutting down")
+	h.Drain(c.DrainNotice, c.RestartETA)
+
+	close(cleanupDone)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(ctx); err != nil {
+			logger.Error().Err(err).Msg("admin shutdown")
+		}
+	}
+	if redirectServer != nil {
+		if err := redirectServer.Shutdown(ctx); err != nil {
+			logger.Error().Err(err).Msg("http redirect shutdown")
+		}
+	}
+	if err := httpServer.Shutdown(ctx); err != nil {
+		logger.Error().Err(err).Msg("http shutdown")
+	}
+	if err := h.Shutdown(); err != nil {
+		logger.Error().Err(err).Msg("hub shutdown")
+	}
+	rm.Close()
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error().Err(err).Msg("tracing shutdown")
+	}
+
+	logger.Info().Msg("server stopped")
+	return nil
+}